@@ -2,6 +2,7 @@ package tmpcache
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/PycMono/go-cache/client"
 	"github.com/bytedance/sonic"
@@ -16,6 +17,10 @@ type Options struct {
 	EnableLog bool          // 是否输出日志
 	WriteNil  bool          // 缓存miss是否写入nil防止缓存穿透，默认不写入
 	Expire    time.Duration // 过期时间
+	Sliding   bool          // 滑动过期，Get 命中后把过期时间刷新为 Expire
+	Timeouts  Timeouts      // 超时配置，默认不限制
+	// 延迟双删配置，用于 Invalidate、UpdateAndInvalidate
+	Invalidate InvalidateOptions
 }
 
 type Base struct {
@@ -49,21 +54,242 @@ func (c *Base) splitKey(k string) string {
 	return sts[0]
 }
 
+// single 单个key加载函数的返回结果
+type single[T any] struct {
+	val T
+	ok  bool
+}
+
 type Cache[T any] struct {
 	handler client.IAdaptor // 适配器client
 	opts    *Options        // 基础配置
 	sf      singleflight.Group
+	lc      lifecycle
+	inv     *invalidator
 	// 还可以增加一些中间件比如日志、埋点之类的
 }
 
 func NewCache[T any](handler client.IAdaptor, opts *Options) ICache[T] {
-	return &Cache[T]{
+	c := &Cache[T]{
 		handler: handler,
 		opts:    opts,
 	}
+	del := func(ctx context.Context, tmpKeys []string) error {
+		return callTier(ctx, 0, "del", c.opts.Timeouts.Del, func(ctx context.Context) error {
+			return c.handler.Del(ctx, tmpKeys)
+		})
+	}
+	c.inv = newInvalidator(opts.Invalidate, del, del)
+	return c
+}
+
+// NewLocalCache 只使用进程内对象缓存，直接保存对象，不做序列化
+func NewLocalCache[T any](local client.ITypedAdaptor[T], opts *Options) ICache[T] {
+	return NewMultiCacheWithLocal[T](&MultiCacheOptions{
+		Base:       opts.Base,
+		EnableLog:  opts.EnableLog,
+		WriteNil:   opts.WriteNil,
+		Expire:     opts.Expire,
+		Sliding:    opts.Sliding,
+		Timeouts:   opts.Timeouts,
+		Invalidate: opts.Invalidate,
+	}, local)
 }
 
 func (c *Cache[T]) Set(ctx context.Context, params map[string]T) error {
+	if err := c.lc.enter(); err != nil {
+		return err
+	}
+	defer c.lc.leave()
+
+	return c.set(ctx, params)
+}
+
+func (c *Cache[T]) Get(ctx context.Context, keys []string) (map[string]T, error) {
+	if err := c.lc.enter(); err != nil {
+		return nil, err
+	}
+	defer c.lc.leave()
+
+	return c.get(ctx, keys)
+}
+
+// GetAndSet 缓存 miss，支持调用f函数从其它db中获取数据
+func (c *Cache[T]) GetAndSet(ctx context.Context, keys []string, f func(keys []string) (map[string]T, error)) (map[string]T, error) {
+	if err := c.lc.enter(); err != nil {
+		return nil, err
+	}
+	defer c.lc.leave()
+
+	return c.getAndSet(ctx, keys, f)
+}
+
+func (c *Cache[T]) GetAndSetSingle(ctx context.Context, k string, f func(k string) (T, bool, error)) (T, bool, error) {
+	if err := c.lc.enter(); err != nil {
+		var val T
+		return val, false, err
+	}
+	defer c.lc.leave()
+
+	return c.getAndSetSingle(ctx, k, f)
+}
+
+func (c *Cache[T]) Del(ctx context.Context, keys []string) error {
+	if err := c.lc.enter(); err != nil {
+		return err
+	}
+	defer c.lc.leave()
+
+	return c.del(ctx, keys)
+}
+
+func (c *Cache[T]) GetWithTTL(ctx context.Context, keys []string) (map[string]Item[T], error) {
+	if err := c.lc.enter(); err != nil {
+		return nil, err
+	}
+	defer c.lc.leave()
+
+	return c.getWithTTL(ctx, keys)
+}
+
+func (c *Cache[T]) TTL(ctx context.Context, keys []string) (map[string]time.Duration, error) {
+	if err := c.lc.enter(); err != nil {
+		return nil, err
+	}
+	defer c.lc.leave()
+
+	return c.ttl(ctx, keys)
+}
+
+func (c *Cache[T]) Touch(ctx context.Context, keys []string) error {
+	if err := c.lc.enter(); err != nil {
+		return err
+	}
+	defer c.lc.leave()
+
+	return c.expire(ctx, keys, c.opts.Expire)
+}
+
+func (c *Cache[T]) Expire(ctx context.Context, keys []string, expire time.Duration) error {
+	if err := c.lc.enter(); err != nil {
+		return err
+	}
+	defer c.lc.leave()
+
+	return c.expire(ctx, keys, expire)
+}
+
+func (c *Cache[T]) GetWithVersion(ctx context.Context, keys []string) (map[string]Versioned[T], error) {
+	if err := c.lc.enter(); err != nil {
+		return nil, err
+	}
+	defer c.lc.leave()
+
+	return c.getWithVersion(ctx, keys)
+}
+
+func (c *Cache[T]) SetIfAbsent(ctx context.Context, params map[string]T) (map[string]bool, error) {
+	if err := c.lc.enter(); err != nil {
+		return nil, err
+	}
+	defer c.lc.leave()
+
+	return c.setIf(ctx, params, "setIfAbsent", c.handler.SetIfAbsent)
+}
+
+func (c *Cache[T]) SetIfPresent(ctx context.Context, params map[string]T) (map[string]bool, error) {
+	if err := c.lc.enter(); err != nil {
+		return nil, err
+	}
+	defer c.lc.leave()
+
+	return c.setIf(ctx, params, "setIfPresent", c.handler.SetIfPresent)
+}
+
+func (c *Cache[T]) CompareAndSwap(ctx context.Context, params map[string]Versioned[T]) (map[string]bool, error) {
+	if err := c.lc.enter(); err != nil {
+		return nil, err
+	}
+	defer c.lc.leave()
+
+	kv, err := buildSwap(&c.opts.Base, params)
+	if err != nil {
+		return nil, err
+	}
+	var written map[string]bool
+	err = callTier(ctx, 0, "compareAndSwap", c.opts.Timeouts.Set, func(ctx context.Context) (err error) {
+		written, err = c.handler.CompareAndSwap(ctx, kv, c.opts.Expire)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return c.opts.splitResult(written), nil
+}
+
+func (c *Cache[T]) SetWithTags(ctx context.Context, params map[string]T, tags map[string][]string) error {
+	if err := c.lc.enter(); err != nil {
+		return err
+	}
+	defer c.lc.leave()
+
+	if !client.Supports[client.ITagAdaptor](c.handler) {
+		return client.ErrNotSupported
+	}
+	tagger := c.handler.(client.ITagAdaptor)
+	if err := c.set(ctx, params); err != nil {
+		return err
+	}
+	return callTier(ctx, 0, "set", c.opts.Timeouts.Set, func(ctx context.Context) error {
+		return tagger.AddTags(ctx, c.opts.buildTags(tags), c.opts.Expire)
+	})
+}
+
+func (c *Cache[T]) DelByTag(ctx context.Context, tags []string) error {
+	if err := c.lc.enter(); err != nil {
+		return err
+	}
+	defer c.lc.leave()
+
+	if !client.Supports[client.ITagAdaptor](c.handler) {
+		return client.ErrNotSupported
+	}
+	tagger := c.handler.(client.ITagAdaptor)
+
+	var (
+		tagKeys = c.opts.buildTagKeys(tags)
+		keys    []string
+	)
+	err := callTier(ctx, 0, "get", c.opts.Timeouts.Get, func(ctx context.Context) (err error) {
+		keys, err = tagger.TagKeys(ctx, tagKeys)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return callTier(ctx, 0, "del", c.opts.Timeouts.Del, func(ctx context.Context) error {
+		if len(keys) > 0 {
+			if err := c.handler.Del(ctx, keys); err != nil {
+				return err
+			}
+		}
+		return tagger.DelTags(ctx, tagKeys)
+	})
+}
+
+// Close 拒绝新的调用并在ctx到期前等待进行中的调用（包括加载函数）结束，之后关闭适配器
+// 等待超时仍会关闭适配器，返回的错误包含 ctx.Err()
+func (c *Cache[T]) Close(ctx context.Context) error {
+	err := c.lc.shutdown(ctx)
+	if errors.Is(err, ErrClosed) {
+		return err
+	}
+
+	return errors.Join(err, c.inv.close(ctx), c.handler.Close())
+}
+
+func (c *Cache[T]) set(ctx context.Context, params map[string]T) error {
 	kv := make(map[string][]byte)
 	for k, v := range params {
 		key := c.opts.buildKey(k)
@@ -74,14 +300,24 @@ func (c *Cache[T]) Set(ctx context.Context, params map[string]T) error {
 		kv[key] = b
 	}
 
-	return c.handler.Set(ctx, kv, c.opts.Expire)
+	setCtx, cancel := withTimeout(ctx, c.opts.Timeouts.Set)
+	defer cancel()
+	err := c.handler.Set(setCtx, kv, c.opts.Expire)
+	return wrapTimeout(ctx, setCtx, err, "set", 0, c.opts.Timeouts.Set)
 }
 
-func (c *Cache[T]) Get(ctx context.Context, keys []string) (map[string]T, error) {
+func (c *Cache[T]) get(ctx context.Context, keys []string) (map[string]T, error) {
 	var (
 		tmpKeys = c.opts.buildKeys(keys)
 	)
-	kv, err := c.handler.Get(ctx, tmpKeys)
+	lookupCtx, cancelLookup := withTimeout(ctx, c.opts.Timeouts.Lookup)
+	defer cancelLookup()
+	getCtx, cancel := withTimeout(lookupCtx, c.opts.Timeouts.Get)
+	defer cancel()
+
+	kv, err := c.handler.Get(getCtx, tmpKeys)
+	err = wrapTimeout(lookupCtx, getCtx, err, "get", 0, c.opts.Timeouts.Get)
+	err = wrapTimeout(ctx, lookupCtx, err, "lookup", NoTier, c.opts.Timeouts.Lookup)
 	if err != nil {
 		return nil, err
 	}
@@ -98,12 +334,118 @@ func (c *Cache[T]) Get(ctx context.Context, keys []string) (map[string]T, error)
 		out[key] = obj
 	}
 
+	// 滑动过期，命中的数据刷新过期时间
+	if c.opts.Sliding && len(kv) > 0 {
+		err = callTier(ctx, 0, "expire", c.opts.Timeouts.Set, func(ctx context.Context) error {
+			return c.handler.Expire(ctx, hitKeys(kv), c.opts.Expire)
+		})
+		if err != nil {
+			fmt.Println(err)
+		}
+	}
+
 	return out, nil
 }
 
-// GetAndSet 缓存 miss，支持调用f函数从其它db中获取数据
-func (c *Cache[T]) GetAndSet(ctx context.Context, keys []string, f func(keys []string) (map[string]T, error)) (map[string]T, error) {
-	kv, err := c.Get(ctx, keys)
+func (c *Cache[T]) getWithTTL(ctx context.Context, keys []string) (map[string]Item[T], error) {
+	var (
+		tmpKeys = c.opts.buildKeys(keys)
+	)
+	lookupCtx, cancelLookup := withTimeout(ctx, c.opts.Timeouts.Lookup)
+	defer cancelLookup()
+	getCtx, cancel := withTimeout(lookupCtx, c.opts.Timeouts.Get)
+	defer cancel()
+
+	kv, err := c.handler.GetWithTTL(getCtx, tmpKeys)
+	err = wrapTimeout(lookupCtx, getCtx, err, "get", 0, c.opts.Timeouts.Get)
+	err = wrapTimeout(ctx, lookupCtx, err, "lookup", NoTier, c.opts.Timeouts.Lookup)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]Item[T])
+	for k, v := range kv {
+		var obj T
+		err = sonic.Unmarshal(v.Value, &obj)
+		if err != nil {
+			return nil, err
+		}
+
+		out[c.opts.splitKey(k)] = Item[T]{Value: obj, TTL: v.TTL}
+	}
+
+	return out, nil
+}
+
+func (c *Cache[T]) ttl(ctx context.Context, keys []string) (map[string]time.Duration, error) {
+	var (
+		tmpKeys = c.opts.buildKeys(keys)
+		kv      map[string]time.Duration
+	)
+	err := callTier(ctx, 0, "get", c.opts.Timeouts.Get, func(ctx context.Context) (err error) {
+		kv, err = c.handler.TTL(ctx, tmpKeys)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]time.Duration)
+	for k, v := range kv {
+		out[c.opts.splitKey(k)] = v
+	}
+
+	return out, nil
+}
+
+func (c *Cache[T]) expire(ctx context.Context, keys []string, expire time.Duration) error {
+	tmpKeys := c.opts.buildKeys(keys)
+	return callTier(ctx, 0, "expire", c.opts.Timeouts.Set, func(ctx context.Context) error {
+		return c.handler.Expire(ctx, tmpKeys, expire)
+	})
+}
+
+func (c *Cache[T]) getWithVersion(ctx context.Context, keys []string) (map[string]Versioned[T], error) {
+	var kv map[string][]byte
+	err := callTier(ctx, 0, "get", c.opts.Timeouts.Get, func(ctx context.Context) (err error) {
+		kv, err = c.handler.Get(ctx, c.opts.buildKeys(keys))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return versioned[T](&c.opts.Base, kv)
+}
+
+// setIf 序列化后执行条件写入
+func (c *Cache[T]) setIf(ctx context.Context, params map[string]T, op string, f func(ctx context.Context, params map[string][]byte, expire time.Duration) (map[string]bool, error)) (map[string]bool, error) {
+	kv := make(map[string][]byte, len(params))
+	for k, v := range params {
+		b, err := sonic.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		kv[c.opts.buildKey(k)] = b
+	}
+
+	var written map[string]bool
+	err := callTier(ctx, 0, op, c.opts.Timeouts.Set, func(ctx context.Context) (err error) {
+		written, err = f(ctx, kv, c.opts.Expire)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return c.opts.splitResult(written), nil
+}
+
+func (c *Cache[T]) getAndSet(ctx context.Context, keys []string, f func(keys []string) (map[string]T, error)) (map[string]T, error) {
+	kv, err := c.get(ctx, keys)
+	if errors.Is(err, client.ErrCircuitOpen) {
+		kv, err = make(map[string]T), nil // 熔断按缓存miss处理，直接回源
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -121,7 +463,9 @@ func (c *Cache[T]) GetAndSet(ctx context.Context, keys []string, f func(keys []s
 		return kv, nil
 	}
 
-	tmpKv, err := f(missKeys)
+	tmpKv, err := runLoader(ctx, &c.lc, c.opts.Timeouts.Loader, func() (map[string]T, error) {
+		return f(missKeys)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -140,7 +484,7 @@ func (c *Cache[T]) GetAndSet(ctx context.Context, keys []string, f func(keys []s
 		}
 	}
 	if len(tmpKv) > 0 {
-		err = c.Set(ctx, tmpKv)
+		err = c.set(ctx, tmpKv)
 		if err != nil {
 			// todo 打印日志就好了，不影响后续流程，下次请求再次尝试加载到缓存
 			fmt.Println(err)
@@ -150,13 +494,16 @@ func (c *Cache[T]) GetAndSet(ctx context.Context, keys []string, f func(keys []s
 	return kv, nil
 }
 
-func (c *Cache[T]) GetAndSetSingle(ctx context.Context, k string, f func(k string) (T, bool, error)) (T, bool, error) {
+func (c *Cache[T]) getAndSetSingle(ctx context.Context, k string, f func(k string) (T, bool, error)) (T, bool, error) {
 	var (
 		val T
 		ok  bool
 	)
 
-	kvMap, err := c.Get(ctx, []string{k})
+	kvMap, err := c.get(ctx, []string{k})
+	if errors.Is(err, client.ErrCircuitOpen) {
+		kvMap, err = make(map[string]T), nil // 熔断按缓存miss处理，直接回源
+	}
 	if err != nil {
 		return val, false, err
 	}
@@ -171,16 +518,16 @@ func (c *Cache[T]) GetAndSetSingle(ctx context.Context, k string, f func(k strin
 	}
 
 	// 单飞查询
-	_, err, _ = c.sf.Do(k, func() (interface{}, error) {
-		val, ok, err = f(k)
-		if err != nil {
-			return nil, err
-		}
-		return val, nil
+	res, err, _ := c.sf.Do(k, func() (interface{}, error) {
+		return runLoader(ctx, &c.lc, c.opts.Timeouts.Loader, func() (single[T], error) {
+			val, ok, err := f(k)
+			return single[T]{val: val, ok: ok}, err
+		})
 	})
 	if err != nil {
 		return val, false, err
 	}
+	val, ok = res.(single[T]).val, res.(single[T]).ok
 
 	// 写入缓存
 	var (
@@ -190,7 +537,7 @@ func (c *Cache[T]) GetAndSetSingle(ctx context.Context, k string, f func(k strin
 	// 写入缓存条件：1、数据存在；2、数据不存在并且WriteNil为true
 	if ok || (c.opts.WriteNil && !ok) {
 		tmpKvMap[k] = val
-		err = c.Set(ctx, tmpKvMap)
+		err = c.set(ctx, tmpKvMap)
 		if err != nil {
 			fmt.Println(err)
 		}
@@ -199,9 +546,12 @@ func (c *Cache[T]) GetAndSetSingle(ctx context.Context, k string, f func(k strin
 	return val, ok, nil
 }
 
-func (c *Cache[T]) Del(ctx context.Context, keys []string) error {
+func (c *Cache[T]) del(ctx context.Context, keys []string) error {
 	var (
 		tmpKeys = c.opts.buildKeys(keys)
 	)
-	return c.handler.Del(ctx, tmpKeys)
+	delCtx, cancel := withTimeout(ctx, c.opts.Timeouts.Del)
+	defer cancel()
+	err := c.handler.Del(delCtx, tmpKeys)
+	return wrapTimeout(ctx, delCtx, err, "del", 0, c.opts.Timeouts.Del)
 }
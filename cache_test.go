@@ -2,8 +2,10 @@ package tmpcache
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/PycMono/go-cache/client"
+	"github.com/PycMono/go-cache/client/local"
 	"github.com/PycMono/go-cache/client/mem"
 	"github.com/PycMono/go-cache/client/redis"
 	"github.com/bytedance/sonic"
@@ -26,9 +28,17 @@ func getRedisAdaptor() client.IAdaptor {
 }
 
 func getMemAdaptor() client.IAdaptor {
-	conf := mem.Config{}.WithCacheSize(1024 * 1024 * 1024).WithGCPercent(20)
+	return newMemAdaptor(1024 * 1024 * 1024)
+}
+
+func newMemAdaptor(cacheSize int) client.IAdaptor {
+	conf := mem.Config{}.WithCacheSize(cacheSize)
+	memClient, err := mem.NewMemCache(&conf)
+	if err != nil {
+		panic(err)
+	}
 
-	return mem.NewMemoryAdaptor(mem.NewMemCache(&conf))
+	return mem.NewMemoryAdaptor(memClient)
 }
 
 func TestSet(t *testing.T) {
@@ -177,3 +187,135 @@ func TestName(t *testing.T) {
 	b, _ := sonic.Marshal(respMap)
 	fmt.Println(string(b))
 }
+
+func TestClose(t *testing.T) {
+	type person struct {
+		Name string `json:"name"`
+		Age  int    `json:"age"`
+	}
+	mgr := NewCache[*person](newMemAdaptor(1024*1024), &Options{
+		Base:   Base{Prefix: "demo"},
+		Expire: time.Minute,
+	})
+
+	// 加载函数一直阻塞，Close 等待到 ctx 超时
+	var (
+		release = make(chan struct{})
+		started = make(chan struct{})
+	)
+	go mgr.GetAndSetSingle(context.TODO(), "1234", func(k string) (*person, bool, error) {
+		close(started)
+		<-release
+		return &person{Name: "113"}, true, nil
+	})
+	<-started
+	it := mgr.Scan(context.TODO(), "*")
+
+	ctx, cancel := context.WithTimeout(context.TODO(), 50*time.Millisecond)
+	defer cancel()
+	err := mgr.Close(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		panic(err)
+	}
+	close(release)
+
+	// 关闭前创建的迭代器不再读取适配器
+	if it.Next(context.TODO()) || !errors.Is(it.Err(), ErrClosed) {
+		t.Fatalf("err = %v, want ErrClosed", it.Err())
+	}
+	_, err = mgr.Get(context.TODO(), []string{"1234"})
+	if !errors.Is(err, ErrClosed) {
+		panic(err)
+	}
+	if err = mgr.Close(context.TODO()); !errors.Is(err, ErrClosed) {
+		panic(err)
+	}
+}
+
+func newLocalAdaptor[T any]() client.ITypedAdaptor[T] {
+	conf := local.Config{}
+	adaptor, err := local.NewLocalAdaptor[T](&conf)
+	if err != nil {
+		panic(err)
+	}
+
+	return adaptor
+}
+
+func TestLocalCache(t *testing.T) {
+	type person struct {
+		Name string `json:"name"`
+		Age  int    `json:"age"`
+	}
+	var (
+		opts       = &MultiCacheOptions{Base: Base{Prefix: "demo"}, Expire: time.Minute}
+		memAdaptor = newMemAdaptor(1024 * 1024)
+	)
+
+	// 第1级缓存有数据，查询后回写进程内缓存
+	err := NewMultiCache[*person](opts, memAdaptor).Set(context.TODO(), map[string]*person{"1": {Name: "111"}})
+	if err != nil {
+		panic(err)
+	}
+	cache := NewMultiCacheWithLocal[*person](opts, newLocalAdaptor[*person](), memAdaptor)
+	first, err := cache.Get(context.TODO(), []string{"1"})
+	if err != nil {
+		panic(err)
+	}
+	second, err := cache.Get(context.TODO(), []string{"1"})
+	if err != nil {
+		panic(err)
+	}
+	if first["1"] == nil || first["1"] != second["1"] {
+		t.Fatalf("first = %v, second = %v, want same object from local tier", first["1"], second["1"])
+	}
+
+	// 删除同时删除进程内缓存
+	if err = cache.Del(context.TODO(), []string{"1"}); err != nil {
+		panic(err)
+	}
+	kvMap, _ := cache.Get(context.TODO(), []string{"1"})
+	if len(kvMap) != 0 {
+		t.Fatalf("kvMap = %v, want empty", kvMap)
+	}
+}
+
+func BenchmarkGetMem(b *testing.B) {
+	type person struct {
+		Name string `json:"name"`
+		Age  int    `json:"age"`
+	}
+	cache := NewCache[*person](newMemAdaptor(64*1024*1024), &Options{Base: Base{Prefix: "demo"}, Expire: time.Hour})
+	benchmarkGet(b, cache, &person{Name: "李四12", Age: 20})
+}
+
+func BenchmarkGetLocal(b *testing.B) {
+	type person struct {
+		Name string `json:"name"`
+		Age  int    `json:"age"`
+	}
+	cache := NewLocalCache[*person](newLocalAdaptor[*person](), &Options{Base: Base{Prefix: "demo"}, Expire: time.Hour})
+	benchmarkGet(b, cache, &person{Name: "李四12", Age: 20})
+}
+
+func benchmarkGet[T any](b *testing.B, cache ICache[T], val T) {
+	keys := make([]string, 1000)
+	kvMap := make(map[string]T)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("%d", i)
+		kvMap[keys[i]] = val
+	}
+	if err := cache.Set(context.TODO(), kvMap); err != nil {
+		panic(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		var i int
+		for pb.Next() {
+			_, _ = cache.Get(context.TODO(), keys[i%len(keys):i%len(keys)+1])
+			i++
+		}
+	})
+}
@@ -0,0 +1,41 @@
+package tmpcache
+
+import (
+	"github.com/PycMono/go-cache/client"
+	"github.com/bytedance/sonic"
+)
+
+// buildSwap 构造key并序列化 CompareAndSwap 的新数据
+func buildSwap[T any](b *Base, params map[string]Versioned[T]) (map[string]client.Swap, error) {
+	out := make(map[string]client.Swap, len(params))
+	for k, v := range params {
+		value, err := sonic.Marshal(v.Value)
+		if err != nil {
+			return nil, err
+		}
+		out[b.buildKey(k)] = client.Swap{Value: value, Version: v.Version}
+	}
+	return out, nil
+}
+
+// versioned 反序列化数据并计算版本号，返回的key已切割前缀
+func versioned[T any](b *Base, kv map[string][]byte) (map[string]Versioned[T], error) {
+	out := make(map[string]Versioned[T], len(kv))
+	for k, v := range kv {
+		var obj T
+		if err := sonic.Unmarshal(v, &obj); err != nil {
+			return nil, err
+		}
+		out[b.splitKey(k)] = Versioned[T]{Value: obj, Version: client.Version(v)}
+	}
+	return out, nil
+}
+
+// splitResult 条件写入的结果切割key前缀
+func (c *Base) splitResult(written map[string]bool) map[string]bool {
+	out := make(map[string]bool, len(written))
+	for k, v := range written {
+		out[c.splitKey(k)] = v
+	}
+	return out
+}
@@ -0,0 +1,133 @@
+package cdc
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// binlog 行事件的动作，和 go-mysql canal 的 RowsEvent.Action 一致
+const (
+	BinlogInsert = "insert"
+	BinlogUpdate = "update"
+	BinlogDelete = "delete"
+)
+
+// BinlogPosition binlog 文件位置
+type BinlogPosition struct {
+	Name string
+	Pos  uint32
+}
+
+func (p BinlogPosition) String() string {
+	return fmt.Sprintf("%s:%d", p.Name, p.Pos)
+}
+
+// BinlogRowsEvent MySQL binlog 的行事件，字段和 go-mysql canal 的 RowsEvent 对应
+// update 事件的 Rows 按 变更前、变更后 成对排列
+type BinlogRowsEvent struct {
+	Schema   string
+	Table    string
+	Action   string
+	Columns  []string // 列名，和 Rows 中每一行的下标对应
+	Rows     [][]any
+	Position BinlogPosition
+}
+
+// binlogSource 把 binlog 行事件转换为 Event
+type binlogSource struct {
+	ch      <-chan BinlogRowsEvent
+	commit  func(ctx context.Context, position string) error
+	pending []Event
+}
+
+// NewBinlogSource 从channel读取 binlog 行事件，由业务在 binlog 客户端（例如 go-mysql canal 的 OnRow）中推送
+// 一个行事件包含多行时拆分为多个 Event，commit 用于保存已处理完成的位置，可以为nil
+func NewBinlogSource(ch <-chan BinlogRowsEvent, commit func(ctx context.Context, position string) error) ChangeSource {
+	return &binlogSource{ch: ch, commit: commit}
+}
+
+func (s *binlogSource) Next(ctx context.Context) (Event, error) {
+	for len(s.pending) == 0 {
+		select {
+		case e, ok := <-s.ch:
+			if !ok {
+				return Event{}, io.EOF
+			}
+			events, err := convertRows(e)
+			if err != nil {
+				return Event{}, err
+			}
+			s.pending = events
+		case <-ctx.Done():
+			return Event{}, ctx.Err()
+		}
+	}
+
+	e := s.pending[0]
+	s.pending = s.pending[1:]
+	return e, nil
+}
+
+func (s *binlogSource) Commit(ctx context.Context, position string) error {
+	if s.commit == nil {
+		return nil
+	}
+	return s.commit(ctx, position)
+}
+
+func (s *binlogSource) Close() error {
+	return nil
+}
+
+// convertRows 拆分行事件，只有最后一行带位置，避免提交后重启丢失同一个行事件中未处理的行
+func convertRows(e BinlogRowsEvent) ([]Event, error) {
+	row := func(values []any) (map[string]any, error) {
+		if len(values) != len(e.Columns) {
+			return nil, fmt.Errorf("cdc: %s.%s row has %d values, want %d columns", e.Schema, e.Table, len(values), len(e.Columns))
+		}
+		out := make(map[string]any, len(values))
+		for i, v := range values {
+			out[e.Columns[i]] = v
+		}
+		return out, nil
+	}
+
+	var events []Event
+	switch e.Action {
+	case BinlogInsert, BinlogDelete:
+		for _, values := range e.Rows {
+			r, err := row(values)
+			if err != nil {
+				return nil, err
+			}
+			event := Event{Schema: e.Schema, Table: e.Table, Op: OpInsert, After: r}
+			if e.Action == BinlogDelete {
+				event = Event{Schema: e.Schema, Table: e.Table, Op: OpDelete, Before: r}
+			}
+			events = append(events, event)
+		}
+	case BinlogUpdate:
+		if len(e.Rows)%2 != 0 {
+			return nil, fmt.Errorf("cdc: %s.%s update has odd number of rows", e.Schema, e.Table)
+		}
+		for i := 0; i < len(e.Rows); i += 2 {
+			before, err := row(e.Rows[i])
+			if err != nil {
+				return nil, err
+			}
+			after, err := row(e.Rows[i+1])
+			if err != nil {
+				return nil, err
+			}
+			events = append(events, Event{Schema: e.Schema, Table: e.Table, Op: OpUpdate, Before: before, After: after})
+		}
+	default:
+		return nil, fmt.Errorf("cdc: unknown binlog action %q", e.Action)
+	}
+
+	if len(events) > 0 {
+		events[len(events)-1].Position = e.Position.String()
+	}
+	return events, nil
+}
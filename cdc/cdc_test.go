@@ -0,0 +1,166 @@
+package cdc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/PycMono/go-cache/client"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// recorder 记录删除的key，fails 大于0时删除失败
+type recorder struct {
+	sm    sync.Mutex
+	keys  []string
+	fails int
+}
+
+func (r *recorder) Del(ctx context.Context, keys []string) error {
+	r.sm.Lock()
+	defer r.sm.Unlock()
+
+	if r.fails > 0 {
+		r.fails--
+		return errors.New("del failed")
+	}
+	r.keys = append(r.keys, keys...)
+	return nil
+}
+
+func (r *recorder) sorted() []string {
+	r.sm.Lock()
+	defer r.sm.Unlock()
+
+	out := append([]string(nil), r.keys...)
+	sort.Strings(out)
+	return out
+}
+
+func TestBinlogSource(t *testing.T) {
+	var (
+		ch        = make(chan BinlogRowsEvent, 3)
+		committed []string
+		refreshed []string
+		target    = &recorder{fails: 1}
+		retry     = client.RetryPolicy{}.WithMaxAttempts(2).WithRetryable(func(err error) bool { return true })
+	)
+	ch <- BinlogRowsEvent{
+		Schema: "shop", Table: "user", Action: BinlogInsert,
+		Columns: []string{"id", "name"}, Rows: [][]any{{1, []byte("a")}, {2, []byte("b")}},
+		Position: BinlogPosition{Name: "binlog.000001", Pos: 100},
+	}
+	// 修改了 name，变更前后的key都需要删除
+	ch <- BinlogRowsEvent{
+		Schema: "shop", Table: "user", Action: BinlogUpdate,
+		Columns: []string{"id", "name"}, Rows: [][]any{{3, "old"}, {3, "new"}},
+		Position: BinlogPosition{Name: "binlog.000001", Pos: 200},
+	}
+	ch <- BinlogRowsEvent{
+		Schema: "shop", Table: "order", Action: BinlogDelete,
+		Columns: []string{"id", "uid"}, Rows: [][]any{{9, 3}},
+		Position: BinlogPosition{Name: "binlog.000001", Pos: 300},
+	}
+	close(ch)
+
+	source := NewBinlogSource(ch, func(ctx context.Context, position string) error {
+		committed = append(committed, position)
+		return nil
+	})
+	consumer, err := NewConsumer(source, target, &Options{
+		Rules: []Rule{
+			{Schema: "shop", Table: "user", Key: "user:{id}"},
+			{Table: "user", Key: "user_name:{name}", Refresh: func(ctx context.Context, keys []string) error {
+				refreshed = append(refreshed, keys...)
+				return nil
+			}},
+			{Table: "order", Keys: func(e Event, row map[string]any) []string {
+				return []string{fmt.Sprint("orders_of_user:", row["uid"])}
+			}},
+		},
+		Retry: &retry,
+	})
+	if err != nil {
+		panic(err)
+	}
+	if err = consumer.Run(context.TODO()); err != nil {
+		panic(err)
+	}
+
+	want := []string{"orders_of_user:3", "user:1", "user:2", "user:3", "user_name:a", "user_name:b", "user_name:new", "user_name:old"}
+	if keys := target.sorted(); !reflect.DeepEqual(keys, want) {
+		t.Fatalf("keys = %v, want %v", keys, want)
+	}
+	if len(refreshed) != 4 {
+		t.Fatalf("refreshed = %v, want 4 keys", refreshed)
+	}
+	// 一个行事件只在最后一行提交位置
+	if want := []string{"binlog.000001:100", "binlog.000001:200", "binlog.000001:300"}; !reflect.DeepEqual(committed, want) {
+		t.Fatalf("committed = %v, want %v", committed, want)
+	}
+
+	if _, err = NewConsumer(source, target, &Options{Rules: []Rule{{Table: "user", Key: "user:{id"}}}); err == nil {
+		t.Fatalf("want error for invalid template")
+	}
+}
+
+func TestFileSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	lines := []string{
+		`{"schema":"shop","table":"user","op":0,"after":{"id":1}}`,
+		`{"schema":"shop","table":"user","op":2,"before":{"id":2}}`,
+		`{"schema":"shop","table":"user","op":1,"before":{"id":3},"after":{"id":3}}`,
+		`{"schema":"shop","table":"user","op":0,"after":{"id":1000000}}`,
+	}
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0o644); err != nil {
+		panic(err)
+	}
+
+	// 第一次只处理第1行
+	target := &recorder{}
+	source, err := NewFileSource(path)
+	if err != nil {
+		panic(err)
+	}
+	noRetry := client.RetryPolicy{}
+	consumer, _ := NewConsumer(source, target, &Options{Rules: []Rule{{Table: "user", Key: "user:{id}"}}, Retry: &noRetry})
+	e, _ := source.Next(context.TODO())
+	if err = consumer.Handle(context.TODO(), e); err != nil {
+		panic(err)
+	}
+	_ = source.Commit(context.TODO(), e.Position)
+	_ = consumer.Close()
+
+	// 重新打开后从第2行继续
+	source, err = NewFileSource(path)
+	if err != nil {
+		panic(err)
+	}
+	consumer, _ = NewConsumer(source, target, &Options{Rules: []Rule{{Table: "user", Key: "user:{id}"}}, Retry: &noRetry})
+	if err = consumer.Run(context.TODO()); err != nil {
+		panic(err)
+	}
+	_ = consumer.Close()
+	if keys := target.sorted(); !reflect.DeepEqual(keys, []string{"user:1", "user:1000000", "user:2", "user:3"}) {
+		t.Fatalf("keys = %v", keys)
+	}
+}
+
+func TestFormatValue(t *testing.T) {
+	for v, want := range map[any]string{
+		float64(1000000): "1000000",
+		1.5:              "1.5",
+		float32(2.25):    "2.25",
+		"a":              "a",
+		7:                "7",
+	} {
+		if got := formatValue(v); got != want {
+			t.Fatalf("formatValue(%v) = %s, want %s", v, got, want)
+		}
+	}
+}
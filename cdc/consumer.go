@@ -0,0 +1,120 @@
+package cdc
+
+import (
+	"context"
+	"errors"
+	"github.com/PycMono/go-cache/client"
+	"io"
+)
+
+// Target 失效的缓存，tmpcache.ICache 可以直接使用，多级缓存会从所有缓存中删除
+type Target interface {
+	Del(ctx context.Context, keys []string) error
+}
+
+// Options Consumer 配置
+type Options struct {
+	Rules []Rule
+	// 删除、重新加载、提交位置的重试策略，默认 client.DefaultRetryPolicy 且所有错误都重试
+	Retry *client.RetryPolicy
+}
+
+// getRetry 获取重试策略
+func (o *Options) getRetry() client.RetryPolicy {
+	if o.Retry == nil {
+		return client.DefaultRetryPolicy().WithRetryable(func(err error) bool { return true })
+	}
+	return *o.Retry
+}
+
+// Consumer 消费变更流，按规则把行变更转换为缓存key并失效
+// 事件处理完成后才提交位置，重试用完仍失败时 Run 返回错误，重启后从上次提交的位置重新处理（至少一次）
+type Consumer struct {
+	source ChangeSource
+	target Target
+	rules  []Rule
+	retry  client.RetryPolicy
+}
+
+func NewConsumer(source ChangeSource, target Target, opts *Options) (*Consumer, error) {
+	rules := make([]Rule, len(opts.Rules))
+	copy(rules, opts.Rules)
+	for i := range rules {
+		if err := rules[i].compile(); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Consumer{
+		source: source,
+		target: target,
+		rules:  rules,
+		retry:  opts.getRetry(),
+	}, nil
+}
+
+// Run 持续处理事件，变更流结束返回nil，ctx结束返回 ctx.Err()
+func (c *Consumer) Run(ctx context.Context) error {
+	for {
+		e, err := c.source.Next(ctx)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if err = c.Handle(ctx, e); err != nil {
+			return err
+		}
+		if e.Position == "" {
+			continue
+		}
+		err = c.retry.Do(ctx, func() error {
+			return c.source.Commit(ctx, e.Position)
+		})
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Handle 处理一个事件，所有匹配的规则都会执行
+func (c *Consumer) Handle(ctx context.Context, e Event) error {
+	for i := range c.rules {
+		r := &c.rules[i]
+		if !r.match(e) {
+			continue
+		}
+
+		keys, err := r.keys(e)
+		if err != nil {
+			return err
+		}
+		if len(keys) == 0 {
+			continue
+		}
+
+		err = c.retry.Do(ctx, func() error {
+			return c.target.Del(ctx, keys)
+		})
+		if err != nil {
+			return err
+		}
+		if r.Refresh == nil || e.Op == OpDelete {
+			continue
+		}
+		err = c.retry.Do(ctx, func() error {
+			return r.Refresh(ctx, keys)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close 关闭变更流
+func (c *Consumer) Close() error {
+	return c.source.Close()
+}
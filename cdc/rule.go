@@ -0,0 +1,140 @@
+package cdc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Rule 表的行变更和缓存key的对应关系
+type Rule struct {
+	Schema string // 库名，为空匹配所有库
+	Table  string // 表名
+	Ops    []Op   // 处理的变更类型，为空处理全部
+	// key模板，{列名} 替换为该列的值，例如 "user:{id}"，变更前后的行分别生成key
+	Key string
+	// 自定义生成key，设置后忽略 Key，row 为变更前或变更后的行
+	Keys func(e Event, row map[string]any) []string
+	// 删除后重新加载，例如调用 LoadingCache.Get，为nil时只删除
+	Refresh func(ctx context.Context, keys []string) error
+
+	segments []segment
+}
+
+// segment key模板的一段，column 不为空时表示列的值
+type segment struct {
+	text   string
+	column string
+}
+
+// compile 解析key模板
+func (r *Rule) compile() error {
+	if r.Table == "" {
+		return fmt.Errorf("cdc: rule table is empty")
+	}
+	if r.Keys != nil {
+		return nil
+	}
+	if r.Key == "" {
+		return fmt.Errorf("cdc: rule %s requires Key or Keys", r.Table)
+	}
+
+	r.segments = r.segments[:0]
+	rest := r.Key
+	for len(rest) > 0 {
+		i := strings.IndexByte(rest, '{')
+		if i < 0 {
+			r.segments = append(r.segments, segment{text: rest})
+			break
+		}
+		j := strings.IndexByte(rest[i:], '}')
+		if j < 0 || j == 1 {
+			return fmt.Errorf("cdc: invalid key template %q", r.Key)
+		}
+		if i > 0 {
+			r.segments = append(r.segments, segment{text: rest[:i]})
+		}
+		r.segments = append(r.segments, segment{column: rest[i+1 : i+j]})
+		rest = rest[i+j+1:]
+	}
+	return nil
+}
+
+func (r *Rule) match(e Event) bool {
+	if r.Table != e.Table || (r.Schema != "" && r.Schema != e.Schema) {
+		return false
+	}
+	if len(r.Ops) == 0 {
+		return true
+	}
+	for _, op := range r.Ops {
+		if op == e.Op {
+			return true
+		}
+	}
+	return false
+}
+
+// keys 变更前后的行生成的key，去重
+func (r *Rule) keys(e Event) ([]string, error) {
+	var (
+		out  []string
+		seen = make(map[string]struct{})
+	)
+	for _, row := range []map[string]any{e.Before, e.After} {
+		if row == nil {
+			continue
+		}
+
+		var keys []string
+		if r.Keys != nil {
+			keys = r.Keys(e, row)
+		} else {
+			key, err := r.render(e, row)
+			if err != nil {
+				return nil, err
+			}
+			keys = []string{key}
+		}
+		for _, k := range keys {
+			if _, ok := seen[k]; !ok {
+				seen[k] = struct{}{}
+				out = append(out, k)
+			}
+		}
+	}
+	return out, nil
+}
+
+func (r *Rule) render(e Event, row map[string]any) (string, error) {
+	var sb strings.Builder
+	for _, s := range r.segments {
+		if s.column == "" {
+			sb.WriteString(s.text)
+			continue
+		}
+		v, ok := row[s.column]
+		if !ok {
+			return "", fmt.Errorf("cdc: column %q not found in %s.%s", s.column, e.Schema, e.Table)
+		}
+		sb.WriteString(formatValue(v))
+	}
+	return sb.String(), nil
+}
+
+// formatValue 列的值转成key中的字符串，浮点数不使用科学计数法
+func formatValue(v any) string {
+	switch x := v.(type) {
+	case []byte:
+		return string(x)
+	case json.Number:
+		return x.String()
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(x), 'f', -1, 32)
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,148 @@
+package cdc
+
+import (
+	"bufio"
+	"context"
+	"github.com/bytedance/sonic"
+	"io"
+	"os"
+	"strconv"
+)
+
+// numberAPI 数字解析为 json.Number，避免大整数转成 float64 后变成科学计数法或丢失精度
+var numberAPI = sonic.Config{UseNumber: true}.Froze()
+
+// Op 行变更类型
+type Op int
+
+const (
+	OpInsert Op = iota
+	OpUpdate
+	OpDelete
+)
+
+func (o Op) String() string {
+	switch o {
+	case OpInsert:
+		return "insert"
+	case OpUpdate:
+		return "update"
+	case OpDelete:
+		return "delete"
+	}
+	return "unknown(" + strconv.Itoa(int(o)) + ")"
+}
+
+// Event 一行数据的变更
+type Event struct {
+	Schema   string         `json:"schema"`
+	Table    string         `json:"table"`
+	Op       Op             `json:"op"`
+	Before   map[string]any `json:"before,omitempty"` // 变更前的行，insert 为空
+	After    map[string]any `json:"after,omitempty"`  // 变更后的行，delete 为空
+	Position string         `json:"position"`         // 事件在变更流中的位置，处理完成后通过 Commit 提交
+}
+
+// ChangeSource 变更流
+type ChangeSource interface {
+	// Next 阻塞读取下一个事件，变更流结束返回 io.EOF
+	Next(ctx context.Context) (Event, error)
+	// Commit 提交已处理完成的位置，重启后从该位置之后继续读取
+	Commit(ctx context.Context, position string) error
+	Close() error
+}
+
+// chanSource 从channel读取事件，channel关闭表示变更流结束
+type chanSource struct {
+	ch <-chan Event
+}
+
+// NewChanSource 从channel读取事件的变更流，Commit 不做任何事，一般用于测试或由业务自行推送事件
+func NewChanSource(ch <-chan Event) ChangeSource {
+	return &chanSource{ch: ch}
+}
+
+func (s *chanSource) Next(ctx context.Context) (Event, error) {
+	select {
+	case e, ok := <-s.ch:
+		if !ok {
+			return Event{}, io.EOF
+		}
+		return e, nil
+	case <-ctx.Done():
+		return Event{}, ctx.Err()
+	}
+}
+
+func (s *chanSource) Commit(ctx context.Context, position string) error {
+	return nil
+}
+
+func (s *chanSource) Close() error {
+	return nil
+}
+
+// fileSource 从文件读取事件，每行一个JSON格式的 Event，位置为行号
+type fileSource struct {
+	f       *os.File
+	scanner *bufio.Scanner
+	line    int
+	posFile string
+	skip    int // 已提交的行数，重新打开时跳过
+}
+
+// NewFileSource 读取JSON行格式的事件文件，事件的位置为行号
+// 已提交的行号保存在 path+".pos"，重新打开时从该行之后继续
+func NewFileSource(path string) (ChangeSource, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &fileSource{
+		f:       f,
+		scanner: bufio.NewScanner(f),
+		posFile: path + ".pos",
+	}
+	s.scanner.Buffer(nil, 64*1024*1024)
+	if b, err := os.ReadFile(s.posFile); err == nil {
+		if s.skip, err = strconv.Atoi(string(b)); err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func (s *fileSource) Next(ctx context.Context) (Event, error) {
+	for {
+		if err := ctx.Err(); err != nil {
+			return Event{}, err
+		}
+		if !s.scanner.Scan() {
+			if err := s.scanner.Err(); err != nil {
+				return Event{}, err
+			}
+			return Event{}, io.EOF
+		}
+		s.line++
+		if s.line <= s.skip || len(s.scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var e Event
+		if err := numberAPI.Unmarshal(s.scanner.Bytes(), &e); err != nil {
+			return Event{}, err
+		}
+		e.Position = strconv.Itoa(s.line)
+		return e, nil
+	}
+}
+
+func (s *fileSource) Commit(ctx context.Context, position string) error {
+	return os.WriteFile(s.posFile, []byte(position), 0o644)
+}
+
+func (s *fileSource) Close() error {
+	return s.f.Close()
+}
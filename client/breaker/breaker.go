@@ -0,0 +1,389 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/PycMono/go-cache/client"
+	"sync"
+	"time"
+)
+
+// State 熔断器状态
+type State int32
+
+const (
+	StateClosed   State = iota // 关闭，请求正常通过
+	StateOpen                  // 打开，请求直接返回 client.ErrCircuitOpen
+	StateHalfOpen              // 半开，放行少量探测请求
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("unknown(%d)", int32(s))
+}
+
+type transition struct {
+	from, to State
+}
+
+// Cache 熔断适配器，包装任意 client.IAdaptor
+// 熔断打开时所有读写操作直接返回 client.ErrCircuitOpen，MultiCache 会跳过该级缓存继续查询下一级或回源
+type Cache struct {
+	client.IAdaptor // 被包装的适配器，未覆盖的方法（如 Close）直接透传
+	conf            Config
+
+	sm         sync.Mutex
+	state      State
+	generation uint64    // 每次状态变化或统计窗口重置加1，丢弃旧周期的统计结果
+	expiry     time.Time // 关闭状态为统计窗口结束时间，打开状态为熔断结束时间
+	total      int       // 请求数（半开状态为已放行的探测数）
+	failures   int       // 失败数
+	slows      int       // 慢调用数
+	successes  int       // 半开状态探测成功数
+	pending    []transition
+}
+
+func NewBreakerAdaptor(handler client.IAdaptor, conf *Config) (client.IAdaptor, error) {
+	c, err := conf.build()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Cache{
+		IAdaptor: handler,
+		conf:     c,
+		expiry:   time.Now().Add(c.window),
+	}, nil
+}
+
+func (b *Cache) Set(ctx context.Context, params map[string][]byte, expire time.Duration) error {
+	return b.do(func() error {
+		return b.IAdaptor.Set(ctx, params, expire)
+	})
+}
+
+func (b *Cache) Get(ctx context.Context, k []string) (map[string][]byte, error) {
+	var out map[string][]byte
+	err := b.do(func() (err error) {
+		out, err = b.IAdaptor.Get(ctx, k)
+		return err
+	})
+	return out, err
+}
+
+func (b *Cache) Del(ctx context.Context, k []string) error {
+	return b.do(func() error {
+		return b.IAdaptor.Del(ctx, k)
+	})
+}
+
+func (b *Cache) GetWithTTL(ctx context.Context, k []string) (map[string]client.Item, error) {
+	var out map[string]client.Item
+	err := b.do(func() (err error) {
+		out, err = b.IAdaptor.GetWithTTL(ctx, k)
+		return err
+	})
+	return out, err
+}
+
+func (b *Cache) TTL(ctx context.Context, k []string) (map[string]time.Duration, error) {
+	var out map[string]time.Duration
+	err := b.do(func() (err error) {
+		out, err = b.IAdaptor.TTL(ctx, k)
+		return err
+	})
+	return out, err
+}
+
+func (b *Cache) Expire(ctx context.Context, k []string, expire time.Duration) error {
+	return b.do(func() error {
+		return b.IAdaptor.Expire(ctx, k, expire)
+	})
+}
+
+func (b *Cache) SetIfAbsent(ctx context.Context, params map[string][]byte, expire time.Duration) (map[string]bool, error) {
+	var out map[string]bool
+	err := b.do(func() (err error) {
+		out, err = b.IAdaptor.SetIfAbsent(ctx, params, expire)
+		return err
+	})
+	return out, err
+}
+
+func (b *Cache) SetIfPresent(ctx context.Context, params map[string][]byte, expire time.Duration) (map[string]bool, error) {
+	var out map[string]bool
+	err := b.do(func() (err error) {
+		out, err = b.IAdaptor.SetIfPresent(ctx, params, expire)
+		return err
+	})
+	return out, err
+}
+
+func (b *Cache) CompareAndSwap(ctx context.Context, params map[string]client.Swap, expire time.Duration) (map[string]bool, error) {
+	var out map[string]bool
+	err := b.do(func() (err error) {
+		out, err = b.IAdaptor.CompareAndSwap(ctx, params, expire)
+		return err
+	})
+	return out, err
+}
+
+// Scan 每读取一批key经过一次熔断判断，熔断打开时迭代器返回 client.ErrCircuitOpen
+func (b *Cache) Scan(ctx context.Context, pattern string) client.KeyIterator {
+	it := b.IAdaptor.Scan(ctx, pattern)
+	return client.NewKeyIterator(func(ctx context.Context) ([]string, bool, error) {
+		var (
+			keys []string
+			done bool
+		)
+		err := b.do(func() error {
+			done = !it.Next(ctx)
+			keys = it.Keys()
+			return it.Err()
+		})
+		return keys, done, err
+	})
+}
+
+func (b *Cache) DelPrefix(ctx context.Context, prefix string) (int64, error) {
+	var n int64
+	err := b.do(func() (err error) {
+		n, err = b.IAdaptor.DelPrefix(ctx, prefix)
+		return err
+	})
+	return n, err
+}
+
+// Unwrap 返回被包装的适配器，用于 client.Supports 判断可选接口
+func (b *Cache) Unwrap() client.IAdaptor {
+	return b.IAdaptor
+}
+
+// IncrBy 被包装的适配器不支持计数时返回 client.ErrNotSupported
+func (b *Cache) IncrBy(ctx context.Context, params map[string]int64, expire time.Duration) (map[string]int64, error) {
+	counter, ok := b.IAdaptor.(client.ICounterAdaptor)
+	if !ok {
+		return nil, client.ErrNotSupported
+	}
+
+	var out map[string]int64
+	err := b.do(func() (err error) {
+		out, err = counter.IncrBy(ctx, params, expire)
+		return err
+	})
+	return out, err
+}
+
+// HSet 被包装的适配器不支持按字段读写时返回 client.ErrNotSupported
+func (b *Cache) HSet(ctx context.Context, params map[string]map[string][]byte, expire time.Duration) error {
+	hash, ok := b.IAdaptor.(client.IHashAdaptor)
+	if !ok {
+		return client.ErrNotSupported
+	}
+
+	return b.do(func() error {
+		return hash.HSet(ctx, params, expire)
+	})
+}
+
+func (b *Cache) HGet(ctx context.Context, k []string, fields []string) (map[string]map[string][]byte, error) {
+	hash, ok := b.IAdaptor.(client.IHashAdaptor)
+	if !ok {
+		return nil, client.ErrNotSupported
+	}
+
+	var out map[string]map[string][]byte
+	err := b.do(func() (err error) {
+		out, err = hash.HGet(ctx, k, fields)
+		return err
+	})
+	return out, err
+}
+
+// AddTags 被包装的适配器不支持标签时返回 client.ErrNotSupported
+func (b *Cache) AddTags(ctx context.Context, params map[string][]string, expire time.Duration) error {
+	tag, ok := b.IAdaptor.(client.ITagAdaptor)
+	if !ok {
+		return client.ErrNotSupported
+	}
+
+	return b.do(func() error {
+		return tag.AddTags(ctx, params, expire)
+	})
+}
+
+func (b *Cache) TagKeys(ctx context.Context, tags []string) ([]string, error) {
+	tag, ok := b.IAdaptor.(client.ITagAdaptor)
+	if !ok {
+		return nil, client.ErrNotSupported
+	}
+
+	var out []string
+	err := b.do(func() (err error) {
+		out, err = tag.TagKeys(ctx, tags)
+		return err
+	})
+	return out, err
+}
+
+func (b *Cache) DelTags(ctx context.Context, tags []string) error {
+	tag, ok := b.IAdaptor.(client.ITagAdaptor)
+	if !ok {
+		return client.ErrNotSupported
+	}
+
+	return b.do(func() error {
+		return tag.DelTags(ctx, tags)
+	})
+}
+
+// State 当前熔断器状态
+func (b *Cache) State() State {
+	b.sm.Lock()
+	b.refresh(time.Now())
+	state := b.state
+	b.sm.Unlock()
+	b.notify()
+
+	return state
+}
+
+func (b *Cache) do(f func() error) error {
+	generation, err := b.allow()
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	err = f()
+	b.record(generation, err, time.Since(start))
+	return err
+}
+
+// allow 判断请求是否放行
+func (b *Cache) allow() (uint64, error) {
+	b.sm.Lock()
+	defer b.notify()
+	defer b.sm.Unlock()
+
+	b.refresh(time.Now())
+	switch b.state {
+	case StateOpen:
+		return 0, client.ErrCircuitOpen
+	case StateHalfOpen:
+		if b.total >= b.conf.halfOpenMaxCalls {
+			return 0, client.ErrCircuitOpen
+		}
+		b.total++
+	}
+
+	return b.generation, nil
+}
+
+// record 记录请求结果并判断是否需要切换状态
+func (b *Cache) record(generation uint64, err error, cost time.Duration) {
+	b.sm.Lock()
+	defer b.notify()
+	defer b.sm.Unlock()
+
+	b.refresh(time.Now())
+	if generation != b.generation {
+		return // 已经不是同一个统计周期
+	}
+
+	var (
+		failed = err != nil && !errors.Is(err, context.Canceled) // 调用方主动取消不算失败
+		slow   = b.conf.slowCall > 0 && cost >= b.conf.slowCall
+	)
+	switch b.state {
+	case StateClosed:
+		b.total++
+		if failed {
+			b.failures++
+		}
+		if slow {
+			b.slows++
+		}
+		if b.total < b.conf.minRequests {
+			return
+		}
+		if float64(b.failures)/float64(b.total) >= b.conf.failureRate ||
+			(b.conf.slowCall > 0 && float64(b.slows)/float64(b.total) >= b.conf.slowCallRate) {
+			b.setState(StateOpen)
+		}
+	case StateHalfOpen:
+		if failed || slow {
+			b.setState(StateOpen)
+			return
+		}
+		b.successes++
+		if b.successes >= b.conf.halfOpenMaxCalls {
+			b.setState(StateClosed)
+		}
+	}
+}
+
+// refresh 处理时间驱动的状态变化：统计窗口到期清空统计、熔断到期进入半开，调用方需持有锁
+func (b *Cache) refresh(now time.Time) {
+	if now.Before(b.expiry) {
+		return
+	}
+
+	switch b.state {
+	case StateClosed:
+		b.reset(now)
+	case StateOpen:
+		b.setState(StateHalfOpen)
+	}
+}
+
+// setState 切换状态，调用方需持有锁，回调在释放锁后由 notify 触发
+func (b *Cache) setState(to State) {
+	if b.state == to {
+		return
+	}
+
+	from := b.state
+	b.state = to
+	b.reset(time.Now())
+	b.pending = append(b.pending, transition{from: from, to: to})
+}
+
+// reset 开启新的统计周期，调用方需持有锁
+func (b *Cache) reset(now time.Time) {
+	b.generation++
+	b.total, b.failures, b.slows, b.successes = 0, 0, 0, 0
+	switch b.state {
+	case StateClosed:
+		b.expiry = now.Add(b.conf.window)
+	case StateOpen:
+		b.expiry = now.Add(b.conf.openTimeout)
+	case StateHalfOpen:
+		b.expiry = time.Time{} // 半开状态只由探测结果驱动
+	}
+}
+
+// notify 在锁外触发状态变化回调和日志，避免回调阻塞请求
+func (b *Cache) notify() {
+	b.sm.Lock()
+	pending := b.pending
+	b.pending = nil
+	b.sm.Unlock()
+
+	for _, v := range pending {
+		if b.conf.logger != nil {
+			b.conf.logger.Info(context.Background(), fmt.Sprintf("breaker %s state change: %s -> %s", b.conf.name, v.from, v.to))
+		}
+		if b.conf.onStateChange != nil {
+			b.conf.onStateChange(b.conf.name, v.from, v.to)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"github.com/PycMono/go-cache/client"
+	"testing"
+	"time"
+)
+
+type fakeAdaptor struct {
+	client.IAdaptor
+	err error
+}
+
+func (f *fakeAdaptor) Get(ctx context.Context, k []string) (map[string][]byte, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return map[string][]byte{}, nil
+}
+
+func TestBreaker(t *testing.T) {
+	var (
+		handler = &fakeAdaptor{err: errors.New("redis down")}
+		changes []State
+	)
+	conf := Config{}.WithName("redis").
+		WithMinRequests(4).
+		WithFailureRate(0.5).
+		WithOpenTimeout(50 * time.Millisecond).
+		WithHalfOpenMaxCalls(1).
+		WithOnStateChange(func(name string, from, to State) {
+			changes = append(changes, to)
+		})
+	adaptor, err := NewBreakerAdaptor(handler, &conf)
+	if err != nil {
+		panic(err)
+	}
+	b := adaptor.(*Cache)
+
+	for i := 0; i < 4; i++ {
+		_, _ = b.Get(context.TODO(), []string{"1"})
+	}
+	if b.State() != StateOpen {
+		t.Fatalf("state = %s, want open", b.State())
+	}
+	if _, err = b.Get(context.TODO(), []string{"1"}); !errors.Is(err, client.ErrCircuitOpen) {
+		t.Fatalf("err = %v, want ErrCircuitOpen", err)
+	}
+
+	// 熔断到期进入半开，探测成功后恢复
+	time.Sleep(60 * time.Millisecond)
+	handler.err = nil
+	if _, err = b.Get(context.TODO(), []string{"1"}); err != nil {
+		panic(err)
+	}
+	if b.State() != StateClosed {
+		t.Fatalf("state = %s, want closed", b.State())
+	}
+
+	want := []State{StateOpen, StateHalfOpen, StateClosed}
+	if len(changes) != len(want) {
+		t.Fatalf("changes = %v, want %v", changes, want)
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Fatalf("changes = %v, want %v", changes, want)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package breaker
+
+import (
+	"fmt"
+	"github.com/PycMono/go-cache/middleware"
+	"time"
+)
+
+// Config 配置文件
+type Config struct {
+	name             string                            // 熔断器名称，日志、回调中用于区分是哪一级缓存
+	window           time.Duration                     // 关闭状态下的统计窗口（默认10秒），窗口结束后清空统计
+	minRequests      int                               // 窗口内最少请求数（默认20），少于该值不触发熔断
+	failureRate      float64                           // 失败率阈值（默认0.5），达到后熔断
+	slowCall         time.Duration                     // 慢调用耗时阈值（默认0，不统计慢调用）
+	slowCallRate     float64                           // 慢调用比例阈值（默认0.5），达到后熔断
+	openTimeout      time.Duration                     // 熔断持续时间（默认5秒），之后进入半开状态
+	halfOpenMaxCalls int                               // 半开状态允许的探测请求数（默认3），全部成功后恢复
+	onStateChange    func(name string, from, to State) // 状态变化回调，可用于上报监控
+	logger           middleware.Logger                 // 状态变化日志
+}
+
+func (c Config) WithName(name string) Config {
+	c.name = name
+	return c
+}
+
+func (c Config) WithWindow(window time.Duration) Config {
+	c.window = window
+	return c
+}
+
+func (c Config) WithMinRequests(minRequests int) Config {
+	c.minRequests = minRequests
+	return c
+}
+
+func (c Config) WithFailureRate(failureRate float64) Config {
+	c.failureRate = failureRate
+	return c
+}
+
+func (c Config) WithSlowCall(slowCall time.Duration, slowCallRate float64) Config {
+	c.slowCall = slowCall
+	c.slowCallRate = slowCallRate
+	return c
+}
+
+func (c Config) WithOpenTimeout(openTimeout time.Duration) Config {
+	c.openTimeout = openTimeout
+	return c
+}
+
+func (c Config) WithHalfOpenMaxCalls(halfOpenMaxCalls int) Config {
+	c.halfOpenMaxCalls = halfOpenMaxCalls
+	return c
+}
+
+func (c Config) WithOnStateChange(onStateChange func(name string, from, to State)) Config {
+	c.onStateChange = onStateChange
+	return c
+}
+
+func (c Config) WithLogger(logger middleware.Logger) Config {
+	c.logger = logger
+	return c
+}
+
+func (c Config) build() (Config, error) {
+	if c.window == 0 {
+		c.window = 10 * time.Second
+	}
+	if c.minRequests == 0 {
+		c.minRequests = 20
+	}
+	if c.failureRate == 0 {
+		c.failureRate = 0.5
+	}
+	if c.slowCallRate == 0 {
+		c.slowCallRate = 0.5
+	}
+	if c.openTimeout == 0 {
+		c.openTimeout = 5 * time.Second
+	}
+	if c.halfOpenMaxCalls == 0 {
+		c.halfOpenMaxCalls = 3
+	}
+
+	if c.window < 0 || c.openTimeout < 0 || c.slowCall < 0 {
+		return c, fmt.Errorf("window、openTimeout、slowCall不能为负数")
+	}
+	if c.minRequests < 0 || c.halfOpenMaxCalls < 0 {
+		return c, fmt.Errorf("minRequests、halfOpenMaxCalls不能为负数")
+	}
+	if c.failureRate < 0 || c.failureRate > 1 || c.slowCallRate < 0 || c.slowCallRate > 1 {
+		return c, fmt.Errorf("failureRate、slowCallRate取值范围为(0, 1]")
+	}
+
+	return c, nil
+}
@@ -0,0 +1,50 @@
+package client
+
+import (
+	"context"
+	"time"
+)
+
+// Z 有序集合的成员及分数
+type Z struct {
+	Member []byte
+	Score  float64
+}
+
+// ICollectionAdaptor 支持列表、集合、有序集合的适配器，语义和 redis 的 LIST、SET、ZSET 一致
+// 写入时 expire 大于0会重新设置整个key的过期时间，小于等于0不修改过期时间
+// 下标从0开始，负数表示从末尾开始计算，stop 包含在结果中，元素全部删除后key也被删除
+type ICollectionAdaptor interface {
+	Del(ctx context.Context, k []string) error
+	Expire(ctx context.Context, k []string, expire time.Duration) error
+	Close() error
+
+	// LPush 依次插入到列表头部，返回插入后的长度
+	LPush(ctx context.Context, key string, values [][]byte, expire time.Duration) (int64, error)
+	// RPush 依次插入到列表尾部，返回插入后的长度
+	RPush(ctx context.Context, key string, values [][]byte, expire time.Duration) (int64, error)
+	LRange(ctx context.Context, key string, start, stop int64) ([][]byte, error)
+	// LTrim 只保留 [start, stop] 范围内的元素
+	LTrim(ctx context.Context, key string, start, stop int64) error
+	LLen(ctx context.Context, key string) (int64, error)
+
+	// SAdd 返回新增的成员数
+	SAdd(ctx context.Context, key string, members [][]byte, expire time.Duration) (int64, error)
+	// SRem 返回删除的成员数
+	SRem(ctx context.Context, key string, members [][]byte) (int64, error)
+	SIsMember(ctx context.Context, key string, members [][]byte) ([]bool, error)
+	SMembers(ctx context.Context, key string) ([][]byte, error)
+	SCard(ctx context.Context, key string) (int64, error)
+
+	// ZAdd 已存在的成员更新分数，返回新增的成员数
+	ZAdd(ctx context.Context, key string, members []Z, expire time.Duration) (int64, error)
+	// ZIncrBy 给成员的分数加上incr，成员不存在时从0开始，返回加上后的分数
+	ZIncrBy(ctx context.Context, key string, member []byte, incr float64, expire time.Duration) (float64, error)
+	ZRem(ctx context.Context, key string, members [][]byte) (int64, error)
+	// ZRange 按分数从小到大（desc 为true时从大到小）返回 [start, stop] 范围内的成员，分数相同按成员字节序
+	ZRange(ctx context.Context, key string, start, stop int64, desc bool) ([]Z, error)
+	// ZRank 返回成员的排名，desc 含义和 ZRange 一致，成员不存在时 ok 为false
+	ZRank(ctx context.Context, key string, member []byte, desc bool) (rank int64, ok bool, err error)
+	ZScore(ctx context.Context, key string, member []byte) (score float64, ok bool, err error)
+	ZCard(ctx context.Context, key string) (int64, error)
+}
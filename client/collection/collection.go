@@ -0,0 +1,479 @@
+package collection
+
+import (
+	"context"
+	"errors"
+	"github.com/PycMono/go-cache/client"
+	"sort"
+	"sync"
+	"time"
+)
+
+// errWrongType 对已有key执行了其它类型的操作，和 redis 的错误信息一致
+var errWrongType = errors.New("WRONGTYPE Operation against a key holding the wrong kind of value")
+
+type kind int
+
+const (
+	kindList kind = iota
+	kindSet
+	kindZSet
+)
+
+type object struct {
+	kind     kind
+	list     [][]byte
+	set      map[string]struct{}
+	zset     *zset
+	expireAt int64 // 过期时间（UnixNano），0表示不过期
+}
+
+func (o *object) len() int {
+	switch o.kind {
+	case kindList:
+		return len(o.list)
+	case kindSet:
+		return len(o.set)
+	}
+	return len(o.zset.sorted)
+}
+
+// Cache 进程内的列表、集合、有序集合，语义和 redis 一致，可用于测试或作为一级缓存
+type Cache struct {
+	sm     sync.Mutex
+	items  map[string]*object
+	closed bool
+	done   chan struct{} // 关闭时通知清理协程退出
+}
+
+func NewCollectionAdaptor(conf *Config) (client.ICollectionAdaptor, error) {
+	c, err := conf.build()
+	if err != nil {
+		return nil, err
+	}
+
+	cache := &Cache{
+		items: make(map[string]*object),
+		done:  make(chan struct{}),
+	}
+	go cache.cleanup(c.cleanupInterval)
+
+	return cache, nil
+}
+
+func (c *Cache) Del(ctx context.Context, k []string) error {
+	c.sm.Lock()
+	defer c.sm.Unlock()
+
+	if c.closed {
+		return client.ErrClosed
+	}
+	for _, key := range k {
+		delete(c.items, key)
+	}
+	return nil
+}
+
+func (c *Cache) Expire(ctx context.Context, k []string, expire time.Duration) error {
+	c.sm.Lock()
+	defer c.sm.Unlock()
+
+	if c.closed {
+		return client.ErrClosed
+	}
+	now := time.Now().UnixNano()
+	for _, key := range k {
+		if o := c.lookup(key, now); o != nil {
+			o.expireAt = 0
+			if expire > 0 {
+				o.expireAt = now + int64(expire)
+			}
+		}
+	}
+	return nil
+}
+
+func (c *Cache) Close() error {
+	c.sm.Lock()
+	defer c.sm.Unlock()
+
+	if c.closed {
+		return client.ErrClosed
+	}
+	c.closed = true
+	c.items = nil
+	close(c.done)
+	return nil
+}
+
+func (c *Cache) LPush(ctx context.Context, key string, values [][]byte, expire time.Duration) (int64, error) {
+	var n int64
+	err := c.write(key, kindList, expire, func(o *object) {
+		// 依次插入到头部，结果为 values 的逆序，和 redis LPUSH 一致
+		list := make([][]byte, 0, len(values)+len(o.list))
+		for i := len(values) - 1; i >= 0; i-- {
+			list = append(list, clone(values[i]))
+		}
+		o.list = append(list, o.list...)
+		n = int64(len(o.list))
+	})
+	return n, err
+}
+
+func (c *Cache) RPush(ctx context.Context, key string, values [][]byte, expire time.Duration) (int64, error) {
+	var n int64
+	err := c.write(key, kindList, expire, func(o *object) {
+		for _, v := range values {
+			o.list = append(o.list, clone(v))
+		}
+		n = int64(len(o.list))
+	})
+	return n, err
+}
+
+func (c *Cache) LRange(ctx context.Context, key string, start, stop int64) ([][]byte, error) {
+	var out [][]byte
+	err := c.read(key, kindList, func(o *object) {
+		if i, j, ok := bounds(start, stop, len(o.list)); ok {
+			for _, v := range o.list[i : j+1] {
+				out = append(out, clone(v))
+			}
+		}
+	})
+	return out, err
+}
+
+func (c *Cache) LTrim(ctx context.Context, key string, start, stop int64) error {
+	return c.write(key, kindList, 0, func(o *object) {
+		i, j, ok := bounds(start, stop, len(o.list))
+		if !ok {
+			o.list = nil
+			return
+		}
+		o.list = append([][]byte(nil), o.list[i:j+1]...)
+	})
+}
+
+func (c *Cache) LLen(ctx context.Context, key string) (int64, error) {
+	var n int64
+	err := c.read(key, kindList, func(o *object) {
+		n = int64(len(o.list))
+	})
+	return n, err
+}
+
+func (c *Cache) SAdd(ctx context.Context, key string, members [][]byte, expire time.Duration) (int64, error) {
+	var n int64
+	err := c.write(key, kindSet, expire, func(o *object) {
+		for _, m := range members {
+			if _, ok := o.set[string(m)]; !ok {
+				o.set[string(m)] = struct{}{}
+				n++
+			}
+		}
+	})
+	return n, err
+}
+
+func (c *Cache) SRem(ctx context.Context, key string, members [][]byte) (int64, error) {
+	var n int64
+	err := c.update(key, kindSet, func(o *object) {
+		for _, m := range members {
+			if _, ok := o.set[string(m)]; ok {
+				delete(o.set, string(m))
+				n++
+			}
+		}
+	})
+	return n, err
+}
+
+func (c *Cache) SIsMember(ctx context.Context, key string, members [][]byte) ([]bool, error) {
+	out := make([]bool, len(members))
+	err := c.read(key, kindSet, func(o *object) {
+		for i, m := range members {
+			_, out[i] = o.set[string(m)]
+		}
+	})
+	return out, err
+}
+
+func (c *Cache) SMembers(ctx context.Context, key string) ([][]byte, error) {
+	var out [][]byte
+	err := c.read(key, kindSet, func(o *object) {
+		for m := range o.set {
+			out = append(out, []byte(m))
+		}
+	})
+	return out, err
+}
+
+func (c *Cache) SCard(ctx context.Context, key string) (int64, error) {
+	var n int64
+	err := c.read(key, kindSet, func(o *object) {
+		n = int64(len(o.set))
+	})
+	return n, err
+}
+
+func (c *Cache) ZAdd(ctx context.Context, key string, members []client.Z, expire time.Duration) (int64, error) {
+	var n int64
+	err := c.write(key, kindZSet, expire, func(o *object) {
+		for _, m := range members {
+			if o.zset.set(string(m.Member), m.Score) {
+				n++
+			}
+		}
+	})
+	return n, err
+}
+
+func (c *Cache) ZIncrBy(ctx context.Context, key string, member []byte, incr float64, expire time.Duration) (float64, error) {
+	var score float64
+	err := c.write(key, kindZSet, expire, func(o *object) {
+		score = o.zset.scores[string(member)] + incr
+		o.zset.set(string(member), score)
+	})
+	return score, err
+}
+
+func (c *Cache) ZRem(ctx context.Context, key string, members [][]byte) (int64, error) {
+	var n int64
+	err := c.update(key, kindZSet, func(o *object) {
+		for _, m := range members {
+			if o.zset.remove(string(m)) {
+				n++
+			}
+		}
+	})
+	return n, err
+}
+
+func (c *Cache) ZRange(ctx context.Context, key string, start, stop int64, desc bool) ([]client.Z, error) {
+	var out []client.Z
+	err := c.read(key, kindZSet, func(o *object) {
+		n := len(o.zset.sorted)
+		i, j, ok := bounds(start, stop, n)
+		if !ok {
+			return
+		}
+		for r := i; r <= j; r++ {
+			e := o.zset.sorted[r]
+			if desc {
+				e = o.zset.sorted[n-1-r]
+			}
+			out = append(out, client.Z{Member: []byte(e.member), Score: e.score})
+		}
+	})
+	return out, err
+}
+
+func (c *Cache) ZRank(ctx context.Context, key string, member []byte, desc bool) (int64, bool, error) {
+	var (
+		rank int64
+		ok   bool
+	)
+	err := c.read(key, kindZSet, func(o *object) {
+		var r int
+		if r, ok = o.zset.rank(string(member)); ok {
+			if desc {
+				r = len(o.zset.sorted) - 1 - r
+			}
+			rank = int64(r)
+		}
+	})
+	return rank, ok, err
+}
+
+func (c *Cache) ZScore(ctx context.Context, key string, member []byte) (float64, bool, error) {
+	var (
+		score float64
+		ok    bool
+	)
+	err := c.read(key, kindZSet, func(o *object) {
+		score, ok = o.zset.scores[string(member)]
+	})
+	return score, ok, err
+}
+
+func (c *Cache) ZCard(ctx context.Context, key string) (int64, error) {
+	var n int64
+	err := c.read(key, kindZSet, func(o *object) {
+		n = int64(len(o.zset.sorted))
+	})
+	return n, err
+}
+
+// read key不存在时不调用f
+func (c *Cache) read(key string, k kind, f func(o *object)) error {
+	c.sm.Lock()
+	defer c.sm.Unlock()
+
+	if c.closed {
+		return client.ErrClosed
+	}
+	o := c.lookup(key, time.Now().UnixNano())
+	if o == nil {
+		return nil
+	}
+	if o.kind != k {
+		return errWrongType
+	}
+	f(o)
+	return nil
+}
+
+// update 修改已存在的key，key不存在时不调用f，元素全部删除后删除key
+func (c *Cache) update(key string, k kind, f func(o *object)) error {
+	return c.read(key, k, func(o *object) {
+		f(o)
+		if o.len() == 0 {
+			delete(c.items, key)
+		}
+	})
+}
+
+// write key不存在时创建，expire 大于0时重新设置过期时间，元素全部删除后删除key
+func (c *Cache) write(key string, k kind, expire time.Duration, f func(o *object)) error {
+	c.sm.Lock()
+	defer c.sm.Unlock()
+
+	if c.closed {
+		return client.ErrClosed
+	}
+	now := time.Now().UnixNano()
+	o := c.lookup(key, now)
+	if o == nil {
+		o = &object{kind: k}
+		switch k {
+		case kindSet:
+			o.set = make(map[string]struct{})
+		case kindZSet:
+			o.zset = &zset{scores: make(map[string]float64)}
+		}
+	}
+	if o.kind != k {
+		return errWrongType
+	}
+
+	f(o)
+	if o.len() == 0 {
+		delete(c.items, key)
+		return nil
+	}
+	if expire > 0 {
+		o.expireAt = now + int64(expire)
+	}
+	c.items[key] = o
+	return nil
+}
+
+// lookup 返回未过期的数据，已过期的直接删除，调用方需要持有锁
+func (c *Cache) lookup(key string, now int64) *object {
+	o, ok := c.items[key]
+	if !ok {
+		return nil
+	}
+	if o.expireAt > 0 && o.expireAt <= now {
+		delete(c.items, key)
+		return nil
+	}
+	return o
+}
+
+func (c *Cache) cleanup(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+			c.sm.Lock()
+			now := time.Now().UnixNano()
+			for key := range c.items {
+				c.lookup(key, now)
+			}
+			c.sm.Unlock()
+		}
+	}
+}
+
+// bounds 按 redis 的规则把 [start, stop] 转换为有效下标，ok 为false表示范围为空
+func bounds(start, stop int64, n int) (int, int, bool) {
+	if start < 0 {
+		start += int64(n)
+	}
+	if stop < 0 {
+		stop += int64(n)
+	}
+	if start < 0 {
+		start = 0
+	}
+	if stop >= int64(n) {
+		stop = int64(n) - 1
+	}
+	if start > stop || start >= int64(n) {
+		return 0, 0, false
+	}
+	return int(start), int(stop), true
+}
+
+func clone(b []byte) []byte {
+	return append([]byte(nil), b...)
+}
+
+// zset 有序集合，sorted 按分数从小到大排列，分数相同按成员字节序
+type zset struct {
+	scores map[string]float64
+	sorted []zentry
+}
+
+type zentry struct {
+	member string
+	score  float64
+}
+
+func (e zentry) less(o zentry) bool {
+	if e.score != o.score {
+		return e.score < o.score
+	}
+	return e.member < o.member
+}
+
+// search 返回第一个不小于e的下标
+func (z *zset) search(e zentry) int {
+	return sort.Search(len(z.sorted), func(i int) bool {
+		return !z.sorted[i].less(e)
+	})
+}
+
+// set 设置分数，返回是否新增
+func (z *zset) set(member string, score float64) bool {
+	added := !z.remove(member)
+	e := zentry{member: member, score: score}
+	i := z.search(e)
+	z.sorted = append(z.sorted, zentry{})
+	copy(z.sorted[i+1:], z.sorted[i:])
+	z.sorted[i] = e
+	z.scores[member] = score
+	return added
+}
+
+func (z *zset) remove(member string) bool {
+	i, ok := z.rank(member)
+	if !ok {
+		return false
+	}
+	z.sorted = append(z.sorted[:i], z.sorted[i+1:]...)
+	delete(z.scores, member)
+	return true
+}
+
+func (z *zset) rank(member string) (int, bool) {
+	score, ok := z.scores[member]
+	if !ok {
+		return 0, false
+	}
+	return z.search(zentry{member: member, score: score}), true
+}
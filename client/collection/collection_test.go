@@ -0,0 +1,132 @@
+package collection
+
+import (
+	"context"
+	"errors"
+	"github.com/PycMono/go-cache/client"
+	"testing"
+	"time"
+)
+
+func newCache() client.ICollectionAdaptor {
+	conf := Config{}
+	adaptor, err := NewCollectionAdaptor(&conf)
+	if err != nil {
+		panic(err)
+	}
+	return adaptor
+}
+
+func strs(values [][]byte) []string {
+	out := make([]string, len(values))
+	for i, v := range values {
+		out[i] = string(v)
+	}
+	return out
+}
+
+func TestList(t *testing.T) {
+	c := newCache()
+	ctx := context.TODO()
+
+	if _, err := c.LPush(ctx, "l", [][]byte{[]byte("a"), []byte("b")}, 0); err != nil {
+		panic(err)
+	}
+	n, _ := c.RPush(ctx, "l", [][]byte{[]byte("c")}, 0)
+	if n != 3 {
+		t.Fatalf("n = %d, want 3", n)
+	}
+
+	// LPUSH a b 后为 b a
+	values, _ := c.LRange(ctx, "l", 0, -1)
+	if got := strs(values); len(got) != 3 || got[0] != "b" || got[1] != "a" || got[2] != "c" {
+		t.Fatalf("values = %v", got)
+	}
+	values, _ = c.LRange(ctx, "l", -2, 100)
+	if got := strs(values); len(got) != 2 || got[0] != "a" {
+		t.Fatalf("values = %v", got)
+	}
+
+	if err := c.LTrim(ctx, "l", 0, 0); err != nil {
+		panic(err)
+	}
+	if n, _ = c.LLen(ctx, "l"); n != 1 {
+		t.Fatalf("len = %d, want 1", n)
+	}
+
+	// 类型不一致
+	if _, err := c.SAdd(ctx, "l", [][]byte{[]byte("a")}, 0); err == nil {
+		t.Fatalf("want WRONGTYPE error")
+	}
+}
+
+func TestSet(t *testing.T) {
+	c := newCache()
+	ctx := context.TODO()
+
+	n, _ := c.SAdd(ctx, "s", [][]byte{[]byte("a"), []byte("b"), []byte("a")}, 0)
+	if n != 2 {
+		t.Fatalf("n = %d, want 2", n)
+	}
+	ok, _ := c.SIsMember(ctx, "s", [][]byte{[]byte("a"), []byte("c")})
+	if !ok[0] || ok[1] {
+		t.Fatalf("ok = %v", ok)
+	}
+
+	// 成员全部删除后key也被删除
+	if n, _ = c.SRem(ctx, "s", [][]byte{[]byte("a"), []byte("b")}); n != 2 {
+		t.Fatalf("n = %d, want 2", n)
+	}
+	if _, err := c.LPush(ctx, "s", [][]byte{[]byte("a")}, 0); err != nil {
+		t.Fatalf("err = %v, want key removed", err)
+	}
+}
+
+func TestZSet(t *testing.T) {
+	c := newCache()
+	ctx := context.TODO()
+
+	n, _ := c.ZAdd(ctx, "z", []client.Z{{Member: []byte("a"), Score: 3}, {Member: []byte("b"), Score: 1}, {Member: []byte("c"), Score: 3}}, 0)
+	if n != 3 {
+		t.Fatalf("n = %d, want 3", n)
+	}
+	score, _ := c.ZIncrBy(ctx, "z", []byte("b"), 5, 0)
+	if score != 6 {
+		t.Fatalf("score = %v, want 6", score)
+	}
+
+	zs, _ := c.ZRange(ctx, "z", 0, -1, true)
+	if len(zs) != 3 || string(zs[0].Member) != "b" || string(zs[1].Member) != "c" || string(zs[2].Member) != "a" {
+		t.Fatalf("zs = %v", zs)
+	}
+	rank, ok, _ := c.ZRank(ctx, "z", []byte("a"), false)
+	if !ok || rank != 0 {
+		t.Fatalf("rank = %d, %v, want 0", rank, ok)
+	}
+	if _, ok, _ = c.ZScore(ctx, "z", []byte("x")); ok {
+		t.Fatalf("want missing member")
+	}
+	if n, _ = c.ZRem(ctx, "z", [][]byte{[]byte("a"), []byte("x")}); n != 1 {
+		t.Fatalf("n = %d, want 1", n)
+	}
+}
+
+func TestExpireAndClose(t *testing.T) {
+	c := newCache()
+	ctx := context.TODO()
+
+	if _, err := c.RPush(ctx, "l", [][]byte{[]byte("a")}, 20*time.Millisecond); err != nil {
+		panic(err)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if n, _ := c.LLen(ctx, "l"); n != 0 {
+		t.Fatalf("len = %d, want expired", n)
+	}
+
+	if err := c.Close(); err != nil {
+		panic(err)
+	}
+	if _, err := c.LLen(ctx, "l"); !errors.Is(err, client.ErrClosed) {
+		t.Fatalf("err = %v, want ErrClosed", err)
+	}
+}
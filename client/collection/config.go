@@ -0,0 +1,26 @@
+package collection
+
+import (
+	"fmt"
+	"time"
+)
+
+// Config 配置文件
+type Config struct {
+	cleanupInterval time.Duration // 过期数据清理间隔（默认1分钟），过期数据在读取时也会删除
+}
+
+func (c Config) WithCleanupInterval(cleanupInterval time.Duration) Config {
+	c.cleanupInterval = cleanupInterval
+	return c
+}
+
+func (c Config) build() (Config, error) {
+	if c.cleanupInterval == 0 {
+		c.cleanupInterval = time.Minute
+	}
+	if c.cleanupInterval < 0 {
+		return c, fmt.Errorf("cleanupInterval不能为负数")
+	}
+	return c, nil
+}
@@ -0,0 +1,60 @@
+package disk
+
+import (
+	"fmt"
+	"time"
+)
+
+// Config 配置文件
+type Config struct {
+	dir             string        // 数据目录，同一目录同一时间只能被一个缓存打开
+	maxSize         int64         // 有效数据的容量上限（字节，默认1GB），超过后按写入顺序淘汰最早写入的数据
+	syncWrites      bool          // 每次写入后是否同步到磁盘，默认只在关闭和压缩时同步
+	cleanupInterval time.Duration // 过期数据清理及压缩检查的间隔（默认1分钟）
+	compactRatio    float64       // 无效数据占文件大小的比例超过该值时压缩（默认0.5）
+}
+
+func (c Config) WithDir(dir string) Config {
+	c.dir = dir
+	return c
+}
+
+func (c Config) WithMaxSize(maxSize int64) Config {
+	c.maxSize = maxSize
+	return c
+}
+
+func (c Config) WithSyncWrites(syncWrites bool) Config {
+	c.syncWrites = syncWrites
+	return c
+}
+
+func (c Config) WithCleanupInterval(cleanupInterval time.Duration) Config {
+	c.cleanupInterval = cleanupInterval
+	return c
+}
+
+func (c Config) WithCompactRatio(compactRatio float64) Config {
+	c.compactRatio = compactRatio
+	return c
+}
+
+func (c Config) build() (Config, error) {
+	if len(c.dir) == 0 {
+		return c, fmt.Errorf("dir为空")
+	}
+	if c.maxSize == 0 {
+		c.maxSize = 1 << 30
+	}
+	if c.cleanupInterval == 0 {
+		c.cleanupInterval = time.Minute
+	}
+	if c.compactRatio == 0 {
+		c.compactRatio = 0.5
+	}
+
+	if c.maxSize < 0 || c.cleanupInterval < 0 || c.compactRatio < 0 || c.compactRatio >= 1 {
+		return c, fmt.Errorf("maxSize、cleanupInterval不能为负数，compactRatio取值[0, 1)")
+	}
+	return c, nil
+}
@@ -0,0 +1,589 @@
+package disk
+
+import (
+	"bufio"
+	"container/list"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/PycMono/go-cache/client"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// 数据文件只追加写入，每条记录：
+//
+//	crc32(4) kind(1) expireAt(8) keyLen(4) valLen(4) key value
+//
+// crc32 覆盖 crc32 之后的全部字节，expireAt 为过期时间（UnixNano），0表示不过期
+// 删除写入一条 kindDel 记录，启动时顺序重放文件重建索引，末尾不完整的记录直接截断
+const (
+	dataFile   = "data.log"
+	headerSize = 21
+
+	kindPut = 1
+	kindDel = 2
+
+	minCompactSize = 1 << 20 // 无效数据小于1MB时不压缩
+
+	scanBatch = 1000
+)
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// entry 索引中一条数据的位置
+type entry struct {
+	key      string
+	offset   int64 // 记录在文件中的起始位置
+	size     int64 // 整条记录的大小
+	expireAt int64
+	elem     *list.Element // 写入顺序，容量不足时从最早写入的开始淘汰
+}
+
+func (e *entry) expired(now int64) bool {
+	return e.expireAt > 0 && e.expireAt <= now
+}
+
+// Cache 本地磁盘缓存，索引保存在内存中，数据追加写入单个文件，定期压缩删除无效数据
+// 适合放在进程内缓存和 redis 之间，保存较大、很少变化的数据，重启后数据仍然可用
+type Cache struct {
+	conf Config
+	path string
+
+	sm     sync.RWMutex
+	file   *os.File
+	size   int64 // 文件大小，新的记录写在这里
+	live   int64 // 有效记录的大小
+	index  map[string]*entry
+	order  *list.List
+	closed bool
+	done   chan struct{}
+	wg     sync.WaitGroup
+}
+
+func NewDiskAdaptor(conf *Config) (client.IAdaptor, error) {
+	c, err := conf.build()
+	if err != nil {
+		return nil, err
+	}
+	if err = os.MkdirAll(c.dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	cache := &Cache{
+		conf:  c,
+		path:  filepath.Join(c.dir, dataFile),
+		index: make(map[string]*entry),
+		order: list.New(),
+		done:  make(chan struct{}),
+	}
+	if err = cache.open(); err != nil {
+		return nil, err
+	}
+	cache.evict()
+
+	cache.wg.Add(1)
+	go cache.cleanup()
+	return cache, nil
+}
+
+func (c *Cache) Set(ctx context.Context, params map[string][]byte, expire time.Duration) error {
+	c.sm.Lock()
+	defer c.sm.Unlock()
+
+	if c.closed {
+		return client.ErrClosed
+	}
+	expireAt := expireAt(expire)
+	for k, v := range params {
+		if err := c.put(k, v, expireAt); err != nil {
+			return err
+		}
+	}
+	return c.commit()
+}
+
+func (c *Cache) Get(ctx context.Context, k []string) (map[string][]byte, error) {
+	items, err := c.read(k, true)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[string][]byte, len(items))
+	for key, item := range items {
+		out[key] = item.Value
+	}
+	return out, nil
+}
+
+func (c *Cache) GetWithTTL(ctx context.Context, k []string) (map[string]client.Item, error) {
+	return c.read(k, true)
+}
+
+func (c *Cache) TTL(ctx context.Context, k []string) (map[string]time.Duration, error) {
+	items, err := c.read(k, false)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]time.Duration, len(items))
+	for key, item := range items {
+		out[key] = item.TTL
+	}
+	return out, nil
+}
+
+func (c *Cache) Del(ctx context.Context, k []string) error {
+	c.sm.Lock()
+	defer c.sm.Unlock()
+
+	if c.closed {
+		return client.ErrClosed
+	}
+	for _, key := range k {
+		if _, ok := c.index[key]; !ok {
+			continue
+		}
+		if err := c.remove(key); err != nil {
+			return err
+		}
+	}
+	return c.commit()
+}
+
+// Expire 磁盘上的过期时间和数据在同一条记录中，修改过期时间需要重写整条记录
+func (c *Cache) Expire(ctx context.Context, k []string, expire time.Duration) error {
+	c.sm.Lock()
+	defer c.sm.Unlock()
+
+	if c.closed {
+		return client.ErrClosed
+	}
+	now := time.Now().UnixNano()
+	for _, key := range k {
+		e, ok := c.index[key]
+		if !ok || e.expired(now) {
+			continue
+		}
+		val, err := c.value(e)
+		if err != nil {
+			return err
+		}
+		if err = c.put(key, val, expireAt(expire)); err != nil {
+			return err
+		}
+	}
+	return c.commit()
+}
+
+func (c *Cache) SetIfAbsent(ctx context.Context, params map[string][]byte, expire time.Duration) (map[string]bool, error) {
+	return c.setIf(params, expire, func(key string, old []byte, found bool) bool {
+		return !found
+	})
+}
+
+func (c *Cache) SetIfPresent(ctx context.Context, params map[string][]byte, expire time.Duration) (map[string]bool, error) {
+	return c.setIf(params, expire, func(key string, old []byte, found bool) bool {
+		return found
+	})
+}
+
+func (c *Cache) CompareAndSwap(ctx context.Context, params map[string]client.Swap, expire time.Duration) (map[string]bool, error) {
+	values := make(map[string][]byte, len(params))
+	for k, v := range params {
+		values[k] = v.Value
+	}
+	return c.setIf(values, expire, func(key string, old []byte, found bool) bool {
+		if params[key].Version == "" {
+			return !found
+		}
+		return found && client.Version(old) == params[key].Version
+	})
+}
+
+// Scan 在索引上遍历，创建时复制匹配的key
+func (c *Cache) Scan(ctx context.Context, pattern string) client.KeyIterator {
+	c.sm.RLock()
+	defer c.sm.RUnlock()
+
+	if c.closed {
+		return client.ErrIterator(client.ErrClosed)
+	}
+	var (
+		keys []string
+		now  = time.Now().UnixNano()
+	)
+	for key, e := range c.index {
+		if !e.expired(now) && client.MatchPattern(pattern, key) {
+			keys = append(keys, key)
+		}
+	}
+	return client.SliceIterator(keys, scanBatch)
+}
+
+func (c *Cache) DelPrefix(ctx context.Context, prefix string) (int64, error) {
+	var (
+		n  int64
+		it = c.Scan(ctx, client.EscapePattern(prefix)+"*")
+	)
+	for it.Next(ctx) {
+		keys := it.Keys()
+		if err := c.Del(ctx, keys); err != nil {
+			return n, err
+		}
+		n += int64(len(keys))
+	}
+	return n, it.Err()
+}
+
+// Compact 立即重写数据文件，只保留未过期的有效数据
+func (c *Cache) Compact() error {
+	c.sm.Lock()
+	defer c.sm.Unlock()
+
+	if c.closed {
+		return client.ErrClosed
+	}
+	return c.compact()
+}
+
+// Size 返回有效数据的大小和数据文件的大小
+func (c *Cache) Size() (live, file int64) {
+	c.sm.RLock()
+	defer c.sm.RUnlock()
+
+	return c.live, c.size
+}
+
+// Close 停止后台清理，同步数据文件并关闭，数据保留在磁盘上，下次打开同一目录时恢复
+func (c *Cache) Close() error {
+	c.sm.Lock()
+	if c.closed {
+		c.sm.Unlock()
+		return client.ErrClosed
+	}
+	c.closed = true
+	close(c.done)
+	c.sm.Unlock()
+
+	c.wg.Wait()
+	c.sm.Lock()
+	defer c.sm.Unlock()
+	c.index = nil
+	return errors.Join(c.file.Sync(), c.file.Close())
+}
+
+// read 查询数据，withValue 为false时只查询过期时间
+func (c *Cache) read(k []string, withValue bool) (map[string]client.Item, error) {
+	c.sm.RLock()
+	defer c.sm.RUnlock()
+
+	if c.closed {
+		return nil, client.ErrClosed
+	}
+	var (
+		out = make(map[string]client.Item)
+		now = time.Now().UnixNano()
+	)
+	for _, key := range k {
+		e, ok := c.index[key]
+		if !ok || e.expired(now) {
+			continue
+		}
+
+		item := client.Item{TTL: client.NoExpire}
+		if e.expireAt > 0 {
+			item.TTL = time.Duration(e.expireAt - now)
+		}
+		if withValue {
+			val, err := c.value(e)
+			if err != nil {
+				return nil, err
+			}
+			item.Value = val
+		}
+		out[key] = item
+	}
+	return out, nil
+}
+
+// setIf 在写锁内判断并写入
+func (c *Cache) setIf(params map[string][]byte, expire time.Duration, ok func(key string, old []byte, found bool) bool) (map[string]bool, error) {
+	c.sm.Lock()
+	defer c.sm.Unlock()
+
+	if c.closed {
+		return nil, client.ErrClosed
+	}
+	var (
+		out = make(map[string]bool, len(params))
+		now = time.Now().UnixNano()
+	)
+	for k, v := range params {
+		var (
+			old   []byte
+			found bool
+		)
+		if e, exists := c.index[k]; exists && !e.expired(now) {
+			var err error
+			if old, err = c.value(e); err != nil {
+				return nil, err
+			}
+			found = true
+		}
+		if !ok(k, old, found) {
+			out[k] = false
+			continue
+		}
+		if err := c.put(k, v, expireAt(expire)); err != nil {
+			return nil, err
+		}
+		out[k] = true
+	}
+	return out, c.commit()
+}
+
+// value 读取记录中的数据并校验，调用方需要持有锁
+func (c *Cache) value(e *entry) ([]byte, error) {
+	buf := make([]byte, e.size)
+	if _, err := c.file.ReadAt(buf, e.offset); err != nil {
+		return nil, err
+	}
+	if crc32.Checksum(buf[4:], castagnoli) != binary.BigEndian.Uint32(buf) {
+		return nil, errors.New("disk: record checksum mismatch")
+	}
+	keyLen := binary.BigEndian.Uint32(buf[13:])
+	return buf[headerSize+int64(keyLen):], nil
+}
+
+// put 追加写入一条记录并更新索引，调用方需要持有写锁
+func (c *Cache) put(key string, val []byte, expireAt int64) error {
+	e := &entry{key: key, offset: c.size, expireAt: expireAt}
+	n, err := c.append(kindPut, key, val, expireAt)
+	if err != nil {
+		return err
+	}
+	e.size = n
+
+	if old, ok := c.index[key]; ok {
+		c.live -= old.size
+		c.order.Remove(old.elem)
+	}
+	e.elem = c.order.PushBack(e)
+	c.index[key] = e
+	c.live += e.size
+	c.evict()
+	return nil
+}
+
+// remove 写入删除记录并从索引中删除，调用方需要持有写锁
+func (c *Cache) remove(key string) error {
+	if _, err := c.append(kindDel, key, nil, 0); err != nil {
+		return err
+	}
+	c.drop(key)
+	return nil
+}
+
+// drop 只从索引中删除，记录变为无效数据
+func (c *Cache) drop(key string) {
+	if e, ok := c.index[key]; ok {
+		c.live -= e.size
+		c.order.Remove(e.elem)
+		delete(c.index, key)
+	}
+}
+
+// evict 有效数据超过容量时从最早写入的开始淘汰
+func (c *Cache) evict() {
+	for c.live > c.conf.maxSize && c.order.Len() > 0 {
+		e := c.order.Front().Value.(*entry)
+		if err := c.remove(e.key); err != nil {
+			c.drop(e.key) // 写入删除记录失败，重启后可能恢复，不影响当前索引
+		}
+	}
+}
+
+func (c *Cache) append(kind byte, key string, val []byte, expireAt int64) (int64, error) {
+	buf := encode(kind, key, val, expireAt)
+	if _, err := c.file.WriteAt(buf, c.size); err != nil {
+		return 0, err
+	}
+	c.size += int64(len(buf))
+	return int64(len(buf)), nil
+}
+
+// commit 按配置同步写入
+func (c *Cache) commit() error {
+	if !c.conf.syncWrites {
+		return nil
+	}
+	return c.file.Sync()
+}
+
+func encode(kind byte, key string, val []byte, expireAt int64) []byte {
+	buf := make([]byte, headerSize+len(key)+len(val))
+	buf[4] = kind
+	binary.BigEndian.PutUint64(buf[5:], uint64(expireAt))
+	binary.BigEndian.PutUint32(buf[13:], uint32(len(key)))
+	binary.BigEndian.PutUint32(buf[17:], uint32(len(val)))
+	copy(buf[headerSize:], key)
+	copy(buf[headerSize+len(key):], val)
+	binary.BigEndian.PutUint32(buf, crc32.Checksum(buf[4:], castagnoli))
+	return buf
+}
+
+// open 打开数据文件并重放记录重建索引，末尾损坏或不完整的记录截断
+func (c *Cache) open() error {
+	f, err := os.OpenFile(c.path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	var (
+		r      = bufio.NewReaderSize(f, 1<<20)
+		offset int64
+		now    = time.Now().UnixNano()
+		header = make([]byte, headerSize)
+	)
+	for {
+		if _, err = io.ReadFull(r, header); err != nil {
+			break
+		}
+		keyLen := binary.BigEndian.Uint32(header[13:])
+		valLen := binary.BigEndian.Uint32(header[17:])
+		// 长度超过文件剩余大小说明记录头损坏，按不完整的末尾处理，避免按损坏的长度分配内存
+		if int64(keyLen)+int64(valLen) > info.Size()-offset-headerSize {
+			break
+		}
+		body := make([]byte, int(keyLen)+int(valLen))
+		if _, err = io.ReadFull(r, body); err != nil {
+			break
+		}
+		crc := crc32.Update(crc32.Checksum(header[4:], castagnoli), castagnoli, body)
+		if crc != binary.BigEndian.Uint32(header) {
+			break
+		}
+
+		size := int64(headerSize + len(body))
+		key := string(body[:keyLen])
+		c.drop(key)
+		if header[4] == kindPut {
+			e := &entry{key: key, offset: offset, size: size, expireAt: int64(binary.BigEndian.Uint64(header[5:]))}
+			if !e.expired(now) {
+				e.elem = c.order.PushBack(e)
+				c.index[key] = e
+				c.live += size
+			}
+		}
+		offset += size
+	}
+
+	if err = f.Truncate(offset); err != nil {
+		_ = f.Close()
+		return err
+	}
+	c.file = f
+	c.size = offset
+	return nil
+}
+
+// compact 把有效数据按写入顺序写入新文件后替换，调用方需要持有写锁
+func (c *Cache) compact() error {
+	tmp := c.path + ".compact"
+	f, err := os.OpenFile(tmp, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+
+	var (
+		offset  int64
+		now     = time.Now().UnixNano()
+		offsets = make(map[*entry]int64, len(c.index))
+		w       = bufio.NewWriterSize(f, 1<<20)
+	)
+	for elem := c.order.Front(); elem != nil; elem = elem.Next() {
+		e := elem.Value.(*entry)
+		if e.expired(now) {
+			continue
+		}
+		buf := make([]byte, e.size)
+		if _, err = c.file.ReadAt(buf, e.offset); err != nil {
+			break
+		}
+		if _, err = w.Write(buf); err != nil {
+			break
+		}
+		offsets[e] = offset
+		offset += e.size
+	}
+	if err = errors.Join(err, w.Flush(), f.Sync()); err != nil {
+		_ = f.Close()
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err = os.Rename(tmp, c.path); err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	_ = c.file.Close()
+	c.file = f
+	c.size = offset
+	c.live = offset
+	for key, e := range c.index {
+		if o, ok := offsets[e]; ok {
+			e.offset = o
+			continue
+		}
+		c.order.Remove(e.elem)
+		delete(c.index, key)
+	}
+	return nil
+}
+
+// cleanup 定期删除过期数据，无效数据比例超过 compactRatio 时压缩
+func (c *Cache) cleanup() {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(c.conf.cleanupInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+			c.sm.Lock()
+			now := time.Now().UnixNano()
+			for key, e := range c.index {
+				if e.expired(now) {
+					c.drop(key) // 过期时间保存在记录中，重启后同样按过期处理，不需要写删除记录
+				}
+			}
+			if dead := c.size - c.live; dead >= minCompactSize && float64(dead) > float64(c.size)*c.conf.compactRatio {
+				if err := c.compact(); err != nil {
+					fmt.Println(err)
+				}
+			}
+			c.sm.Unlock()
+		}
+	}
+}
+
+func expireAt(expire time.Duration) int64 {
+	if expire <= 0 {
+		return 0
+	}
+	return time.Now().Add(expire).UnixNano()
+}
@@ -0,0 +1,203 @@
+package disk
+
+import (
+	"context"
+	"fmt"
+	"github.com/PycMono/go-cache/client"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestDisk(dir string, maxSize int64) *Cache {
+	conf := Config{}.WithDir(dir).WithMaxSize(maxSize).WithCleanupInterval(time.Hour)
+	adaptor, err := NewDiskAdaptor(&conf)
+	if err != nil {
+		panic(err)
+	}
+	return adaptor.(*Cache)
+}
+
+func TestConfig(t *testing.T) {
+	if _, err := NewDiskAdaptor(&Config{}); err == nil {
+		t.Fatalf("want error for empty dir")
+	}
+	conf := Config{}.WithDir(t.TempDir()).WithCompactRatio(1)
+	if _, err := NewDiskAdaptor(&conf); err == nil {
+		t.Fatalf("want error for compactRatio 1")
+	}
+}
+
+func TestDisk(t *testing.T) {
+	var (
+		dir   = t.TempDir()
+		cache = newTestDisk(dir, 0)
+		ctx   = context.TODO()
+	)
+	if err := cache.Set(ctx, map[string][]byte{"1": []byte("a"), "2": []byte("b")}, time.Hour); err != nil {
+		panic(err)
+	}
+	if err := cache.Set(ctx, map[string][]byte{"3": []byte("c")}, 50*time.Millisecond); err != nil {
+		panic(err)
+	}
+	kvMap, err := cache.Get(ctx, []string{"1", "2", "3", "4"})
+	if err != nil {
+		panic(err)
+	}
+	if len(kvMap) != 3 || string(kvMap["1"]) != "a" {
+		t.Fatalf("kvMap = %v, want 1,2,3", kvMap)
+	}
+	ttl, _ := cache.TTL(ctx, []string{"1"})
+	if ttl["1"] <= 59*time.Minute || ttl["1"] > time.Hour {
+		t.Fatalf("ttl = %v, want about 1h", ttl["1"])
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if kvMap, _ = cache.Get(ctx, []string{"3"}); len(kvMap) != 0 {
+		t.Fatalf("kvMap = %v, want expired", kvMap)
+	}
+
+	// 条件写入
+	ok, _ := cache.SetIfAbsent(ctx, map[string][]byte{"1": []byte("x"), "3": []byte("x")}, 0)
+	if ok["1"] || !ok["3"] {
+		t.Fatalf("SetIfAbsent = %v, want 3 only", ok)
+	}
+	ok, _ = cache.CompareAndSwap(ctx, map[string]client.Swap{"1": {Value: []byte("aa"), Version: client.Version([]byte("a"))}}, time.Hour)
+	if !ok["1"] {
+		t.Fatalf("CompareAndSwap = %v, want swapped", ok)
+	}
+
+	_ = cache.Del(ctx, []string{"2"})
+	if err = cache.Close(); err != nil {
+		panic(err)
+	}
+	if _, err = cache.Get(ctx, []string{"1"}); err != client.ErrClosed {
+		t.Fatalf("err = %v, want ErrClosed", err)
+	}
+
+	// 重启后恢复
+	cache = newTestDisk(dir, 0)
+	defer cache.Close()
+	kvMap, _ = cache.Get(ctx, []string{"1", "2", "3"})
+	if len(kvMap) != 2 || string(kvMap["1"]) != "aa" || string(kvMap["3"]) != "x" {
+		t.Fatalf("kvMap = %v, want 1=aa 3=x", kvMap)
+	}
+	ttl, _ = cache.TTL(ctx, []string{"3"})
+	if ttl["3"] != client.NoExpire {
+		t.Fatalf("ttl = %v, want NoExpire", ttl["3"])
+	}
+	if n, _ := cache.DelPrefix(ctx, "1"); n != 1 {
+		t.Fatalf("DelPrefix = %d, want 1", n)
+	}
+}
+
+func TestMaxSize(t *testing.T) {
+	var (
+		cache = newTestDisk(t.TempDir(), 10*(headerSize+2+100))
+		ctx   = context.TODO()
+		value = make([]byte, 100)
+	)
+	defer cache.Close()
+
+	for i := 10; i < 30; i++ {
+		_ = cache.Set(ctx, map[string][]byte{fmt.Sprint(i): value}, 0)
+	}
+	kvMap, _ := cache.Get(ctx, []string{"10", "19", "20", "29"})
+	if len(kvMap) != 2 || kvMap["20"] == nil || kvMap["29"] == nil {
+		t.Fatalf("keys = %v, want 20 and 29", len(kvMap))
+	}
+	if live, _ := cache.Size(); live > 10*(headerSize+2+100) {
+		t.Fatalf("live = %d, want <= maxSize", live)
+	}
+}
+
+func TestCompact(t *testing.T) {
+	var (
+		dir   = t.TempDir()
+		cache = newTestDisk(dir, 0)
+		ctx   = context.TODO()
+	)
+	for i := 0; i < 100; i++ {
+		_ = cache.Set(ctx, map[string][]byte{fmt.Sprint(i % 10): []byte(fmt.Sprint(i))}, 0)
+	}
+	_, before := cache.Size()
+	if err := cache.Compact(); err != nil {
+		panic(err)
+	}
+	live, after := cache.Size()
+	if live != after || after >= before {
+		t.Fatalf("size = %d after compact, want < %d", after, before)
+	}
+	kvMap, _ := cache.Get(ctx, []string{"0", "9"})
+	if string(kvMap["0"]) != "90" || string(kvMap["9"]) != "99" {
+		t.Fatalf("kvMap = %v, want 0=90 9=99", kvMap)
+	}
+
+	// 压缩后写入并重启
+	_ = cache.Set(ctx, map[string][]byte{"0": []byte("new")}, 0)
+	_ = cache.Close()
+	cache = newTestDisk(dir, 0)
+	defer cache.Close()
+	kvMap, _ = cache.Get(ctx, []string{"0", "9"})
+	if string(kvMap["0"]) != "new" || string(kvMap["9"]) != "99" {
+		t.Fatalf("kvMap = %v, want 0=new 9=99", kvMap)
+	}
+}
+
+func TestRecover(t *testing.T) {
+	var (
+		dir   = t.TempDir()
+		cache = newTestDisk(dir, 0)
+		ctx   = context.TODO()
+	)
+	_ = cache.Set(ctx, map[string][]byte{"1": []byte("a")}, 0)
+	_ = cache.Set(ctx, map[string][]byte{"2": []byte("b")}, 0)
+	_ = cache.Close()
+
+	// 模拟最后一条记录只写了一半
+	path := filepath.Join(dir, dataFile)
+	info, err := os.Stat(path)
+	if err != nil {
+		panic(err)
+	}
+	if err = os.Truncate(path, info.Size()-1); err != nil {
+		panic(err)
+	}
+
+	cache = newTestDisk(dir, 0)
+	kvMap, _ := cache.Get(ctx, []string{"1", "2"})
+	if len(kvMap) != 1 || string(kvMap["1"]) != "a" {
+		t.Fatalf("kvMap = %v, want 1=a", kvMap)
+	}
+	if _, size := cache.Size(); size != headerSize+2 {
+		t.Fatalf("file size = %d, want %d", size, headerSize+2)
+	}
+	_ = cache.Set(ctx, map[string][]byte{"2": []byte("c")}, 0)
+	if kvMap, _ = cache.Get(ctx, []string{"2"}); string(kvMap["2"]) != "c" {
+		t.Fatalf("kvMap = %v, want 2=c", kvMap)
+	}
+	_ = cache.Close()
+
+	// 记录头中的长度损坏，超过文件剩余大小，按不完整的末尾截断
+	info, _ = os.Stat(path)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		panic(err)
+	}
+	header := make([]byte, headerSize)
+	for i := 13; i < headerSize; i++ {
+		header[i] = 0xff
+	}
+	_, _ = f.Write(header)
+	_ = f.Close()
+
+	cache = newTestDisk(dir, 0)
+	defer cache.Close()
+	if kvMap, _ = cache.Get(ctx, []string{"1", "2"}); len(kvMap) != 2 {
+		t.Fatalf("kvMap = %v, want 1 and 2", kvMap)
+	}
+	if _, size := cache.Size(); size != info.Size() {
+		t.Fatalf("file size = %d, want %d", size, info.Size())
+	}
+}
@@ -0,0 +1,161 @@
+package evict
+
+const (
+	segT1 = iota // 最近只访问过一次
+	segT2        // 最近访问过多次
+)
+
+// arc 自适应替换缓存，按占用（cost）计算的 ARC
+// t1、t2 保存数据，b1、b2 只记录最近从 t1、t2 淘汰的key，命中 b1 说明应该给 t1 更多空间，命中 b2 反之
+type arc struct {
+	capacity int64
+	p        int64 // t1 的目标占用
+	t1, t2   sizedList
+	b1, b2   ghostList
+}
+
+func newARC(capacity int64) *arc {
+	return &arc{
+		capacity: capacity,
+		b1:       newGhostList(),
+		b2:       newGhostList(),
+	}
+}
+
+func (a *arc) add(e *entry) []*entry {
+	var fromB2 bool
+	switch {
+	case a.b1.contains(e.key):
+		// 最近从 t1 淘汰的数据再次访问，增大 t1 的目标占用
+		a.p = min(a.capacity, a.p+max(a.b2.cost/max(a.b1.cost, 1), 1)*e.cost)
+		a.b1.remove(e.key)
+		a.t2.pushFront(e, segT2)
+	case a.b2.contains(e.key):
+		a.p = max(0, a.p-max(a.b1.cost/max(a.b2.cost, 1), 1)*e.cost)
+		a.b2.remove(e.key)
+		a.t2.pushFront(e, segT2)
+		fromB2 = true
+	default:
+		a.t1.pushFront(e, segT1)
+	}
+
+	victims := a.replace(fromB2)
+	a.trimGhost()
+	return victims
+}
+
+func (a *arc) hit(e *entry) {
+	if e.seg == segT1 {
+		a.t1.remove(e)
+		a.t2.pushFront(e, segT2)
+		return
+	}
+	a.t2.moveToFront(e)
+}
+
+func (a *arc) update(e *entry, delta int64) []*entry {
+	if e.seg == segT1 {
+		a.t1.cost += delta
+	} else {
+		a.t2.cost += delta
+	}
+	a.hit(e)
+
+	victims := a.replace(false)
+	a.trimGhost()
+	return victims
+}
+
+func (a *arc) remove(e *entry) {
+	if e.seg == segT1 {
+		a.t1.remove(e)
+		return
+	}
+	a.t2.remove(e)
+}
+
+// replace 数据超出容量时，按目标占用p从 t1 或 t2 淘汰数据，并记录到对应的 ghost 列表
+func (a *arc) replace(fromB2 bool) []*entry {
+	var victims []*entry
+	for a.t1.cost+a.t2.cost > a.capacity {
+		var victim *entry
+		if a.t1.cost > 0 && (a.t1.cost > a.p || (fromB2 && a.t1.cost == a.p) || a.t2.cost == 0) {
+			victim = a.t1.back()
+			a.t1.remove(victim)
+			a.b1.pushFront(victim.key, victim.cost)
+		} else {
+			victim = a.t2.back()
+			a.t2.remove(victim)
+			a.b2.pushFront(victim.key, victim.cost)
+		}
+		victims = append(victims, victim)
+	}
+	return victims
+}
+
+// trimGhost 限制 ghost 列表大小：t1+b1 不超过容量，总和不超过2倍容量
+func (a *arc) trimGhost() {
+	for a.t1.cost+a.b1.cost > a.capacity && a.b1.len() > 0 {
+		a.b1.removeBack()
+	}
+	for a.t1.cost+a.t2.cost+a.b1.cost+a.b2.cost > 2*a.capacity && a.b2.len() > 0 {
+		a.b2.removeBack()
+	}
+}
+
+// ghostList 只记录key和占用的LRU链表
+type ghostList struct {
+	keys  map[string]*ghost
+	head  ghost // 哨兵，head.next 为最近淘汰
+	cost  int64
+	count int
+}
+
+type ghost struct {
+	key        string
+	cost       int64
+	prev, next *ghost
+}
+
+func newGhostList() ghostList {
+	return ghostList{keys: make(map[string]*ghost)}
+}
+
+func (g *ghostList) contains(key string) bool {
+	_, ok := g.keys[key]
+	return ok
+}
+
+func (g *ghostList) len() int {
+	return g.count
+}
+
+func (g *ghostList) pushFront(key string, cost int64) {
+	if g.head.next == nil {
+		g.head.next, g.head.prev = &g.head, &g.head
+	}
+	n := &ghost{key: key, cost: cost, prev: &g.head, next: g.head.next}
+	g.head.next.prev = n
+	g.head.next = n
+	g.keys[key] = n
+	g.cost += cost
+	g.count++
+}
+
+func (g *ghostList) remove(key string) {
+	n, ok := g.keys[key]
+	if !ok {
+		return
+	}
+	n.prev.next = n.next
+	n.next.prev = n.prev
+	delete(g.keys, key)
+	g.cost -= n.cost
+	g.count--
+}
+
+func (g *ghostList) removeBack() {
+	if g.count > 0 {
+		g.remove(g.head.prev.key)
+	}
+}
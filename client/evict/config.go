@@ -0,0 +1,127 @@
+package evict
+
+import (
+	"fmt"
+	"github.com/PycMono/go-cache/client"
+	"time"
+)
+
+// Policy 淘汰策略
+type Policy int
+
+const (
+	PolicyTinyLFU Policy = iota // W-TinyLFU，默认策略，窗口LRU + 频率准入 + 分段LRU，适合热点明显的场景
+	PolicyLRU                   // 最近最少使用
+	PolicyLFU                   // 最不经常使用
+	PolicyARC                   // 自适应替换，根据访问模式在最近使用和经常使用之间自动调整
+)
+
+func (p Policy) String() string {
+	switch p {
+	case PolicyTinyLFU:
+		return "tinylfu"
+	case PolicyLRU:
+		return "lru"
+	case PolicyLFU:
+		return "lfu"
+	case PolicyARC:
+		return "arc"
+	}
+	return fmt.Sprintf("unknown(%d)", int(p))
+}
+
+// Config 配置文件
+type Config struct {
+	policy          Policy                             // 淘汰策略，默认 PolicyTinyLFU
+	maxCost         int64                              // 总容量（默认64MB），按 weigher 计算，平均分配到每个分片
+	weigher         func(key string, val []byte) int64 // 计算单条数据的占用，默认 len(key)+len(val)，可以自定义为条数等权重
+	shards          int                                // 分片数（默认16），向上取整为2的幂
+	cleanupInterval time.Duration                      // 过期数据清理间隔（默认1分钟），过期数据在读取时也会删除
+	listeners       client.RemovalListeners[[]byte]    // 数据离开缓存的回调，异步执行
+	listenerBuffer  int                                // 回调事件队列长度（默认1024），队列满时等待 listenerWait 后丢弃事件
+	listenerWait    time.Duration                      // 回调事件队列满时的最长等待时间（默认0，直接丢弃），等待期间同一分片的读写也会等待
+}
+
+func (c Config) WithPolicy(policy Policy) Config {
+	c.policy = policy
+	return c
+}
+
+func (c Config) WithMaxCost(maxCost int64) Config {
+	c.maxCost = maxCost
+	return c
+}
+
+func (c Config) WithWeigher(weigher func(key string, val []byte) int64) Config {
+	c.weigher = weigher
+	return c
+}
+
+func (c Config) WithShards(shards int) Config {
+	c.shards = shards
+	return c
+}
+
+func (c Config) WithCleanupInterval(cleanupInterval time.Duration) Config {
+	c.cleanupInterval = cleanupInterval
+	return c
+}
+
+func (c Config) WithOnEvict(onEvict func(e client.RemovalEvent[[]byte])) Config {
+	c.listeners.OnEvict = onEvict
+	return c
+}
+
+func (c Config) WithOnExpire(onExpire func(e client.RemovalEvent[[]byte])) Config {
+	c.listeners.OnExpire = onExpire
+	return c
+}
+
+func (c Config) WithOnDelete(onDelete func(e client.RemovalEvent[[]byte])) Config {
+	c.listeners.OnDelete = onDelete
+	return c
+}
+
+func (c Config) WithListenerBuffer(listenerBuffer int) Config {
+	c.listenerBuffer = listenerBuffer
+	return c
+}
+
+func (c Config) WithListenerWait(listenerWait time.Duration) Config {
+	c.listenerWait = listenerWait
+	return c
+}
+
+func (c Config) build() (Config, error) {
+	if c.maxCost == 0 {
+		c.maxCost = 64 * 1024 * 1024
+	}
+	if c.weigher == nil {
+		c.weigher = func(key string, val []byte) int64 {
+			return int64(len(key) + len(val))
+		}
+	}
+	if c.shards == 0 {
+		c.shards = 16
+	}
+	if c.cleanupInterval == 0 {
+		c.cleanupInterval = time.Minute
+	}
+
+	if c.policy < PolicyTinyLFU || c.policy > PolicyARC {
+		return c, fmt.Errorf("不支持的淘汰策略%s", c.policy)
+	}
+	if c.maxCost < 0 || c.shards < 0 || c.cleanupInterval < 0 {
+		return c, fmt.Errorf("maxCost、shards、cleanupInterval不能为负数")
+	}
+	shards := 1
+	for shards < c.shards {
+		shards <<= 1
+	}
+	c.shards = shards
+	if c.maxCost < int64(c.shards) {
+		return c, fmt.Errorf("maxCost不能小于分片数%d", c.shards)
+	}
+
+	return c, nil
+}
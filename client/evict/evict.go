@@ -0,0 +1,613 @@
+package evict
+
+import (
+	"context"
+	"github.com/PycMono/go-cache/client"
+	"hash/maphash"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Cache 可选淘汰策略的内存缓存，容量按 weigher 计算的占用限制
+type Cache struct {
+	conf   Config
+	shards []*shard
+	mask   uint64
+	seed   maphash.Seed
+
+	notifier *client.RemovalNotifier[[]byte]
+	tags     client.TagIndex
+
+	hits, misses, sets, evictions, expired, rejected atomic.Uint64
+
+	sm     sync.RWMutex
+	closed bool
+	done   chan struct{} // 关闭时通知清理协程退出
+}
+
+// Stats 统计数据
+type Stats struct {
+	Hits      uint64 // 命中次数
+	Misses    uint64 // 未命中次数（包括已过期）
+	Sets      uint64 // 写入条数
+	Evictions uint64 // 因容量不足被淘汰的条数
+	Expired   uint64 // 过期删除的条数
+	Rejected  uint64 // 写入时被拒绝的条数（超过单个分片容量或未通过 TinyLFU 准入）
+	Dropped   uint64 // 回调队列满被丢弃的事件数
+	Len       int    // 当前条数
+	Cost      int64  // 当前占用
+}
+
+// HitRatio 命中率
+func (s Stats) HitRatio() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+type shard struct {
+	sm       sync.Mutex
+	items    map[string]*entry
+	policy   policy
+	capacity int64
+	cost     int64
+}
+
+func NewEvictAdaptor(conf *Config) (client.IAdaptor, error) {
+	c, err := conf.build()
+	if err != nil {
+		return nil, err
+	}
+
+	cache := &Cache{
+		conf:   c,
+		shards: make([]*shard, c.shards),
+		mask:   uint64(c.shards - 1),
+		seed:   maphash.MakeSeed(),
+		done:   make(chan struct{}),
+
+		notifier: client.NewRemovalNotifier(c.listeners, c.listenerBuffer, c.listenerWait),
+	}
+	capacity := c.maxCost / int64(c.shards)
+	for i := range cache.shards {
+		cache.shards[i] = &shard{
+			items:    make(map[string]*entry),
+			policy:   newPolicy(c.policy, capacity),
+			capacity: capacity,
+		}
+	}
+	go cache.cleanup(c.cleanupInterval)
+
+	return cache, nil
+}
+
+func (c *Cache) Set(ctx context.Context, params map[string][]byte, expire time.Duration) error {
+	if c.isClosed() {
+		return client.ErrClosed
+	}
+
+	var expireAt int64
+	if expire > 0 {
+		expireAt = time.Now().Add(expire).UnixNano()
+	}
+	for k, v := range params {
+		e := &entry{
+			key:      k,
+			val:      append([]byte(nil), v...),
+			cost:     c.conf.weigher(k, v),
+			expireAt: expireAt,
+		}
+		c.sets.Add(1)
+		c.getShard(k).set(c, e)
+	}
+
+	return nil
+}
+
+func (c *Cache) SetIfAbsent(ctx context.Context, params map[string][]byte, expire time.Duration) (map[string]bool, error) {
+	return c.setIf(params, expire, func(k string, old []byte, found bool) bool {
+		return !found
+	})
+}
+
+func (c *Cache) SetIfPresent(ctx context.Context, params map[string][]byte, expire time.Duration) (map[string]bool, error) {
+	return c.setIf(params, expire, func(k string, old []byte, found bool) bool {
+		return found
+	})
+}
+
+func (c *Cache) CompareAndSwap(ctx context.Context, params map[string]client.Swap, expire time.Duration) (map[string]bool, error) {
+	var (
+		kv      = make(map[string][]byte, len(params))
+		version = make(map[string]string, len(params))
+	)
+	for k, v := range params {
+		kv[k], version[k] = v.Value, v.Version
+	}
+
+	return c.setIf(kv, expire, func(k string, old []byte, found bool) bool {
+		if !found {
+			return version[k] == ""
+		}
+		return version[k] == client.Version(old)
+	})
+}
+
+func (c *Cache) IncrBy(ctx context.Context, params map[string]int64, expire time.Duration) (map[string]int64, error) {
+	if c.isClosed() {
+		return nil, client.ErrClosed
+	}
+
+	var (
+		now      = time.Now().UnixNano()
+		expireAt int64
+		out      = make(map[string]int64, len(params))
+	)
+	if expire > 0 {
+		expireAt = now + int64(expire)
+	}
+	for k, delta := range params {
+		n, err := c.getShard(k).incrBy(c, k, delta, expireAt, now)
+		if err != nil {
+			return nil, client.NotAppliedError(out, params, err)
+		}
+		c.sets.Add(1)
+		out[k] = n
+	}
+
+	return out, nil
+}
+
+// setIf 逐个key在分片锁内判断是否写入
+func (c *Cache) setIf(params map[string][]byte, expire time.Duration, ok func(k string, old []byte, found bool) bool) (map[string]bool, error) {
+	if c.isClosed() {
+		return nil, client.ErrClosed
+	}
+
+	var (
+		now      = time.Now().UnixNano()
+		expireAt int64
+		out      = make(map[string]bool, len(params))
+	)
+	if expire > 0 {
+		expireAt = now + int64(expire)
+	}
+	for k, v := range params {
+		e := &entry{
+			key:      k,
+			val:      append([]byte(nil), v...),
+			cost:     c.conf.weigher(k, v),
+			expireAt: expireAt,
+		}
+		out[k] = c.getShard(k).setIf(c, e, now, func(old []byte, found bool) bool {
+			return ok(k, old, found)
+		})
+		if out[k] {
+			c.sets.Add(1)
+		}
+	}
+
+	return out, nil
+}
+
+func (c *Cache) Get(ctx context.Context, k []string) (map[string][]byte, error) {
+	if c.isClosed() {
+		return nil, client.ErrClosed
+	}
+
+	var (
+		now = time.Now().UnixNano()
+		out = make(map[string][]byte)
+	)
+	for _, key := range k {
+		if val, ok := c.getShard(key).get(c, key, now); ok {
+			out[key] = val
+			c.hits.Add(1)
+			continue
+		}
+		c.misses.Add(1)
+	}
+
+	return out, nil
+}
+
+func (c *Cache) Del(ctx context.Context, k []string) error {
+	if c.isClosed() {
+		return client.ErrClosed
+	}
+
+	for _, key := range k {
+		c.getShard(key).del(c, key)
+	}
+	c.tags.Remove(k)
+
+	return nil
+}
+
+func (c *Cache) GetWithTTL(ctx context.Context, k []string) (map[string]client.Item, error) {
+	if c.isClosed() {
+		return nil, client.ErrClosed
+	}
+
+	var (
+		now = time.Now().UnixNano()
+		out = make(map[string]client.Item)
+	)
+	for _, key := range k {
+		if val, expireAt, ok := c.getShard(key).getWithExpire(c, key, now); ok {
+			out[key] = client.Item{Value: val, TTL: ttl(expireAt, now)}
+			c.hits.Add(1)
+			continue
+		}
+		c.misses.Add(1)
+	}
+
+	return out, nil
+}
+
+func (c *Cache) TTL(ctx context.Context, k []string) (map[string]time.Duration, error) {
+	if c.isClosed() {
+		return nil, client.ErrClosed
+	}
+
+	var (
+		now = time.Now().UnixNano()
+		out = make(map[string]time.Duration)
+	)
+	for _, key := range k {
+		if expireAt, ok := c.getShard(key).expireAt(key, now); ok {
+			out[key] = ttl(expireAt, now)
+		}
+	}
+
+	return out, nil
+}
+
+// Expire 只修改过期时间，不算作访问
+func (c *Cache) Expire(ctx context.Context, k []string, expire time.Duration) error {
+	if c.isClosed() {
+		return client.ErrClosed
+	}
+
+	var (
+		now      = time.Now().UnixNano()
+		expireAt int64
+	)
+	if expire > 0 {
+		expireAt = now + int64(expire)
+	}
+	for _, key := range k {
+		c.getShard(key).expire(key, expireAt, now)
+	}
+
+	return nil
+}
+
+// Close 停止清理协程并清空缓存，重复调用返回 client.ErrClosed
+func (c *Cache) Close() error {
+	c.sm.Lock()
+	defer c.sm.Unlock()
+
+	if c.closed {
+		return client.ErrClosed
+	}
+	c.closed = true
+	close(c.done)
+	for _, v := range c.shards {
+		v.clear(c.conf.policy)
+	}
+	c.notifier.Close() // Close 清空的数据不通知
+	c.tags.Clear()
+
+	return nil
+}
+
+// Scan 每次 Next 返回一个分片中匹配的key
+func (c *Cache) Scan(ctx context.Context, pattern string) client.KeyIterator {
+	if c.isClosed() {
+		return client.ErrIterator(client.ErrClosed)
+	}
+
+	i := 0
+	return client.NewKeyIterator(func(ctx context.Context) ([]string, bool, error) {
+		if c.isClosed() {
+			return nil, true, client.ErrClosed
+		}
+		keys := c.shards[i].keys(pattern, time.Now().UnixNano())
+		i++
+		return keys, i == len(c.shards), nil
+	})
+}
+
+func (c *Cache) DelPrefix(ctx context.Context, prefix string) (int64, error) {
+	var (
+		n  int64
+		it = c.Scan(ctx, client.EscapePattern(prefix)+"*")
+	)
+	for it.Next(ctx) {
+		keys := it.Keys()
+		if err := c.Del(ctx, keys); err != nil {
+			return n, err
+		}
+		n += int64(len(keys))
+	}
+	return n, it.Err()
+}
+
+func (c *Cache) AddTags(ctx context.Context, params map[string][]string, expire time.Duration) error {
+	if c.isClosed() {
+		return client.ErrClosed
+	}
+
+	c.tags.Add(params, expire)
+	return nil
+}
+
+func (c *Cache) TagKeys(ctx context.Context, tags []string) ([]string, error) {
+	if c.isClosed() {
+		return nil, client.ErrClosed
+	}
+
+	now := time.Now().UnixNano()
+	return c.tags.Keys(tags, func(key string) bool {
+		_, ok := c.getShard(key).expireAt(key, now)
+		return ok
+	}), nil
+}
+
+func (c *Cache) DelTags(ctx context.Context, tags []string) error {
+	if c.isClosed() {
+		return client.ErrClosed
+	}
+
+	c.tags.Del(tags)
+	return nil
+}
+
+// Stats 统计数据
+func (c *Cache) Stats() Stats {
+	s := Stats{
+		Hits:      c.hits.Load(),
+		Misses:    c.misses.Load(),
+		Sets:      c.sets.Load(),
+		Evictions: c.evictions.Load(),
+		Expired:   c.expired.Load(),
+		Rejected:  c.rejected.Load(),
+		Dropped:   c.notifier.Dropped(),
+	}
+	for _, v := range c.shards {
+		v.sm.Lock()
+		s.Len += len(v.items)
+		s.Cost += v.cost
+		v.sm.Unlock()
+	}
+	return s
+}
+
+func (c *Cache) isClosed() bool {
+	c.sm.RLock()
+	defer c.sm.RUnlock()
+
+	return c.closed
+}
+
+func (c *Cache) getShard(k string) *shard {
+	return c.shards[maphash.String(c.seed, k)&c.mask]
+}
+
+// cleanup 定时清理过期数据，Close 后退出
+func (c *Cache) cleanup(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+		}
+
+		now := time.Now().UnixNano()
+		for _, v := range c.shards {
+			v.removeExpired(c, now)
+		}
+	}
+}
+
+func (s *shard) set(c *Cache, e *entry) {
+	s.sm.Lock()
+	defer s.sm.Unlock()
+
+	s.setLocked(c, e)
+}
+
+// setIf 在分片锁内检查当前数据，ok 返回true时写入，已过期的数据按不存在处理
+func (s *shard) setIf(c *Cache, e *entry, now int64, ok func(old []byte, found bool) bool) bool {
+	s.sm.Lock()
+	defer s.sm.Unlock()
+
+	old, found := s.items[e.key]
+	if found && old.expireAt > 0 && old.expireAt <= now {
+		found = false
+	}
+	var val []byte
+	if found {
+		val = old.val
+	}
+	if !ok(val, found) {
+		return false
+	}
+
+	s.setLocked(c, e)
+	return true
+}
+
+// incrBy 在分片锁内给计数加上delta，已存在的key保留原来的过期时间
+func (s *shard) incrBy(c *Cache, k string, delta, expireAt, now int64) (int64, error) {
+	s.sm.Lock()
+	defer s.sm.Unlock()
+
+	var n int64
+	if old, ok := s.items[k]; ok && (old.expireAt == 0 || old.expireAt > now) {
+		v, err := strconv.ParseInt(string(old.val), 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		n, expireAt = v, old.expireAt
+	}
+	n += delta
+
+	val := []byte(strconv.FormatInt(n, 10))
+	s.setLocked(c, &entry{key: k, val: val, cost: c.conf.weigher(k, val), expireAt: expireAt})
+	return n, nil
+}
+
+// setLocked 写入数据，调用方需要持有分片锁
+func (s *shard) setLocked(c *Cache, e *entry) {
+	old, ok := s.items[e.key]
+	if e.cost > s.capacity {
+		// 单条数据超过分片容量，直接拒绝，同时删除旧数据避免读到旧值
+		if ok {
+			s.remove(old)
+			c.notifier.Notify(old.key, old.val, client.ReasonReplaced)
+		}
+		c.rejected.Add(1)
+		return
+	}
+
+	var (
+		victims []*entry
+		written = e // 更新时原地修改旧entry，淘汰策略返回的是旧entry
+	)
+	if ok {
+		c.notifier.Notify(old.key, old.val, client.ReasonReplaced)
+		delta := e.cost - old.cost
+		old.val, old.cost, old.expireAt = e.val, e.cost, e.expireAt
+		s.cost += delta
+		victims = s.policy.update(old, delta)
+		written = old
+	} else {
+		s.items[e.key] = e
+		s.cost += e.cost
+		victims = s.policy.add(e)
+	}
+
+	for _, v := range victims {
+		delete(s.items, v.key)
+		s.cost -= v.cost
+		if v == written {
+			// 写入的数据被立即淘汰按拒绝处理，更新时只通知旧数据被替换，和超过分片容量时一致
+			c.rejected.Add(1)
+			continue
+		}
+		c.evictions.Add(1)
+		c.notifier.Notify(v.key, v.val, client.ReasonEvicted)
+	}
+}
+
+func (s *shard) get(c *Cache, k string, now int64) ([]byte, bool) {
+	val, _, ok := s.getWithExpire(c, k, now)
+	return val, ok
+}
+
+func (s *shard) getWithExpire(c *Cache, k string, now int64) ([]byte, int64, bool) {
+	s.sm.Lock()
+	defer s.sm.Unlock()
+
+	e, ok := s.items[k]
+	if !ok {
+		return nil, 0, false
+	}
+	if e.expireAt > 0 && e.expireAt <= now {
+		s.remove(e)
+		c.expired.Add(1)
+		c.notifier.Notify(e.key, e.val, client.ReasonExpired)
+		return nil, 0, false
+	}
+
+	s.policy.hit(e)
+	return e.val, e.expireAt, true
+}
+
+func (s *shard) expireAt(k string, now int64) (int64, bool) {
+	s.sm.Lock()
+	defer s.sm.Unlock()
+
+	e, ok := s.items[k]
+	if !ok || (e.expireAt > 0 && e.expireAt <= now) {
+		return 0, false
+	}
+	return e.expireAt, true
+}
+
+func (s *shard) expire(k string, expireAt, now int64) {
+	s.sm.Lock()
+	defer s.sm.Unlock()
+
+	if e, ok := s.items[k]; ok && (e.expireAt == 0 || e.expireAt > now) {
+		e.expireAt = expireAt
+	}
+}
+
+func (s *shard) del(c *Cache, k string) {
+	s.sm.Lock()
+	defer s.sm.Unlock()
+
+	if e, ok := s.items[k]; ok {
+		s.remove(e)
+		c.notifier.Notify(e.key, e.val, client.ReasonDeleted)
+	}
+}
+
+// keys 返回匹配pattern的未过期key
+func (s *shard) keys(pattern string, now int64) []string {
+	s.sm.Lock()
+	defer s.sm.Unlock()
+
+	var out []string
+	for k, e := range s.items {
+		if (e.expireAt == 0 || e.expireAt > now) && client.MatchPattern(pattern, k) {
+			out = append(out, k)
+		}
+	}
+	return out
+}
+
+func (s *shard) removeExpired(c *Cache, now int64) {
+	s.sm.Lock()
+	defer s.sm.Unlock()
+
+	for _, e := range s.items {
+		if e.expireAt > 0 && e.expireAt <= now {
+			s.remove(e)
+			c.expired.Add(1)
+			c.notifier.Notify(e.key, e.val, client.ReasonExpired)
+		}
+	}
+}
+
+func (s *shard) clear(p Policy) {
+	s.sm.Lock()
+	defer s.sm.Unlock()
+
+	s.items = make(map[string]*entry)
+	s.policy = newPolicy(p, s.capacity)
+	s.cost = 0
+}
+
+// ttl 根据过期时间计算剩余过期时间
+func ttl(expireAt, now int64) time.Duration {
+	if expireAt == 0 {
+		return client.NoExpire
+	}
+	return time.Duration(expireAt - now)
+}
+
+// remove 删除数据，调用方需持有锁
+func (s *shard) remove(e *entry) {
+	s.policy.remove(e)
+	delete(s.items, e.key)
+	s.cost -= e.cost
+}
@@ -0,0 +1,267 @@
+package evict
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/PycMono/go-cache/client"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestEvict(t *testing.T) {
+	for _, policy := range []Policy{PolicyTinyLFU, PolicyLRU, PolicyLFU, PolicyARC} {
+		t.Run(policy.String(), func(t *testing.T) {
+			// 按条数计算容量，最多100条
+			conf := Config{}.WithPolicy(policy).
+				WithShards(1).
+				WithMaxCost(100).
+				WithWeigher(func(key string, val []byte) int64 { return 1 })
+			adaptor, err := NewEvictAdaptor(&conf)
+			if err != nil {
+				panic(err)
+			}
+			c := adaptor.(*Cache)
+
+			for i := 0; i < 1000; i++ {
+				k := fmt.Sprintf("%d", i)
+				_ = c.Set(context.TODO(), map[string][]byte{k: []byte(k)}, time.Hour)
+				_, _ = c.Get(context.TODO(), []string{k})
+			}
+			stats := c.Stats()
+			if stats.Cost > 100 || stats.Len != int(stats.Cost) {
+				t.Fatalf("stats = %+v, want cost <= 100", stats)
+			}
+
+			_ = c.Set(context.TODO(), map[string][]byte{"ttl": []byte("1")}, 10*time.Millisecond)
+			time.Sleep(20 * time.Millisecond)
+			kvMap, _ := c.Get(context.TODO(), []string{"ttl"})
+			if len(kvMap) != 0 {
+				t.Fatalf("kvMap = %v, want expired", kvMap)
+			}
+
+			_ = c.Close()
+			if _, err = c.Get(context.TODO(), []string{"1"}); !errors.Is(err, client.ErrClosed) {
+				t.Fatalf("err = %v, want ErrClosed", err)
+			}
+		})
+	}
+}
+
+// TestHitRatio 热点数据（zipf分布）下各策略的命中率
+func TestHitRatio(t *testing.T) {
+	var (
+		r    = rand.New(rand.NewSource(1))
+		zipf = rand.NewZipf(r, 1.1, 10, 100000)
+		keys = make([]string, 200000)
+	)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("%d", zipf.Uint64())
+	}
+
+	ratio := make(map[Policy]float64)
+	for _, policy := range []Policy{PolicyTinyLFU, PolicyLRU, PolicyLFU, PolicyARC} {
+		conf := Config{}.WithPolicy(policy).
+			WithShards(1).
+			WithMaxCost(1000).
+			WithWeigher(func(key string, val []byte) int64 { return 1 })
+		adaptor, err := NewEvictAdaptor(&conf)
+		if err != nil {
+			panic(err)
+		}
+		c := adaptor.(*Cache)
+
+		for _, k := range keys {
+			kvMap, _ := c.Get(context.TODO(), []string{k})
+			if len(kvMap) == 0 {
+				_ = c.Set(context.TODO(), map[string][]byte{k: []byte(k)}, 0)
+			}
+		}
+		ratio[policy] = c.Stats().HitRatio()
+		t.Logf("%s hit ratio: %.4f", policy, ratio[policy])
+	}
+
+	if ratio[PolicyTinyLFU] <= ratio[PolicyLRU] {
+		t.Fatalf("tinylfu hit ratio %.4f, want > lru %.4f", ratio[PolicyTinyLFU], ratio[PolicyLRU])
+	}
+}
+
+// TestSlowListener 回调阻塞时写入不受影响，多出来的事件被丢弃
+func TestSlowListener(t *testing.T) {
+	release := make(chan struct{})
+	conf := Config{}.WithPolicy(PolicyLRU).
+		WithShards(1).
+		WithMaxCost(10).
+		WithWeigher(func(key string, val []byte) int64 { return 1 }).
+		WithListenerBuffer(1).
+		WithOnEvict(func(e client.RemovalEvent[[]byte]) {
+			<-release
+		})
+	adaptor, err := NewEvictAdaptor(&conf)
+	if err != nil {
+		panic(err)
+	}
+	c := adaptor.(*Cache)
+	defer c.Close()
+	defer close(release)
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 100; i++ {
+			k := fmt.Sprintf("%d", i)
+			_ = c.Set(context.TODO(), map[string][]byte{k: []byte(k)}, 0)
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("Set blocked by slow listener")
+	}
+
+	if stats := c.Stats(); stats.Evictions != 90 || stats.Dropped == 0 {
+		t.Fatalf("stats = %+v, want 90 evictions and dropped events", stats)
+	}
+}
+
+// TestUpdateRejected 更新后的数据被立即淘汰时按拒绝处理，只通知旧数据被替换
+func TestUpdateRejected(t *testing.T) {
+	events := make(chan client.RemovalReason, 10)
+	listener := func(e client.RemovalEvent[[]byte]) {
+		events <- e.Reason
+	}
+	conf := Config{}.WithPolicy(PolicyLFU).
+		WithShards(1).
+		WithMaxCost(10).
+		WithWeigher(func(key string, val []byte) int64 { return int64(len(val)) }).
+		WithOnEvict(listener).
+		WithOnDelete(listener)
+	adaptor, err := NewEvictAdaptor(&conf)
+	if err != nil {
+		panic(err)
+	}
+	c := adaptor.(*Cache)
+	defer c.Close()
+
+	_ = c.Set(context.TODO(), map[string][]byte{"a": []byte("aaaaa"), "b": []byte("b")}, 0)
+	for i := 0; i < 5; i++ {
+		_, _ = c.Get(context.TODO(), []string{"a"})
+	}
+	// b 变大后超出容量，访问次数最少的b自己被淘汰
+	_ = c.Set(context.TODO(), map[string][]byte{"b": []byte("bbbbbb")}, 0)
+
+	if stats := c.Stats(); stats.Rejected != 1 || stats.Evictions != 0 {
+		t.Fatalf("stats = %+v, want 1 rejected and no evictions", stats)
+	}
+	select {
+	case r := <-events:
+		if r != client.ReasonReplaced {
+			t.Fatalf("reason = %s, want replaced", r)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("want replaced event")
+	}
+	select {
+	case r := <-events:
+		t.Fatalf("unexpected %s event", r)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestTags(t *testing.T) {
+	conf := Config{}
+	adaptor, err := NewEvictAdaptor(&conf)
+	if err != nil {
+		panic(err)
+	}
+	tagger := adaptor.(client.ITagAdaptor)
+	ctx := context.TODO()
+
+	_ = tagger.Set(ctx, map[string][]byte{"a": []byte("a")}, 20*time.Millisecond)
+	_ = tagger.Set(ctx, map[string][]byte{"b": []byte("b"), "c": []byte("c"), "d": []byte("d")}, 0)
+	err = tagger.AddTags(ctx, map[string][]string{"a": {"t1"}}, 20*time.Millisecond)
+	if err != nil {
+		panic(err)
+	}
+	_ = tagger.AddTags(ctx, map[string][]string{"b": {"t1", "t2"}, "c": {"t2"}, "d": {"t2"}, "missing": {"t2"}}, 0)
+	keys, _ := tagger.TagKeys(ctx, []string{"t1"})
+	if len(keys) != 2 {
+		t.Fatalf("keys = %v, want a and b", keys)
+	}
+
+	// 删除的key和缓存中不存在的key从索引中清理
+	_ = tagger.Del(ctx, []string{"c"})
+	_, _ = tagger.DelPrefix(ctx, "d")
+	if keys, _ = tagger.TagKeys(ctx, []string{"t2"}); len(keys) != 1 || keys[0] != "b" {
+		t.Fatalf("keys = %v, want b", keys)
+	}
+
+	// 过期的key从索引中清理
+	time.Sleep(30 * time.Millisecond)
+	keys, _ = tagger.TagKeys(ctx, []string{"t1"})
+	if len(keys) != 1 || keys[0] != "b" {
+		t.Fatalf("keys = %v, want b", keys)
+	}
+
+	_ = tagger.DelTags(ctx, []string{"t1"})
+	if keys, _ = tagger.TagKeys(ctx, []string{"t1"}); len(keys) != 0 {
+		t.Fatalf("keys = %v, want empty", keys)
+	}
+}
+
+func TestScan(t *testing.T) {
+	conf := Config{}.WithShards(4)
+	adaptor, err := NewEvictAdaptor(&conf)
+	if err != nil {
+		panic(err)
+	}
+	ctx := context.TODO()
+
+	_ = adaptor.Set(ctx, map[string][]byte{
+		"user:1":   []byte("1"),
+		"user:2":   []byte("2"),
+		"user:*":   []byte("*"),
+		"order:1":  []byte("1"),
+		"user:old": []byte("old"),
+	}, time.Hour)
+	_ = adaptor.Set(ctx, map[string][]byte{"user:3": []byte("3")}, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+
+	count := func(pattern string) int {
+		var n int
+		it := adaptor.Scan(ctx, pattern)
+		for it.Next(ctx) {
+			n += len(it.Keys())
+		}
+		if err := it.Err(); err != nil {
+			panic(err)
+		}
+		return n
+	}
+	for pattern, want := range map[string]int{
+		"user:*":       4, // 不返回已过期的key
+		"user:?":       3,
+		"user:[12]":    2,
+		"user:[^12]":   1,
+		`user:\*`:      1,
+		"*":            5,
+		"order:[a-z]*": 0,
+	} {
+		if n := count(pattern); n != want {
+			t.Fatalf("Scan(%q) = %d keys, want %d", pattern, n, want)
+		}
+	}
+
+	// 前缀中的通配符按普通字符处理
+	n, err := adaptor.DelPrefix(ctx, "user:*")
+	if err != nil || n != 1 {
+		t.Fatalf("DelPrefix(user:*) = %d, %v, want 1", n, err)
+	}
+	if n, _ = adaptor.DelPrefix(ctx, "user:"); n != 3 {
+		t.Fatalf("DelPrefix(user:) = %d, want 3", n)
+	}
+	if n := count("*"); n != 1 {
+		t.Fatalf("remaining = %d, want 1", n)
+	}
+}
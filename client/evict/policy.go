@@ -0,0 +1,189 @@
+package evict
+
+import (
+	"container/heap"
+	"container/list"
+)
+
+// entry 缓存数据
+type entry struct {
+	key      string
+	val      []byte
+	cost     int64
+	expireAt int64 // 过期时间（UnixNano），0表示不过期
+
+	el    *list.Element // 所在链表节点
+	seg   int           // 所在分段，由各策略定义
+	freq  uint64        // LFU 访问次数
+	tick  uint64        // LFU 最近访问序号，次数相同时先淘汰更早访问的数据
+	index int           // LFU 堆下标
+}
+
+// policy 淘汰策略，所有方法由分片在持有锁时调用
+// 返回的 victims 为需要从分片中删除的数据，可能包含刚写入的数据（表示拒绝写入）
+type policy interface {
+	add(e *entry) (victims []*entry)
+	hit(e *entry)
+	update(e *entry, delta int64) (victims []*entry) // 已存在的数据重新写入，delta 为占用变化
+	remove(e *entry)
+}
+
+func newPolicy(p Policy, capacity int64) policy {
+	switch p {
+	case PolicyLRU:
+		return &lru{capacity: capacity}
+	case PolicyLFU:
+		return &lfu{capacity: capacity}
+	case PolicyARC:
+		return newARC(capacity)
+	default:
+		return newTinyLFU(capacity)
+	}
+}
+
+// sizedList 记录总占用的链表，头部为最近使用
+type sizedList struct {
+	ll   list.List
+	cost int64
+}
+
+func (l *sizedList) pushFront(e *entry, seg int) {
+	e.el = l.ll.PushFront(e)
+	e.seg = seg
+	l.cost += e.cost
+}
+
+func (l *sizedList) remove(e *entry) {
+	l.ll.Remove(e.el)
+	l.cost -= e.cost
+	e.el = nil
+}
+
+func (l *sizedList) moveToFront(e *entry) {
+	l.ll.MoveToFront(e.el)
+}
+
+func (l *sizedList) back() *entry {
+	if el := l.ll.Back(); el != nil {
+		return el.Value.(*entry)
+	}
+	return nil
+}
+
+// lru 最近最少使用
+type lru struct {
+	capacity int64
+	list     sizedList
+}
+
+func (p *lru) add(e *entry) []*entry {
+	p.list.pushFront(e, 0)
+	return p.evict()
+}
+
+func (p *lru) hit(e *entry) {
+	p.list.moveToFront(e)
+}
+
+func (p *lru) update(e *entry, delta int64) []*entry {
+	p.list.cost += delta
+	p.list.moveToFront(e)
+	return p.evict()
+}
+
+func (p *lru) remove(e *entry) {
+	p.list.remove(e)
+}
+
+func (p *lru) evict() []*entry {
+	var victims []*entry
+	for p.list.cost > p.capacity {
+		victim := p.list.back()
+		p.list.remove(victim)
+		victims = append(victims, victim)
+	}
+	return victims
+}
+
+// lfu 最不经常使用，访问次数相同时淘汰最早访问的数据
+type lfu struct {
+	capacity int64
+	cost     int64
+	tick     uint64
+	h        lfuHeap
+}
+
+func (p *lfu) add(e *entry) []*entry {
+	// 先淘汰再写入，避免新数据因为访问次数少被立即淘汰
+	var victims []*entry
+	for p.cost+e.cost > p.capacity && p.h.Len() > 0 {
+		victim := heap.Pop(&p.h).(*entry)
+		p.cost -= victim.cost
+		victims = append(victims, victim)
+	}
+
+	p.tick++
+	e.freq, e.tick = 1, p.tick
+	heap.Push(&p.h, e)
+	p.cost += e.cost
+	return victims
+}
+
+func (p *lfu) hit(e *entry) {
+	p.tick++
+	e.freq++
+	e.tick = p.tick
+	heap.Fix(&p.h, e.index)
+}
+
+func (p *lfu) update(e *entry, delta int64) []*entry {
+	p.cost += delta
+	p.hit(e)
+
+	var victims []*entry
+	for p.cost > p.capacity {
+		victim := heap.Pop(&p.h).(*entry)
+		p.cost -= victim.cost
+		victims = append(victims, victim)
+	}
+	return victims
+}
+
+func (p *lfu) remove(e *entry) {
+	heap.Remove(&p.h, e.index)
+	p.cost -= e.cost
+}
+
+type lfuHeap []*entry
+
+func (h lfuHeap) Len() int {
+	return len(h)
+}
+
+func (h lfuHeap) Less(i, j int) bool {
+	if h[i].freq != h[j].freq {
+		return h[i].freq < h[j].freq
+	}
+	return h[i].tick < h[j].tick
+}
+
+func (h lfuHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *lfuHeap) Push(x any) {
+	e := x.(*entry)
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+
+func (h *lfuHeap) Pop() any {
+	old := *h
+	e := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	e.index = -1
+	return e
+}
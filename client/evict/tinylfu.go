@@ -0,0 +1,222 @@
+package evict
+
+import "hash/maphash"
+
+const (
+	segWindow = iota
+	segProbation
+	segProtected
+)
+
+// tinyLFU W-TinyLFU：新数据先进入窗口LRU（1%），从窗口淘汰的数据与主缓存待淘汰数据比较访问频率，频率更高才能进入主缓存
+// 主缓存为分段LRU，试用区（20%）命中后晋升到保护区（80%）
+type tinyLFU struct {
+	windowCap    int64
+	mainCap      int64
+	protectedCap int64
+	window       sizedList
+	probation    sizedList
+	protected    sizedList
+	sketch       *cmSketch
+}
+
+func newTinyLFU(capacity int64) *tinyLFU {
+	windowCap := max(capacity/100, 1)
+	mainCap := capacity - windowCap
+
+	// 按平均每条数据256字节估算计数器数量
+	width := uint64(1024)
+	for width < uint64(capacity/256) && width < 1<<22 {
+		width <<= 1
+	}
+
+	return &tinyLFU{
+		windowCap:    windowCap,
+		mainCap:      mainCap,
+		protectedCap: mainCap * 8 / 10,
+		sketch:       newCMSketch(width),
+	}
+}
+
+func (p *tinyLFU) add(e *entry) []*entry {
+	p.sketch.increment(e.key)
+	p.window.pushFront(e, segWindow)
+	return p.balance()
+}
+
+func (p *tinyLFU) hit(e *entry) {
+	p.sketch.increment(e.key)
+	switch e.seg {
+	case segWindow:
+		p.window.moveToFront(e)
+	case segProbation:
+		p.probation.remove(e)
+		p.protected.pushFront(e, segProtected)
+		p.demote()
+	case segProtected:
+		p.protected.moveToFront(e)
+	}
+}
+
+func (p *tinyLFU) update(e *entry, delta int64) []*entry {
+	p.segment(e).cost += delta
+	p.hit(e)
+	p.demote()
+	// 数据变大导致主缓存超出容量时直接按LRU淘汰
+	return p.evictMain(p.balance())
+}
+
+func (p *tinyLFU) remove(e *entry) {
+	p.segment(e).remove(e)
+}
+
+func (p *tinyLFU) segment(e *entry) *sizedList {
+	switch e.seg {
+	case segProbation:
+		return &p.probation
+	case segProtected:
+		return &p.protected
+	}
+	return &p.window
+}
+
+// demote 保护区超出容量时把最久未访问的数据降级到试用区
+func (p *tinyLFU) demote() {
+	for p.protected.cost > p.protectedCap {
+		e := p.protected.back()
+		p.protected.remove(e)
+		p.probation.pushFront(e, segProbation)
+	}
+}
+
+// balance 窗口超出容量时，把窗口淘汰的数据作为候选者尝试进入主缓存
+func (p *tinyLFU) balance() []*entry {
+	var victims []*entry
+	for p.window.cost > p.windowCap {
+		candidate := p.window.back()
+		p.window.remove(candidate)
+		if candidate.cost > p.mainCap {
+			victims = append(victims, candidate)
+			continue
+		}
+
+		// 依次从试用区、保护区尾部找出需要腾出的数据，只要有一个访问频率不低于候选者就拒绝候选者
+		var (
+			freq      = p.sketch.estimate(candidate.key)
+			need      = p.probation.cost + p.protected.cost + candidate.cost - p.mainCap
+			pending   []*entry
+			admit     = true
+			protected bool
+			e         = p.probation.back()
+		)
+		for need > 0 {
+			if e == nil {
+				if protected {
+					break
+				}
+				protected, e = true, p.protected.back()
+				continue
+			}
+			if p.sketch.estimate(e.key) >= freq {
+				admit = false
+				break
+			}
+			pending = append(pending, e)
+			need -= e.cost
+			e = prevEntry(e)
+		}
+
+		if !admit || need > 0 {
+			victims = append(victims, candidate)
+			continue
+		}
+		for _, v := range pending {
+			p.segment(v).remove(v)
+		}
+		victims = append(victims, pending...)
+		p.probation.pushFront(candidate, segProbation)
+	}
+	return victims
+}
+
+// evictMain 按LRU淘汰主缓存直到满足容量
+func (p *tinyLFU) evictMain(victims []*entry) []*entry {
+	for p.probation.cost+p.protected.cost > p.mainCap {
+		e := p.probation.back()
+		if e == nil {
+			e = p.protected.back()
+		}
+		p.segment(e).remove(e)
+		victims = append(victims, e)
+	}
+	return victims
+}
+
+// prevEntry 同一个链表中更早访问的数据，已经是链表头部返回nil
+func prevEntry(e *entry) *entry {
+	if prev := e.el.Prev(); prev != nil {
+		return prev.Value.(*entry)
+	}
+	return nil
+}
+
+// cmSketch 4行的 Count-Min Sketch，用于估算访问频率，计数次数达到阈值后全部减半实现老化
+type cmSketch struct {
+	rows      [4][]uint8
+	mask      uint64
+	seed      maphash.Seed
+	additions uint64
+	resetAt   uint64
+}
+
+func newCMSketch(width uint64) *cmSketch {
+	s := &cmSketch{
+		mask:    width - 1,
+		seed:    maphash.MakeSeed(),
+		resetAt: width * 10,
+	}
+	for i := range s.rows {
+		s.rows[i] = make([]uint8, width)
+	}
+	return s
+}
+
+func (s *cmSketch) increment(key string) {
+	h := maphash.String(s.seed, key)
+	for i := range s.rows {
+		idx := s.index(h, i)
+		if s.rows[i][idx] < 15 {
+			s.rows[i][idx]++
+		}
+	}
+
+	s.additions++
+	if s.additions >= s.resetAt {
+		s.reset()
+	}
+}
+
+func (s *cmSketch) estimate(key string) uint8 {
+	var (
+		h   = maphash.String(s.seed, key)
+		out = uint8(15)
+	)
+	for i := range s.rows {
+		out = min(out, s.rows[i][s.index(h, i)])
+	}
+	return out
+}
+
+func (s *cmSketch) index(h uint64, i int) uint64 {
+	h1, h2 := h, h>>32|h<<32
+	return (h1 + uint64(i)*h2) & s.mask
+}
+
+func (s *cmSketch) reset() {
+	s.additions /= 2
+	for i := range s.rows {
+		for j := range s.rows[i] {
+			s.rows[i][j] >>= 1
+		}
+	}
+}
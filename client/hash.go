@@ -0,0 +1,79 @@
+package client
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"time"
+)
+
+// IHashAdaptor 支持按字段读写的适配器，一个key对应一组字段
+type IHashAdaptor interface {
+	IAdaptor
+	// HSet 写入每个key的部分字段，其它字段保留，每次写入把过期时间重新设置为expire
+	HSet(ctx context.Context, params map[string]map[string][]byte, expire time.Duration) error
+	// HGet 查询每个key的指定字段，fields 为空时查询全部字段，不存在的key和字段不返回
+	HGet(ctx context.Context, k []string, fields []string) (map[string]map[string][]byte, error)
+}
+
+// errBadFields 字段数据格式错误
+var errBadFields = errors.New("cache: malformed hash fields")
+
+// EncodeFields 把字段编码为一个值，供只能保存整个值的内存缓存使用
+// 格式：字段数，之后每个字段依次为 名字长度、名字、值长度、值，长度均为uvarint
+func EncodeFields(fields map[string][]byte) []byte {
+	size := binary.MaxVarintLen64
+	for k, v := range fields {
+		size += 2*binary.MaxVarintLen64 + len(k) + len(v)
+	}
+
+	b := make([]byte, 0, size)
+	b = binary.AppendUvarint(b, uint64(len(fields)))
+	for k, v := range fields {
+		b = binary.AppendUvarint(b, uint64(len(k)))
+		b = append(b, k...)
+		b = binary.AppendUvarint(b, uint64(len(v)))
+		b = append(b, v...)
+	}
+	return b
+}
+
+// DecodeFields 解码 EncodeFields 编码的字段
+func DecodeFields(b []byte) (map[string][]byte, error) {
+	n, b, err := readUvarint(b)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make(map[string][]byte, min(n, uint64(len(b))))
+	for i := uint64(0); i < n; i++ {
+		var k, v []byte
+		if k, b, err = readBytes(b); err != nil {
+			return nil, err
+		}
+		if v, b, err = readBytes(b); err != nil {
+			return nil, err
+		}
+		fields[string(k)] = v
+	}
+	return fields, nil
+}
+
+func readUvarint(b []byte) (uint64, []byte, error) {
+	n, size := binary.Uvarint(b)
+	if size <= 0 {
+		return 0, nil, errBadFields
+	}
+	return n, b[size:], nil
+}
+
+func readBytes(b []byte) ([]byte, []byte, error) {
+	n, b, err := readUvarint(b)
+	if err != nil {
+		return nil, nil, err
+	}
+	if n > uint64(len(b)) {
+		return nil, nil, errBadFields
+	}
+	return b[:n:n], b[n:], nil
+}
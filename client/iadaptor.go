@@ -2,12 +2,136 @@ package client
 
 import (
 	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"time"
 )
 
+var (
+	// ErrClosed 适配器已关闭后继续调用返回该错误
+	ErrClosed = errors.New("cache: closed")
+	// ErrCircuitOpen 熔断器打开时返回该错误，MultiCache 查询时跳过当前这一级缓存
+	// 写入、删除、修改过期时间时作为该级缓存的 *TierError 按出错策略处理，避免熔断恢复后读到旧数据
+	ErrCircuitOpen = errors.New("cache: circuit breaker is open")
+	// ErrNotSupported 被包装的适配器不支持该操作
+	ErrNotSupported = errors.New("cache: operation not supported")
+)
+
+// NoExpire 表示数据没有设置过期时间
+const NoExpire time.Duration = -1
+
+// Item 数据及剩余过期时间
+type Item struct {
+	Value []byte
+	TTL   time.Duration // 剩余过期时间，不过期为 NoExpire
+}
+
+// Swap CompareAndSwap 的参数
+type Swap struct {
+	Value   []byte // 新数据
+	Version string // 期望的当前版本号，为空表示期望key不存在
+}
+
+// Version 计算数据的版本号：数据的sha1十六进制摘要
+// 版本号由数据本身得出，不需要额外存储，和 redis 脚本中的 redis.sha1hex 结果一致
+// 相同的数据版本号相同，数据改回原来的值后旧版本号重新有效（ABA），CompareAndSwap 不能发现中间的写入
+func Version(value []byte) string {
+	sum := sha1.Sum(value)
+	return hex.EncodeToString(sum[:])
+}
+
 // IAdaptor 接口转换器
 type IAdaptor interface {
 	Set(ctx context.Context, params map[string][]byte, expire time.Duration) error
 	Get(ctx context.Context, k []string) (map[string][]byte, error)
 	Del(ctx context.Context, k []string) error
+	Close() error // 释放连接、内存等资源，关闭后再调用其它方法返回 ErrClosed
+	// GetWithTTL 查询数据及剩余过期时间，不存在的key不返回
+	GetWithTTL(ctx context.Context, k []string) (map[string]Item, error)
+	// TTL 查询剩余过期时间，不存在的key不返回，不过期为 NoExpire
+	TTL(ctx context.Context, k []string) (map[string]time.Duration, error)
+	// Expire 重新设置过期时间，不重写数据，expire 小于等于0表示不过期，不存在的key忽略
+	Expire(ctx context.Context, k []string, expire time.Duration) error
+	// SetIfAbsent key不存在时才写入，返回每个key是否写入
+	SetIfAbsent(ctx context.Context, params map[string][]byte, expire time.Duration) (map[string]bool, error)
+	// SetIfPresent key存在时才写入，返回每个key是否写入
+	SetIfPresent(ctx context.Context, params map[string][]byte, expire time.Duration) (map[string]bool, error)
+	// CompareAndSwap 当前数据的版本号等于 Swap.Version 时才写入，返回每个key是否写入，比较和写入是原子的
+	CompareAndSwap(ctx context.Context, params map[string]Swap, expire time.Duration) (map[string]bool, error)
+	// Scan 遍历匹配 glob 模式的key，语义见 MatchPattern
+	Scan(ctx context.Context, pattern string) KeyIterator
+	// DelPrefix 分批删除以prefix开头的全部key，返回删除的数量，每一批使用 BatchContext 派生的ctx
+	DelPrefix(ctx context.Context, prefix string) (int64, error)
+}
+
+// ICounterAdaptor 支持原子计数的适配器，计数以十进制字符串保存，Get 到的数据可以直接按 int64 反序列化
+type ICounterAdaptor interface {
+	IAdaptor
+	// IncrBy 原子地给每个key加上delta，key不存在时从0开始并设置过期时间，已存在的key不修改过期时间，返回加上后的值
+	// 部分key出错时返回 *IncrError，说明哪些key已经加上、哪些确认没有加上
+	IncrBy(ctx context.Context, params map[string]int64, expire time.Duration) (map[string]int64, error)
+}
+
+// IncrError IncrBy 部分失败时返回，调用方只重试 NotApplied 中的key，避免重复计数
+// Applied、NotApplied 都不包含的key无法确定是否已经加上，例如命令已发出但读取响应超时
+type IncrError struct {
+	Applied    map[string]int64 // 已经加上的key及加上后的值
+	NotApplied []string         // 确认没有加上的key
+	Err        error
+}
+
+func (e *IncrError) Error() string {
+	return fmt.Sprintf("cache: incr failed, %d applied, %d not applied: %v", len(e.Applied), len(e.NotApplied), e.Err)
+}
+
+func (e *IncrError) Unwrap() error {
+	return e.Err
+}
+
+// Wrapper 包装其它适配器的适配器（如熔断、重试），包装器总是实现全部可选接口，不支持时返回 ErrNotSupported
+type Wrapper interface {
+	Unwrap() IAdaptor
+}
+
+// Supports 判断适配器是否真正支持可选接口T（ICounterAdaptor、IHashAdaptor、ITagAdaptor），包装器按被包装的适配器判断
+func Supports[T any](a IAdaptor) bool {
+	for a != nil {
+		if _, ok := a.(T); !ok {
+			return false
+		}
+		w, ok := a.(Wrapper)
+		if !ok {
+			return true
+		}
+		a = w.Unwrap()
+	}
+	return false
+}
+
+// ITypedAdaptor 直接存储对象的适配器，不需要序列化，用于进程内缓存
+// Get 返回的对象和 Set 传入的是同一个值，指针类型的对象不要在外部修改
+type ITypedAdaptor[T any] interface {
+	Set(ctx context.Context, params map[string]T, expire time.Duration) error
+	Get(ctx context.Context, k []string) (map[string]T, error)
+	Del(ctx context.Context, k []string) error
+	Close() error // 释放内存等资源，关闭后再调用其它方法返回 ErrClosed
+	TTL(ctx context.Context, k []string) (map[string]time.Duration, error)
+	Expire(ctx context.Context, k []string, expire time.Duration) error
+	// Update 对每个key原子地执行 f，f 返回 ok 为true时写入新值，返回每个key是否写入
+	Update(ctx context.Context, k []string, f func(key string, old T, found bool) (T, bool), expire time.Duration) (map[string]bool, error)
+	Scan(ctx context.Context, pattern string) KeyIterator
+	DelPrefix(ctx context.Context, prefix string) (int64, error)
+}
+
+// NotAppliedError 逐个key计数的适配器出错时使用：applied 之外的key都没有加上
+func NotAppliedError(applied map[string]int64, params map[string]int64, err error) *IncrError {
+	e := &IncrError{Applied: applied, Err: err}
+	for k := range params {
+		if _, ok := applied[k]; !ok {
+			e.NotApplied = append(e.NotApplied, k)
+		}
+	}
+	return e
 }
@@ -0,0 +1,164 @@
+package client
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RemovalReason 数据离开缓存的原因
+type RemovalReason int
+
+const (
+	ReasonEvicted  RemovalReason = iota // 容量不足被淘汰
+	ReasonExpired                       // 过期
+	ReasonDeleted                       // 调用 Del 删除
+	ReasonReplaced                      // 被新写入的值覆盖
+)
+
+func (r RemovalReason) String() string {
+	switch r {
+	case ReasonEvicted:
+		return "evicted"
+	case ReasonExpired:
+		return "expired"
+	case ReasonDeleted:
+		return "deleted"
+	case ReasonReplaced:
+		return "replaced"
+	}
+	return fmt.Sprintf("unknown(%d)", int(r))
+}
+
+// RemovalEvent 数据离开缓存的事件
+type RemovalEvent[V any] struct {
+	Key    string
+	Value  V
+	Reason RemovalReason
+}
+
+// RemovalListeners 数据离开缓存的回调，按原因区分，未设置的忽略
+// 只有 local、evict 适配器支持；mem 适配器基于 freecache，freecache 不提供淘汰和过期通知，不支持回调
+type RemovalListeners[V any] struct {
+	OnEvict  func(e RemovalEvent[V]) // ReasonEvicted
+	OnExpire func(e RemovalEvent[V]) // ReasonExpired
+	OnDelete func(e RemovalEvent[V]) // ReasonDeleted、ReasonReplaced
+}
+
+func (l RemovalListeners[V]) empty() bool {
+	return l.OnEvict == nil && l.OnExpire == nil && l.OnDelete == nil
+}
+
+func (l RemovalListeners[V]) get(r RemovalReason) func(e RemovalEvent[V]) {
+	switch r {
+	case ReasonEvicted:
+		return l.OnEvict
+	case ReasonExpired:
+		return l.OnExpire
+	}
+	return l.OnDelete
+}
+
+// RemovalNotifier 在单独的协程中异步投递删除事件
+// 队列满时最多等待 wait，仍然满则丢弃事件并计数（见 Dropped）；wait 为0时直接丢弃，慢的回调不会阻塞缓存的读写
+// 适配器在持有分片锁时投递事件，等待期间同一分片的读写也会等待
+type RemovalNotifier[V any] struct {
+	listeners RemovalListeners[V]
+	ch        chan RemovalEvent[V]
+	wait      time.Duration
+	dropped   atomic.Uint64
+
+	sm     sync.RWMutex
+	closed bool
+}
+
+// NewRemovalNotifier 创建事件投递器，没有设置任何回调时返回nil，nil可以正常调用
+func NewRemovalNotifier[V any](listeners RemovalListeners[V], bufferSize int, wait time.Duration) *RemovalNotifier[V] {
+	if listeners.empty() {
+		return nil
+	}
+	if bufferSize <= 0 {
+		bufferSize = 1024
+	}
+
+	n := &RemovalNotifier[V]{
+		listeners: listeners,
+		ch:        make(chan RemovalEvent[V], bufferSize),
+		wait:      wait,
+	}
+	go n.run()
+
+	return n
+}
+
+// Notify 投递事件，队列满时最多等待 wait
+func (n *RemovalNotifier[V]) Notify(key string, value V, reason RemovalReason) {
+	if n == nil || n.listeners.get(reason) == nil {
+		return
+	}
+
+	n.sm.RLock()
+	defer n.sm.RUnlock()
+
+	if n.closed {
+		return
+	}
+	e := RemovalEvent[V]{Key: key, Value: value, Reason: reason}
+	select {
+	case n.ch <- e:
+		return
+	default:
+	}
+	if n.wait > 0 {
+		timer := time.NewTimer(n.wait)
+		defer timer.Stop()
+		select {
+		case n.ch <- e:
+			return
+		case <-timer.C:
+		}
+	}
+	n.dropped.Add(1)
+}
+
+// Dropped 因为队列满（等待超时）被丢弃的事件数
+func (n *RemovalNotifier[V]) Dropped() uint64 {
+	if n == nil {
+		return 0
+	}
+	return n.dropped.Load()
+}
+
+// Close 停止接收事件，已在队列中的事件投递完后协程退出
+func (n *RemovalNotifier[V]) Close() {
+	if n == nil {
+		return
+	}
+
+	n.sm.Lock()
+	defer n.sm.Unlock()
+
+	if n.closed {
+		return
+	}
+	n.closed = true
+	close(n.ch)
+}
+
+func (n *RemovalNotifier[V]) run() {
+	for e := range n.ch {
+		n.call(e)
+	}
+}
+
+// call 调用回调，回调panic不影响后续事件
+func (n *RemovalNotifier[V]) call(e RemovalEvent[V]) {
+	defer func() {
+		if err := recover(); err != nil {
+			fmt.Println(err)
+		}
+	}()
+
+	n.listeners.get(e.Reason)(e)
+}
@@ -0,0 +1,75 @@
+package local
+
+import (
+	"fmt"
+	"github.com/PycMono/go-cache/client"
+	"time"
+)
+
+// Config 配置文件
+type Config struct {
+	shards          int           // 分片数（默认64），向上取整为2的幂，分片越多锁竞争越小
+	maxEntries      int           // 最大缓存条数（默认100000），平均分配到每个分片，超过后按LRU淘汰
+	cleanupInterval time.Duration // 过期数据清理间隔（默认1分钟），过期数据在读取时也会删除
+	listeners       any           // client.RemovalListeners[T]，通过 WithRemovalListeners 设置
+	listenerBuffer  int           // 回调事件队列长度（默认1024），队列满时等待 listenerWait 后丢弃事件
+	listenerWait    time.Duration // 回调事件队列满时的最长等待时间（默认0，直接丢弃），等待期间同一分片的读写也会等待
+}
+
+func (c Config) WithShards(shards int) Config {
+	c.shards = shards
+	return c
+}
+
+func (c Config) WithMaxEntries(maxEntries int) Config {
+	c.maxEntries = maxEntries
+	return c
+}
+
+func (c Config) WithCleanupInterval(cleanupInterval time.Duration) Config {
+	c.cleanupInterval = cleanupInterval
+	return c
+}
+
+func (c Config) WithListenerBuffer(listenerBuffer int) Config {
+	c.listenerBuffer = listenerBuffer
+	return c
+}
+
+func (c Config) WithListenerWait(listenerWait time.Duration) Config {
+	c.listenerWait = listenerWait
+	return c
+}
+
+// WithRemovalListeners 设置数据离开缓存的回调，异步执行，T 必须和 NewLocalAdaptor 的类型一致
+// Go 不支持泛型方法，所以这里是函数而不是 Config 的方法
+func WithRemovalListeners[T any](c Config, listeners client.RemovalListeners[T]) Config {
+	c.listeners = listeners
+	return c
+}
+
+func (c Config) build() (Config, error) {
+	if c.shards == 0 {
+		c.shards = 64
+	}
+	if c.maxEntries == 0 {
+		c.maxEntries = 100000
+	}
+	if c.cleanupInterval == 0 {
+		c.cleanupInterval = time.Minute
+	}
+
+	if c.shards < 0 || c.maxEntries < 0 || c.cleanupInterval < 0 {
+		return c, fmt.Errorf("shards、maxEntries、cleanupInterval不能为负数")
+	}
+	shards := 1
+	for shards < c.shards {
+		shards <<= 1
+	}
+	c.shards = shards
+	if c.maxEntries < c.shards {
+		return c, fmt.Errorf("maxEntries不能小于分片数%d", c.shards)
+	}
+
+	return c, nil
+}
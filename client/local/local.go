@@ -0,0 +1,424 @@
+package local
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"github.com/PycMono/go-cache/client"
+	"hash/maphash"
+	"sync"
+	"time"
+)
+
+// Cache 进程内对象缓存，分片map + LRU淘汰 + 过期时间，直接保存对象不做序列化
+type Cache[T any] struct {
+	shards []*shard[T]
+	mask   uint64
+	seed   maphash.Seed
+
+	notifier *client.RemovalNotifier[T]
+
+	sm     sync.RWMutex
+	closed bool
+	done   chan struct{} // 关闭时通知清理协程退出
+}
+
+type shard[T any] struct {
+	sm         sync.Mutex
+	items      map[string]*list.Element
+	ll         *list.List // 链表头部为最近访问
+	maxEntries int
+}
+
+type entry[T any] struct {
+	key      string
+	val      T
+	expireAt int64 // 过期时间（UnixNano），0表示不过期
+}
+
+func NewLocalAdaptor[T any](conf *Config) (client.ITypedAdaptor[T], error) {
+	c, err := conf.build()
+	if err != nil {
+		return nil, err
+	}
+	var listeners client.RemovalListeners[T]
+	if c.listeners != nil {
+		var ok bool
+		if listeners, ok = c.listeners.(client.RemovalListeners[T]); !ok {
+			return nil, fmt.Errorf("listeners类型%T和缓存类型不一致", c.listeners)
+		}
+	}
+
+	cache := &Cache[T]{
+		shards: make([]*shard[T], c.shards),
+		mask:   uint64(c.shards - 1),
+		seed:   maphash.MakeSeed(),
+		done:   make(chan struct{}),
+
+		notifier: client.NewRemovalNotifier(listeners, c.listenerBuffer, c.listenerWait),
+	}
+	for i := range cache.shards {
+		cache.shards[i] = &shard[T]{
+			items:      make(map[string]*list.Element),
+			ll:         list.New(),
+			maxEntries: c.maxEntries / c.shards,
+		}
+	}
+	go cache.cleanup(c.cleanupInterval)
+
+	return cache, nil
+}
+
+func (c *Cache[T]) Set(ctx context.Context, params map[string]T, expire time.Duration) error {
+	if c.isClosed() {
+		return client.ErrClosed
+	}
+
+	var expireAt int64
+	if expire > 0 {
+		expireAt = time.Now().Add(expire).UnixNano()
+	}
+	for k, v := range params {
+		c.getShard(k).set(c, k, v, expireAt)
+	}
+
+	return nil
+}
+
+func (c *Cache[T]) Update(ctx context.Context, k []string, f func(key string, old T, found bool) (T, bool), expire time.Duration) (map[string]bool, error) {
+	if c.isClosed() {
+		return nil, client.ErrClosed
+	}
+
+	var (
+		now      = time.Now().UnixNano()
+		expireAt int64
+		out      = make(map[string]bool, len(k))
+	)
+	if expire > 0 {
+		expireAt = now + int64(expire)
+	}
+	for _, key := range k {
+		out[key] = c.getShard(key).update(c, key, f, expireAt, now)
+	}
+
+	return out, nil
+}
+
+// Scan 每次 Next 返回一个分片中匹配的key
+func (c *Cache[T]) Scan(ctx context.Context, pattern string) client.KeyIterator {
+	if c.isClosed() {
+		return client.ErrIterator(client.ErrClosed)
+	}
+
+	i := 0
+	return client.NewKeyIterator(func(ctx context.Context) ([]string, bool, error) {
+		if c.isClosed() {
+			return nil, true, client.ErrClosed
+		}
+		keys := c.shards[i].keys(pattern, time.Now().UnixNano())
+		i++
+		return keys, i == len(c.shards), nil
+	})
+}
+
+func (c *Cache[T]) DelPrefix(ctx context.Context, prefix string) (int64, error) {
+	var (
+		n  int64
+		it = c.Scan(ctx, client.EscapePattern(prefix)+"*")
+	)
+	for it.Next(ctx) {
+		keys := it.Keys()
+		if err := c.Del(ctx, keys); err != nil {
+			return n, err
+		}
+		n += int64(len(keys))
+	}
+	return n, it.Err()
+}
+
+func (c *Cache[T]) Get(ctx context.Context, k []string) (map[string]T, error) {
+	if c.isClosed() {
+		return nil, client.ErrClosed
+	}
+
+	var (
+		now = time.Now().UnixNano()
+		out = make(map[string]T)
+	)
+	for _, key := range k {
+		if val, ok := c.getShard(key).get(c, key, now); ok {
+			out[key] = val
+		}
+	}
+
+	return out, nil
+}
+
+func (c *Cache[T]) Del(ctx context.Context, k []string) error {
+	if c.isClosed() {
+		return client.ErrClosed
+	}
+
+	for _, key := range k {
+		c.getShard(key).del(c, key)
+	}
+
+	return nil
+}
+
+func (c *Cache[T]) TTL(ctx context.Context, k []string) (map[string]time.Duration, error) {
+	if c.isClosed() {
+		return nil, client.ErrClosed
+	}
+
+	var (
+		now = time.Now().UnixNano()
+		out = make(map[string]time.Duration)
+	)
+	for _, key := range k {
+		expireAt, ok := c.getShard(key).expireAt(key, now)
+		if !ok {
+			continue
+		}
+		if expireAt == 0 {
+			out[key] = client.NoExpire
+			continue
+		}
+		out[key] = time.Duration(expireAt - now)
+	}
+
+	return out, nil
+}
+
+// Expire 只修改过期时间，不算作访问
+func (c *Cache[T]) Expire(ctx context.Context, k []string, expire time.Duration) error {
+	if c.isClosed() {
+		return client.ErrClosed
+	}
+
+	var (
+		now      = time.Now().UnixNano()
+		expireAt int64
+	)
+	if expire > 0 {
+		expireAt = now + int64(expire)
+	}
+	for _, key := range k {
+		c.getShard(key).expire(key, expireAt, now)
+	}
+
+	return nil
+}
+
+// Close 停止清理协程并清空缓存，重复调用返回 client.ErrClosed
+func (c *Cache[T]) Close() error {
+	c.sm.Lock()
+	defer c.sm.Unlock()
+
+	if c.closed {
+		return client.ErrClosed
+	}
+	c.closed = true
+	close(c.done)
+	for _, v := range c.shards {
+		v.clear()
+	}
+	c.notifier.Close() // Close 清空的数据不通知
+
+	return nil
+}
+
+// Dropped 回调事件队列满被丢弃的事件数
+func (c *Cache[T]) Dropped() uint64 {
+	return c.notifier.Dropped()
+}
+
+// Len 当前缓存条数（包括已过期未清理的数据）
+func (c *Cache[T]) Len() int {
+	var n int
+	for _, v := range c.shards {
+		v.sm.Lock()
+		n += v.ll.Len()
+		v.sm.Unlock()
+	}
+	return n
+}
+
+func (c *Cache[T]) isClosed() bool {
+	c.sm.RLock()
+	defer c.sm.RUnlock()
+
+	return c.closed
+}
+
+func (c *Cache[T]) getShard(k string) *shard[T] {
+	return c.shards[maphash.String(c.seed, k)&c.mask]
+}
+
+// cleanup 定时清理过期数据，Close 后退出
+func (c *Cache[T]) cleanup(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+		}
+
+		now := time.Now().UnixNano()
+		for _, v := range c.shards {
+			v.removeExpired(c, now)
+		}
+	}
+}
+
+func (s *shard[T]) set(c *Cache[T], k string, v T, expireAt int64) {
+	s.sm.Lock()
+	defer s.sm.Unlock()
+
+	s.setLocked(c, k, v, expireAt)
+}
+
+// update 在分片锁内执行 f，已过期的数据按不存在处理
+func (s *shard[T]) update(c *Cache[T], k string, f func(key string, old T, found bool) (T, bool), expireAt, now int64) bool {
+	s.sm.Lock()
+	defer s.sm.Unlock()
+
+	var (
+		old   T
+		found bool
+	)
+	if el, ok := s.items[k]; ok {
+		if e := el.Value.(*entry[T]); e.expireAt == 0 || e.expireAt > now {
+			old, found = e.val, true
+		}
+	}
+	v, ok := f(k, old, found)
+	if !ok {
+		return false
+	}
+
+	s.setLocked(c, k, v, expireAt)
+	return true
+}
+
+// setLocked 写入数据，调用方需要持有分片锁
+func (s *shard[T]) setLocked(c *Cache[T], k string, v T, expireAt int64) {
+	if el, ok := s.items[k]; ok {
+		e := el.Value.(*entry[T])
+		c.notifier.Notify(k, e.val, client.ReasonReplaced)
+		e.val, e.expireAt = v, expireAt
+		s.ll.MoveToFront(el)
+		return
+	}
+
+	s.items[k] = s.ll.PushFront(&entry[T]{key: k, val: v, expireAt: expireAt})
+	for s.ll.Len() > s.maxEntries {
+		e := s.remove(s.ll.Back()) // 淘汰最久未访问的数据
+		c.notifier.Notify(e.key, e.val, client.ReasonEvicted)
+	}
+}
+
+func (s *shard[T]) get(c *Cache[T], k string, now int64) (T, bool) {
+	s.sm.Lock()
+	defer s.sm.Unlock()
+
+	var zero T
+	el, ok := s.items[k]
+	if !ok {
+		return zero, false
+	}
+	e := el.Value.(*entry[T])
+	if e.expireAt > 0 && e.expireAt <= now {
+		s.remove(el)
+		c.notifier.Notify(e.key, e.val, client.ReasonExpired)
+		return zero, false
+	}
+
+	s.ll.MoveToFront(el)
+	return e.val, true
+}
+
+func (s *shard[T]) expireAt(k string, now int64) (int64, bool) {
+	s.sm.Lock()
+	defer s.sm.Unlock()
+
+	el, ok := s.items[k]
+	if !ok {
+		return 0, false
+	}
+	e := el.Value.(*entry[T])
+	if e.expireAt > 0 && e.expireAt <= now {
+		return 0, false
+	}
+	return e.expireAt, true
+}
+
+func (s *shard[T]) expire(k string, expireAt, now int64) {
+	s.sm.Lock()
+	defer s.sm.Unlock()
+
+	el, ok := s.items[k]
+	if !ok {
+		return
+	}
+	if e := el.Value.(*entry[T]); e.expireAt == 0 || e.expireAt > now {
+		e.expireAt = expireAt
+	}
+}
+
+func (s *shard[T]) del(c *Cache[T], k string) {
+	s.sm.Lock()
+	defer s.sm.Unlock()
+
+	if el, ok := s.items[k]; ok {
+		e := s.remove(el)
+		c.notifier.Notify(e.key, e.val, client.ReasonDeleted)
+	}
+}
+
+// keys 返回匹配pattern的未过期key
+func (s *shard[T]) keys(pattern string, now int64) []string {
+	s.sm.Lock()
+	defer s.sm.Unlock()
+
+	var out []string
+	for k, el := range s.items {
+		if e := el.Value.(*entry[T]); (e.expireAt == 0 || e.expireAt > now) && client.MatchPattern(pattern, k) {
+			out = append(out, k)
+		}
+	}
+	return out
+}
+
+func (s *shard[T]) removeExpired(c *Cache[T], now int64) {
+	s.sm.Lock()
+	defer s.sm.Unlock()
+
+	for el := s.ll.Back(); el != nil; {
+		prev := el.Prev()
+		if e := el.Value.(*entry[T]); e.expireAt > 0 && e.expireAt <= now {
+			s.remove(el)
+			c.notifier.Notify(e.key, e.val, client.ReasonExpired)
+		}
+		el = prev
+	}
+}
+
+func (s *shard[T]) clear() {
+	s.sm.Lock()
+	defer s.sm.Unlock()
+
+	s.items = make(map[string]*list.Element)
+	s.ll.Init()
+}
+
+// remove 删除链表节点，返回被删除的数据，调用方需持有锁
+func (s *shard[T]) remove(el *list.Element) *entry[T] {
+	e := el.Value.(*entry[T])
+	s.ll.Remove(el)
+	delete(s.items, e.key)
+	return e
+}
@@ -0,0 +1,123 @@
+package local
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/PycMono/go-cache/client"
+	"testing"
+	"time"
+)
+
+func TestLocal(t *testing.T) {
+	type person struct {
+		Name string
+	}
+	conf := Config{}.WithShards(1).WithMaxEntries(2)
+	adaptor, err := NewLocalAdaptor[*person](&conf)
+	if err != nil {
+		panic(err)
+	}
+
+	p := &person{Name: "111"}
+	_ = adaptor.Set(context.TODO(), map[string]*person{"1": p}, time.Hour)
+	_ = adaptor.Set(context.TODO(), map[string]*person{"2": {Name: "222"}}, 20*time.Millisecond)
+	kvMap, _ := adaptor.Get(context.TODO(), []string{"1", "2"})
+	if kvMap["1"] != p || len(kvMap) != 2 {
+		t.Fatalf("kvMap = %v, want same object", kvMap)
+	}
+
+	// 过期
+	time.Sleep(30 * time.Millisecond)
+	kvMap, _ = adaptor.Get(context.TODO(), []string{"2"})
+	if len(kvMap) != 0 {
+		t.Fatalf("kvMap = %v, want expired", kvMap)
+	}
+
+	// 超过容量淘汰最久未访问的数据
+	_ = adaptor.Set(context.TODO(), map[string]*person{"3": {Name: "333"}}, 0)
+	_, _ = adaptor.Get(context.TODO(), []string{"1"})
+	_ = adaptor.Set(context.TODO(), map[string]*person{"4": {Name: "444"}}, 0)
+	kvMap, _ = adaptor.Get(context.TODO(), []string{"1", "3", "4"})
+	if _, ok := kvMap["3"]; ok || len(kvMap) != 2 {
+		t.Fatalf("kvMap = %v, want 3 evicted", kvMap)
+	}
+
+	if err = adaptor.Close(); err != nil {
+		panic(err)
+	}
+	if _, err = adaptor.Get(context.TODO(), []string{"1"}); !errors.Is(err, client.ErrClosed) {
+		t.Fatalf("err = %v, want ErrClosed", err)
+	}
+}
+
+func TestRemovalListeners(t *testing.T) {
+	events := make(chan client.RemovalEvent[int], 10)
+	listener := func(e client.RemovalEvent[int]) {
+		events <- e
+	}
+	conf := WithRemovalListeners(Config{}.WithShards(1).WithMaxEntries(1), client.RemovalListeners[int]{
+		OnEvict:  listener,
+		OnExpire: listener,
+		OnDelete: listener,
+	})
+	adaptor, err := NewLocalAdaptor[int](&conf)
+	if err != nil {
+		panic(err)
+	}
+	defer adaptor.Close()
+
+	_ = adaptor.Set(context.TODO(), map[string]int{"1": 1}, 0)
+	_ = adaptor.Set(context.TODO(), map[string]int{"1": 2}, 0)                   // 覆盖
+	_ = adaptor.Set(context.TODO(), map[string]int{"2": 3}, 10*time.Millisecond) // 淘汰1
+	time.Sleep(20 * time.Millisecond)
+	_, _ = adaptor.Get(context.TODO(), []string{"2"}) // 过期
+	_ = adaptor.Set(context.TODO(), map[string]int{"3": 4}, 0)
+	_ = adaptor.Del(context.TODO(), []string{"3"}) // 删除
+
+	want := []client.RemovalEvent[int]{
+		{Key: "1", Value: 1, Reason: client.ReasonReplaced},
+		{Key: "1", Value: 2, Reason: client.ReasonEvicted},
+		{Key: "2", Value: 3, Reason: client.ReasonExpired},
+		{Key: "3", Value: 4, Reason: client.ReasonDeleted},
+	}
+	for _, v := range want {
+		select {
+		case e := <-events:
+			if e != v {
+				t.Fatalf("event = %+v, want %+v", e, v)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("event %+v not received", v)
+		}
+	}
+
+	// 类型不一致
+	conf = WithRemovalListeners(Config{}, client.RemovalListeners[string]{OnEvict: func(e client.RemovalEvent[string]) {}})
+	if _, err = NewLocalAdaptor[int](&conf); err == nil {
+		t.Fatalf("want error for mismatched listener type")
+	}
+}
+
+func TestListenerWait(t *testing.T) {
+	slow := func(e client.RemovalEvent[int]) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	for _, wait := range []time.Duration{0, time.Second} {
+		conf := WithRemovalListeners(Config{}.WithShards(1).WithMaxEntries(1).WithListenerBuffer(1).WithListenerWait(wait),
+			client.RemovalListeners[int]{OnEvict: slow})
+		adaptor, err := NewLocalAdaptor[int](&conf)
+		if err != nil {
+			panic(err)
+		}
+		for i := 0; i < 10; i++ {
+			_ = adaptor.Set(context.TODO(), map[string]int{fmt.Sprint(i): i}, 0)
+		}
+		// 不等待时队列满直接丢弃，等待足够长时不丢弃
+		dropped := adaptor.(*Cache[int]).Dropped()
+		if (wait == 0) != (dropped > 0) {
+			t.Fatalf("wait = %v, dropped = %d", wait, dropped)
+		}
+		_ = adaptor.Close()
+	}
+}
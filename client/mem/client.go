@@ -1,21 +1,70 @@
 package mem
 
 import (
+	"fmt"
+	"github.com/PycMono/go-cache/client"
 	"github.com/coocood/freecache"
 	"runtime/debug"
+	"sync"
 )
 
 type Client struct {
 	conf        *Config
 	cacheClient *freecache.Cache
+	sm          sync.RWMutex
 }
 
-func NewMemCache(conf *Config) *Client {
-	cacheClient := freecache.NewCache(conf.cacheSize)
-	debug.SetGCPercent(conf.gcPercent)
+// NewMemCache 创建内存缓存，不会修改进程的GC配置，同一进程可以创建多个
+func NewMemCache(conf *Config) (*Client, error) {
+	c, err := conf.build()
+	if err != nil {
+		return nil, err
+	}
+
+	cli := &Client{
+		conf:        &c,
+		cacheClient: freecache.NewCache(c.cacheSize),
+	}
+	if c.snapshot != "" {
+		// 快照损坏不影响启动，从空缓存开始
+		if err = cli.restoreFile(c.snapshot); err != nil {
+			fmt.Println(err)
+		}
+	}
+	return cli, nil
+}
+
+// TuneGC 设置整个进程的垃圾收集目标百分比，返回之前的值
+// freecache 的数据不受GC扫描影响，缓存较大时可以适当调低以减少内存峰值，影响进程内所有对象，需由应用显式调用
+func TuneGC(gcPercent int) int {
+	return debug.SetGCPercent(gcPercent)
+}
+
+// getCacheClient 获取freecache实例，已关闭返回 client.ErrClosed
+func (c *Client) getCacheClient() (*freecache.Cache, error) {
+	c.sm.RLock()
+	defer c.sm.RUnlock()
+
+	if c.cacheClient == nil {
+		return nil, client.ErrClosed
+	}
+	return c.cacheClient, nil
+}
+
+// Close 设置了快照时先写入快照，再清空缓存并释放freecache占用的内存，重复调用返回 client.ErrClosed
+func (c *Client) Close() error {
+	var err error
+	if c.conf.snapshot != "" {
+		err = c.dumpFile(c.conf.snapshot)
+	}
+
+	c.sm.Lock()
+	defer c.sm.Unlock()
 
-	return &Client{
-		conf:        conf,
-		cacheClient: cacheClient,
+	if c.cacheClient == nil {
+		return client.ErrClosed
 	}
+	c.cacheClient.Clear()
+	c.cacheClient = nil // 交给gc回收
+	return err
 }
@@ -1,9 +1,17 @@
 package mem
 
+import "fmt"
+
+const (
+	defaultCacheSize = 64 * 1024 * 1024 // 默认缓存大小64MB
+	minCacheSize     = 512 * 1024       // freecache 最小缓存大小512KB
+)
+
 // Config 配置文件
 type Config struct {
-	cacheSize int // 缓存块大小
-	gcPercent int // 垃圾收集目标百分比
+	cacheSize int    // 缓存块大小（字节），默认64MB，最小512KB
+	gcPercent int    // 垃圾收集目标百分比
+	snapshot  string // 快照文件路径，创建时从快照恢复，关闭时写入快照，为空不使用
 }
 
 func (c Config) WithCacheSize(cacheSize int) Config {
@@ -11,7 +19,27 @@ func (c Config) WithCacheSize(cacheSize int) Config {
 	return c
 }
 
+// WithGCPercent 设置垃圾收集目标百分比
+//
+// Deprecated: GC 是进程级别的配置，NewMemCache 不再修改，需要调整时显式调用 TuneGC
 func (c Config) WithGCPercent(gcPercent int) Config {
 	c.gcPercent = gcPercent
 	return c
 }
+
+// WithSnapshot 设置快照文件，NewMemCache 时恢复未过期的数据，Close 时把数据写入快照
+func (c Config) WithSnapshot(path string) Config {
+	c.snapshot = path
+	return c
+}
+
+func (c Config) build() (Config, error) {
+	if c.cacheSize == 0 {
+		c.cacheSize = defaultCacheSize
+	}
+	if c.cacheSize < minCacheSize {
+		return c, fmt.Errorf("cacheSize不能小于%d", minCacheSize)
+	}
+
+	return c, nil
+}
@@ -5,20 +5,34 @@ import (
 	"errors"
 	"github.com/PycMono/go-cache/client"
 	"github.com/coocood/freecache"
+	"hash/fnv"
+	"strconv"
+	"sync"
 	"time"
 )
 
+// Cache 基于 freecache 的适配器，freecache 不提供淘汰和过期通知，不支持 client.RemovalListeners
 type Cache struct {
 	client *Client
+	locks  [64]sync.Mutex // 计数使用的分段锁，freecache.Update 不能保留原来的过期时间
+	tags   client.TagIndex
 }
 
+// scanBatch Scan 每批返回的key数量
+const scanBatch = 1000
+
 func NewMemoryAdaptor(client *Client) client.IAdaptor {
 	return &Cache{client: client}
 }
 
 func (r *Cache) Set(ctx context.Context, params map[string][]byte, expire time.Duration) error {
+	cacheClient, err := r.client.getCacheClient()
+	if err != nil {
+		return err
+	}
+
 	for k, v := range params {
-		err := r.client.cacheClient.Set([]byte(k), v, int(expire.Seconds()))
+		err = cacheClient.Set([]byte(k), v, int(expire.Seconds()))
 		if err != nil {
 			return err
 		}
@@ -28,19 +42,30 @@ func (r *Cache) Set(ctx context.Context, params map[string][]byte, expire time.D
 }
 
 func (r *Cache) Del(ctx context.Context, k []string) error {
+	cacheClient, err := r.client.getCacheClient()
+	if err != nil {
+		return err
+	}
+
 	for _, v := range k {
-		r.client.cacheClient.Del([]byte(v))
+		cacheClient.Del([]byte(v))
 	}
+	r.tags.Remove(k)
 
 	return nil
 }
 
 func (r *Cache) Get(ctx context.Context, k []string) (map[string][]byte, error) {
+	cacheClient, err := r.client.getCacheClient()
+	if err != nil {
+		return nil, err
+	}
+
 	out := make(map[string][]byte)
 	for _, id := range k {
-		val, err := r.client.cacheClient.Get([]byte(id))
+		val, err := cacheClient.Get([]byte(id))
 		if err != nil {
-			if errors.As(err, &freecache.ErrNotFound) {
+			if errors.Is(err, freecache.ErrNotFound) {
 				continue
 			}
 			return nil, err
@@ -50,3 +75,315 @@ func (r *Cache) Get(ctx context.Context, k []string) (map[string][]byte, error)
 
 	return out, nil
 }
+
+func (r *Cache) GetWithTTL(ctx context.Context, k []string) (map[string]client.Item, error) {
+	cacheClient, err := r.client.getCacheClient()
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]client.Item)
+	for _, id := range k {
+		val, expireAt, err := cacheClient.GetWithExpiration([]byte(id))
+		if err != nil {
+			if errors.Is(err, freecache.ErrNotFound) {
+				continue
+			}
+			return nil, err
+		}
+		out[id] = client.Item{Value: val, TTL: ttl(expireAt)}
+	}
+
+	return out, nil
+}
+
+func (r *Cache) TTL(ctx context.Context, k []string) (map[string]time.Duration, error) {
+	cacheClient, err := r.client.getCacheClient()
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]time.Duration)
+	for _, id := range k {
+		timeLeft, err := cacheClient.TTL([]byte(id))
+		if err != nil {
+			if errors.Is(err, freecache.ErrNotFound) {
+				continue
+			}
+			return nil, err
+		}
+		if timeLeft == 0 {
+			out[id] = client.NoExpire
+			continue
+		}
+		out[id] = time.Duration(timeLeft) * time.Second
+	}
+
+	return out, nil
+}
+
+// Expire freecache 的过期时间精度为秒，不足1秒按1秒处理
+func (r *Cache) Expire(ctx context.Context, k []string, expire time.Duration) error {
+	cacheClient, err := r.client.getCacheClient()
+	if err != nil {
+		return err
+	}
+
+	var seconds int
+	if expire > 0 {
+		seconds = max(int(expire.Seconds()), 1)
+	}
+	for _, id := range k {
+		err = cacheClient.Touch([]byte(id), seconds)
+		if err != nil && !errors.Is(err, freecache.ErrNotFound) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *Cache) SetIfAbsent(ctx context.Context, params map[string][]byte, expire time.Duration) (map[string]bool, error) {
+	return r.update(params, expire, func(k string, old []byte, found bool) bool {
+		return !found
+	})
+}
+
+func (r *Cache) SetIfPresent(ctx context.Context, params map[string][]byte, expire time.Duration) (map[string]bool, error) {
+	return r.update(params, expire, func(k string, old []byte, found bool) bool {
+		return found
+	})
+}
+
+func (r *Cache) CompareAndSwap(ctx context.Context, params map[string]client.Swap, expire time.Duration) (map[string]bool, error) {
+	var (
+		kv      = make(map[string][]byte, len(params))
+		version = make(map[string]string, len(params))
+	)
+	for k, v := range params {
+		kv[k], version[k] = v.Value, v.Version
+	}
+
+	return r.update(kv, expire, func(k string, old []byte, found bool) bool {
+		if !found {
+			return version[k] == ""
+		}
+		return version[k] == client.Version(old)
+	})
+}
+
+// update 使用 freecache.Update 在segment锁内完成比较和写入，ok 返回true时写入
+func (r *Cache) update(params map[string][]byte, expire time.Duration, ok func(k string, old []byte, found bool) bool) (map[string]bool, error) {
+	cacheClient, err := r.client.getCacheClient()
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]bool, len(params))
+	for k, v := range params {
+		_, replaced, err := cacheClient.Update([]byte(k), func(old []byte, found bool) ([]byte, bool, int) {
+			return v, ok(k, old, found), int(expire.Seconds())
+		})
+		if err != nil {
+			return nil, err
+		}
+		out[k] = replaced
+	}
+
+	return out, nil
+}
+
+// IncrBy 计数之间是原子的，和同一个key的 Set 并发时以后写入的为准
+func (r *Cache) IncrBy(ctx context.Context, params map[string]int64, expire time.Duration) (map[string]int64, error) {
+	cacheClient, err := r.client.getCacheClient()
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]int64, len(params))
+	for k, delta := range params {
+		n, err := r.incrBy(cacheClient, k, delta, expire)
+		if err != nil {
+			return nil, client.NotAppliedError(out, params, err)
+		}
+		out[k] = n
+	}
+
+	return out, nil
+}
+
+func (r *Cache) incrBy(cacheClient *freecache.Cache, k string, delta int64, expire time.Duration) (int64, error) {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(k))
+	mu := &r.locks[h.Sum32()%uint32(len(r.locks))]
+	mu.Lock()
+	defer mu.Unlock()
+
+	seconds := int(expire.Seconds())
+	val, expireAt, err := cacheClient.GetWithExpiration([]byte(k))
+	switch {
+	case errors.Is(err, freecache.ErrNotFound):
+		val = nil
+	case err != nil:
+		return 0, err
+	case expireAt > 0:
+		// 已存在的key保留剩余过期时间
+		seconds = int(int64(expireAt) - time.Now().Unix())
+		if seconds <= 0 {
+			val, seconds = nil, int(expire.Seconds())
+		}
+	default:
+		seconds = 0
+	}
+
+	var n int64
+	if val != nil {
+		n, err = strconv.ParseInt(string(val), 10, 64)
+		if err != nil {
+			return 0, err
+		}
+	}
+	n += delta
+	err = cacheClient.Set([]byte(k), []byte(strconv.FormatInt(n, 10)), seconds)
+	return n, err
+}
+
+// HSet 字段编码后保存为一个值，在segment锁内合并原有字段
+func (r *Cache) HSet(ctx context.Context, params map[string]map[string][]byte, expire time.Duration) error {
+	cacheClient, err := r.client.getCacheClient()
+	if err != nil {
+		return err
+	}
+
+	for k, fields := range params {
+		var decodeErr error
+		_, _, err = cacheClient.Update([]byte(k), func(old []byte, found bool) ([]byte, bool, int) {
+			merged := make(map[string][]byte, len(fields))
+			if found {
+				if merged, decodeErr = client.DecodeFields(old); decodeErr != nil {
+					return nil, false, 0
+				}
+			}
+			for f, v := range fields {
+				merged[f] = v
+			}
+			return client.EncodeFields(merged), true, int(expire.Seconds())
+		})
+		if err = errors.Join(decodeErr, err); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *Cache) HGet(ctx context.Context, k []string, fields []string) (map[string]map[string][]byte, error) {
+	kv, err := r.Get(ctx, k)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]map[string][]byte, len(kv))
+	for key, v := range kv {
+		all, err := client.DecodeFields(v)
+		if err != nil {
+			return nil, err
+		}
+		if len(fields) == 0 {
+			out[key] = all
+			continue
+		}
+
+		selected := make(map[string][]byte, len(fields))
+		for _, f := range fields {
+			if fv, ok := all[f]; ok {
+				selected[f] = fv
+			}
+		}
+		out[key] = selected
+	}
+
+	return out, nil
+}
+
+// Scan 使用 freecache 的迭代器遍历，迭代器逐个segment加锁读取，不会长时间阻塞写入
+func (r *Cache) Scan(ctx context.Context, pattern string) client.KeyIterator {
+	cacheClient, err := r.client.getCacheClient()
+	if err != nil {
+		return client.ErrIterator(err)
+	}
+
+	it := cacheClient.NewIterator()
+	return client.NewKeyIterator(func(ctx context.Context) ([]string, bool, error) {
+		var keys []string
+		for len(keys) < scanBatch {
+			entry := it.Next()
+			if entry == nil {
+				return keys, true, nil
+			}
+			if key := string(entry.Key); client.MatchPattern(pattern, key) {
+				keys = append(keys, key)
+			}
+		}
+		return keys, false, nil
+	})
+}
+
+func (r *Cache) DelPrefix(ctx context.Context, prefix string) (int64, error) {
+	var (
+		n  int64
+		it = r.Scan(ctx, client.EscapePattern(prefix)+"*")
+	)
+	for it.Next(ctx) {
+		keys := it.Keys()
+		if err := r.Del(ctx, keys); err != nil {
+			return n, err
+		}
+		n += int64(len(keys))
+	}
+	return n, it.Err()
+}
+
+// ttl 根据freecache的过期时间戳（秒）计算剩余过期时间
+func ttl(expireAt uint32) time.Duration {
+	if expireAt == 0 {
+		return client.NoExpire
+	}
+	return max(time.Until(time.Unix(int64(expireAt), 0)), 0)
+}
+
+func (r *Cache) AddTags(ctx context.Context, params map[string][]string, expire time.Duration) error {
+	if _, err := r.client.getCacheClient(); err != nil {
+		return err
+	}
+
+	r.tags.Add(params, expire)
+	return nil
+}
+
+func (r *Cache) TagKeys(ctx context.Context, tags []string) ([]string, error) {
+	cacheClient, err := r.client.getCacheClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return r.tags.Keys(tags, func(key string) bool {
+		_, err := cacheClient.TTL([]byte(key))
+		return err == nil
+	}), nil
+}
+
+func (r *Cache) DelTags(ctx context.Context, tags []string) error {
+	if _, err := r.client.getCacheClient(); err != nil {
+		return err
+	}
+
+	r.tags.Del(tags)
+	return nil
+}
+
+// Close 关闭底层的内存缓存
+func (r *Cache) Close() error {
+	r.tags.Clear()
+	return r.client.Close()
+}
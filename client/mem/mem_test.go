@@ -1,18 +1,52 @@
 package mem
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/PycMono/go-cache/client"
+	"path/filepath"
 	"testing"
 	"time"
 )
 
+func TestConfig(t *testing.T) {
+	// 默认大小
+	memClient, err := NewMemCache(&Config{})
+	if err != nil {
+		panic(err)
+	}
+	if memClient.conf.cacheSize != defaultCacheSize {
+		t.Fatalf("cacheSize = %d, want %d", memClient.conf.cacheSize, defaultCacheSize)
+	}
+
+	// 不修改进程GC配置
+	old := TuneGC(77)
+	defer TuneGC(old)
+	conf := Config{}.WithCacheSize(minCacheSize).WithGCPercent(10)
+	if _, err = NewMemCache(&conf); err != nil {
+		panic(err)
+	}
+	if v := TuneGC(old); v != 77 {
+		t.Fatalf("gc percent = %d, want 77", v)
+	}
+
+	conf = Config{}.WithCacheSize(1024)
+	if _, err = NewMemCache(&conf); err == nil {
+		t.Fatalf("want error for cacheSize < %d", minCacheSize)
+	}
+}
+
 func TestMem(t *testing.T) {
-	memoryAdaptor := NewMemoryAdaptor(NewMemCache(&Config{
+	memClient, err := NewMemCache(&Config{
 		cacheSize: 1024 * 1024 * 1024,
-		gcPercent: 40,
-	}))
+	})
+	if err != nil {
+		panic(err)
+	}
+	memoryAdaptor := NewMemoryAdaptor(memClient)
 
 	kvMap := make(map[string][]byte)
 	kvMap["123"] = []byte("3333")
@@ -33,3 +67,82 @@ func TestMem(t *testing.T) {
 	fmt.Println(string(b))
 	fmt.Println("-------------------")
 }
+
+func TestSnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mem.snapshot")
+	conf := Config{}.WithCacheSize(minCacheSize).WithSnapshot(path)
+	memClient, err := NewMemCache(&conf)
+	if err != nil {
+		panic(err)
+	}
+	adaptor := NewMemoryAdaptor(memClient)
+	ctx := context.TODO()
+
+	_ = adaptor.Set(ctx, map[string][]byte{"forever": []byte("1")}, 0)
+	_ = adaptor.Set(ctx, map[string][]byte{"hour": []byte("2")}, time.Hour)
+	_ = adaptor.Set(ctx, map[string][]byte{"short": []byte("3")}, time.Second)
+	if err = adaptor.Close(); err != nil {
+		panic(err)
+	}
+
+	// 重启后恢复，跳过已过期的数据
+	time.Sleep(1100 * time.Millisecond)
+	memClient, err = NewMemCache(&conf)
+	if err != nil {
+		panic(err)
+	}
+	adaptor = NewMemoryAdaptor(memClient)
+	items, err := adaptor.GetWithTTL(ctx, []string{"forever", "hour", "short"})
+	if err != nil {
+		panic(err)
+	}
+	if len(items) != 2 || string(items["forever"].Value) != "1" || items["forever"].TTL != client.NoExpire {
+		t.Fatalf("items = %v", items)
+	}
+	if ttl := items["hour"].TTL; ttl < 59*time.Minute || ttl > time.Hour {
+		t.Fatalf("ttl = %s, want about 1h", ttl)
+	}
+
+	// 损坏的快照校验失败，不恢复任何数据
+	var buf bytes.Buffer
+	if n, err := memClient.Dump(ctx, &buf); err != nil || n != 2 {
+		t.Fatalf("Dump = %d, %v, want 2", n, err)
+	}
+	b := buf.Bytes()
+	b[len(b)-6] ^= 0xff
+	other, _ := NewMemCache(&Config{cacheSize: minCacheSize})
+	if _, err = other.Restore(ctx, bytes.NewReader(b)); err == nil {
+		t.Fatalf("want checksum error")
+	}
+	if kv, _ := NewMemoryAdaptor(other).Get(ctx, []string{"forever", "hour"}); len(kv) != 0 {
+		t.Fatalf("kv = %v, want empty after failed restore", kv)
+	}
+	if _, err = other.Restore(ctx, bytes.NewReader(b[:3])); !errors.Is(err, ErrSnapshotFormat) {
+		t.Fatalf("err = %v, want ErrSnapshotFormat", err)
+	}
+	_ = adaptor.Close()
+}
+
+func TestTags(t *testing.T) {
+	conf := Config{}.WithCacheSize(1024 * 1024)
+	memClient, err := NewMemCache(&conf)
+	if err != nil {
+		panic(err)
+	}
+	tagger := NewMemoryAdaptor(memClient).(client.ITagAdaptor)
+	defer tagger.Close()
+	ctx := context.TODO()
+
+	_ = tagger.Set(ctx, map[string][]byte{"a": []byte("a"), "b": []byte("b"), "c": []byte("c")}, 0)
+	_ = tagger.AddTags(ctx, map[string][]string{"a": {"t1"}, "b": {"t1", "t2"}, "c": {"t1"}, "missing": {"t1"}}, 0)
+
+	// 删除的key和缓存中不存在的key从索引中清理
+	_ = tagger.Del(ctx, []string{"a"})
+	_, _ = tagger.DelPrefix(ctx, "c")
+	if keys, _ := tagger.TagKeys(ctx, []string{"t1"}); len(keys) != 1 || keys[0] != "b" {
+		t.Fatalf("keys = %v, want b", keys)
+	}
+	if keys, _ := tagger.TagKeys(ctx, []string{"t2"}); len(keys) != 1 || keys[0] != "b" {
+		t.Fatalf("keys = %v, want b", keys)
+	}
+}
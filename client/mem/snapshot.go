@@ -0,0 +1,259 @@
+package mem
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"os"
+	"time"
+)
+
+// 快照格式：
+//
+//	magic(4) version(1)
+//	{ 1 keyLen(uvarint) key valLen(uvarint) value expireAt(uvarint) }...
+//	0 count(uvarint) crc32(4, 大端)
+//
+// expireAt 为过期时间戳（秒），0表示不过期，crc32 使用 Castagnoli 多项式，覆盖 crc32 之前的全部字节
+const (
+	snapshotMagic   = "GCMS"
+	snapshotVersion = 1
+
+	recordEntry = 1
+	recordEnd   = 0
+
+	maxKeyLen = 65535 // freecache 的key长度上限
+)
+
+var (
+	ErrSnapshotFormat   = errors.New("mem: invalid snapshot format")
+	ErrSnapshotChecksum = errors.New("mem: snapshot checksum mismatch")
+)
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// Dump 把未过期的数据流式写入w，逐个segment加锁读取，不会复制整个缓存，返回写入的条数
+// 写入期间的并发修改可能包含也可能不包含在快照中
+func (c *Client) Dump(ctx context.Context, w io.Writer) (int, error) {
+	cacheClient, err := c.getCacheClient()
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		crc = crc32.New(castagnoli)
+		bw  = bufio.NewWriter(io.MultiWriter(w, crc))
+		buf [binary.MaxVarintLen64]byte
+		n   int
+	)
+	putUvarint := func(v uint64) {
+		_, _ = bw.Write(buf[:binary.PutUvarint(buf[:], v)])
+	}
+
+	_, _ = bw.WriteString(snapshotMagic)
+	_ = bw.WriteByte(snapshotVersion)
+	it := cacheClient.NewIterator()
+	for entry := it.Next(); entry != nil; entry = it.Next() {
+		if n%1000 == 0 {
+			if err = ctx.Err(); err != nil {
+				return n, err
+			}
+		}
+		_ = bw.WriteByte(recordEntry)
+		putUvarint(uint64(len(entry.Key)))
+		_, _ = bw.Write(entry.Key)
+		putUvarint(uint64(len(entry.Value)))
+		_, _ = bw.Write(entry.Value)
+		putUvarint(uint64(entry.ExpireAt))
+		n++
+	}
+	_ = bw.WriteByte(recordEnd)
+	putUvarint(uint64(n))
+	if err = bw.Flush(); err != nil {
+		return n, err
+	}
+
+	_, err = w.Write(binary.BigEndian.AppendUint32(nil, crc.Sum32()))
+	return n, err
+}
+
+// Restore 从r流式恢复数据，跳过已过期的数据，返回恢复的条数
+// 读取到结尾才能校验 checksum，校验失败或格式错误时删除已恢复的数据，为此只在内存中保留已恢复的key
+func (c *Client) Restore(ctx context.Context, r io.Reader) (int, error) {
+	cacheClient, err := c.getCacheClient()
+	if err != nil {
+		return 0, err
+	}
+
+	sr := &snapshotReader{r: bufio.NewReader(r), crc: crc32.New(castagnoli)}
+	var restored [][]byte
+	rollback := func(err error) (int, error) {
+		for _, k := range restored {
+			cacheClient.Del(k)
+		}
+		return 0, err
+	}
+
+	header := make([]byte, len(snapshotMagic)+1)
+	if err = sr.full(header); err != nil {
+		return rollback(err)
+	}
+	if string(header[:len(snapshotMagic)]) != snapshotMagic {
+		return rollback(ErrSnapshotFormat)
+	}
+	if header[len(snapshotMagic)] != snapshotVersion {
+		return rollback(fmt.Errorf("mem: unsupported snapshot version %d", header[len(snapshotMagic)]))
+	}
+
+	var count uint64
+	for i := 0; ; i++ {
+		if i%1000 == 0 {
+			if err = ctx.Err(); err != nil {
+				return rollback(err)
+			}
+		}
+
+		kind, err := sr.byte()
+		if err != nil {
+			return rollback(err)
+		}
+		if kind == recordEnd {
+			break
+		}
+		if kind != recordEntry {
+			return rollback(ErrSnapshotFormat)
+		}
+
+		key, err := sr.bytes(maxKeyLen)
+		if err != nil {
+			return rollback(err)
+		}
+		val, err := sr.bytes(uint64(c.conf.cacheSize))
+		if err != nil {
+			return rollback(err)
+		}
+		expireAt, err := sr.uvarint()
+		if err != nil {
+			return rollback(err)
+		}
+		count++
+
+		var seconds int
+		if expireAt > 0 {
+			remaining := time.Until(time.Unix(int64(expireAt), 0))
+			if remaining < time.Second {
+				continue // 已过期或即将过期
+			}
+			seconds = int(remaining.Seconds())
+		}
+		if err = cacheClient.Set(key, val, seconds); err != nil {
+			continue // 超过单条数据上限，跳过
+		}
+		restored = append(restored, key)
+	}
+
+	n, err := sr.uvarint()
+	if err != nil {
+		return rollback(err)
+	}
+	if n != count {
+		return rollback(ErrSnapshotFormat)
+	}
+	sum := sr.crc.Sum32()
+	tail := make([]byte, 4)
+	if _, err = io.ReadFull(sr.r, tail); err != nil {
+		return rollback(ErrSnapshotFormat)
+	}
+	if binary.BigEndian.Uint32(tail) != sum {
+		return rollback(ErrSnapshotChecksum)
+	}
+
+	return len(restored), nil
+}
+
+// dumpFile 写入临时文件后重命名，避免写入中途退出留下不完整的快照
+func (c *Client) dumpFile(path string) error {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	_, err = c.Dump(context.Background(), f)
+	if err = errors.Join(err, f.Sync(), f.Close()); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// restoreFile 快照文件不存在时忽略
+func (c *Client) restoreFile(path string) error {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = c.Restore(context.Background(), f)
+	return err
+}
+
+// snapshotReader 读取时同时计算 checksum
+type snapshotReader struct {
+	r   *bufio.Reader
+	crc hash.Hash32
+}
+
+func (s *snapshotReader) full(b []byte) error {
+	if _, err := io.ReadFull(s.r, b); err != nil {
+		return ErrSnapshotFormat
+	}
+	_, _ = s.crc.Write(b)
+	return nil
+}
+
+func (s *snapshotReader) byte() (byte, error) {
+	b, err := s.r.ReadByte()
+	if err != nil {
+		return 0, ErrSnapshotFormat
+	}
+	_, _ = s.crc.Write([]byte{b})
+	return b, nil
+}
+
+func (s *snapshotReader) uvarint() (uint64, error) {
+	v, err := binary.ReadUvarint(byteReader{s})
+	if err != nil {
+		return 0, ErrSnapshotFormat
+	}
+	return v, nil
+}
+
+// bytes 读取长度和内容，长度超过limit视为格式错误，避免损坏的文件申请过大的内存
+func (s *snapshotReader) bytes(limit uint64) ([]byte, error) {
+	n, err := s.uvarint()
+	if err != nil {
+		return nil, err
+	}
+	if n > limit {
+		return nil, ErrSnapshotFormat
+	}
+	b := make([]byte, n)
+	return b, s.full(b)
+}
+
+type byteReader struct {
+	s *snapshotReader
+}
+
+func (b byteReader) ReadByte() (byte, error) {
+	return b.s.byte()
+}
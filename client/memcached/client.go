@@ -0,0 +1,411 @@
+package memcached
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/PycMono/go-cache/client"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxRelativeExpire memcached 的过期时间超过30天时按 unix 时间戳处理
+const maxRelativeExpire = 30 * 24 * time.Hour
+
+// Entry 查询到的数据
+type Entry struct {
+	Value []byte
+	Flags uint32
+	CAS   uint64 // gets 返回的 cas 唯一值，用于 cas 命令
+}
+
+// Client memcached 文本协议客户端，按一致性哈希把key分配到各个地址，每个地址维护一个连接池
+// 同一个地址的多个命令在一个连接上批量发送后再依次读取响应，不同地址并发执行
+type Client struct {
+	conf   Config
+	ring   *ring
+	pools  map[string]chan *conn // 地址→空闲连接
+	sm     sync.RWMutex
+	closed bool
+}
+
+func NewMemcachedClient(conf *Config) (*Client, error) {
+	c, err := conf.build()
+	if err != nil {
+		return nil, err
+	}
+
+	pools := make(map[string]chan *conn, len(c.servers))
+	for _, addr := range c.servers {
+		pools[addr] = make(chan *conn, c.maxIdleConns)
+	}
+	return &Client{
+		conf:  c,
+		ring:  newRing(c.servers, c.replicas),
+		pools: pools,
+	}, nil
+}
+
+// GetMulti 使用 gets 批量查询，不存在的key不返回
+func (c *Client) GetMulti(ctx context.Context, keys []string) (map[string]Entry, error) {
+	return each(ctx, c, keys, func(cn *conn, keys []string) (map[string]Entry, error) {
+		return cn.gets(keys)
+	})
+}
+
+// Close 关闭全部空闲连接，重复调用返回 client.ErrClosed
+func (c *Client) Close() error {
+	c.sm.Lock()
+	defer c.sm.Unlock()
+
+	if c.closed {
+		return client.ErrClosed
+	}
+	c.closed = true
+	for _, pool := range c.pools {
+		close(pool)
+		for cn := range pool {
+			_ = cn.nc.Close()
+		}
+	}
+	return nil
+}
+
+// each 按地址分组后并发执行f，合并各个地址的结果，部分地址出错时返回已成功的结果和合并后的错误
+func each[T any](ctx context.Context, c *Client, keys []string, f func(cn *conn, keys []string) (map[string]T, error)) (map[string]T, error) {
+	c.sm.RLock()
+	closed := c.closed
+	c.sm.RUnlock()
+	if closed {
+		return nil, client.ErrClosed
+	}
+	for _, key := range keys {
+		if err := checkKey(key); err != nil {
+			return nil, err
+		}
+	}
+
+	var (
+		sm   sync.Mutex
+		wg   sync.WaitGroup
+		errs []error
+		out  = make(map[string]T, len(keys))
+	)
+	for addr, group := range c.ring.group(keys) {
+		wg.Add(1)
+		go func(addr string, group []string) {
+			defer wg.Done()
+
+			var res map[string]T
+			err := c.withConn(ctx, addr, func(cn *conn) (err error) {
+				res, err = f(cn, group)
+				return err
+			})
+
+			sm.Lock()
+			defer sm.Unlock()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("memcached %s: %w", addr, err))
+				return
+			}
+			for k, v := range res {
+				out[k] = v
+			}
+		}(addr, group)
+	}
+	wg.Wait()
+	return out, errors.Join(errs...)
+}
+
+// withConn 从连接池获取连接执行f，出错的连接可能还有未读取的响应，直接关闭不放回连接池
+func (c *Client) withConn(ctx context.Context, addr string, f func(cn *conn) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	cn, err := c.getConn(ctx, addr)
+	if err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(c.conf.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err = cn.nc.SetDeadline(deadline); err == nil {
+		err = f(cn)
+	}
+	if err != nil {
+		_ = cn.nc.Close()
+		return err
+	}
+	c.putConn(addr, cn)
+	return nil
+}
+
+func (c *Client) getConn(ctx context.Context, addr string) (*conn, error) {
+	c.sm.RLock()
+	if c.closed {
+		c.sm.RUnlock()
+		return nil, client.ErrClosed
+	}
+	select {
+	case cn := <-c.pools[addr]:
+		c.sm.RUnlock()
+		return cn, nil
+	default:
+	}
+	c.sm.RUnlock()
+
+	dialer := net.Dialer{Timeout: c.conf.timeout}
+	nc, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{nc: nc, rw: bufio.NewReadWriter(bufio.NewReader(nc), bufio.NewWriter(nc))}, nil
+}
+
+// putConn 放回连接池，连接池已满或已关闭时关闭连接
+func (c *Client) putConn(addr string, cn *conn) {
+	c.sm.RLock()
+	defer c.sm.RUnlock()
+
+	if !c.closed {
+		select {
+		case c.pools[addr] <- cn:
+			return
+		default:
+		}
+	}
+	_ = cn.nc.Close()
+}
+
+// conn 一个 memcached 连接
+type conn struct {
+	nc net.Conn
+	rw *bufio.ReadWriter
+}
+
+// storeOp 一个写入命令，cmd 为 set、add、replace 或 cas
+type storeOp struct {
+	cmd    string
+	value  []byte
+	expire int64
+	cas    uint64
+}
+
+// store 批量发送写入命令后依次读取响应，返回每个key是否写入
+func (cn *conn) store(keys []string, ops map[string]storeOp) (map[string]bool, error) {
+	for _, key := range keys {
+		op := ops[key]
+		if op.cmd == "cas" {
+			_, _ = fmt.Fprintf(cn.rw, "cas %s 0 %d %d %d\r\n", key, op.expire, len(op.value), op.cas)
+		} else {
+			_, _ = fmt.Fprintf(cn.rw, "%s %s 0 %d %d\r\n", op.cmd, key, op.expire, len(op.value))
+		}
+		_, _ = cn.rw.Write(op.value)
+		_, _ = cn.rw.WriteString("\r\n")
+	}
+	lines, err := cn.roundTrip(len(keys))
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]bool, len(keys))
+	for i, key := range keys {
+		switch lines[i] {
+		case "STORED":
+			out[key] = true
+		case "NOT_STORED", "EXISTS", "NOT_FOUND":
+			out[key] = false
+		default:
+			return nil, fmt.Errorf("unexpected response %q", lines[i])
+		}
+	}
+	return out, nil
+}
+
+// command 批量发送只返回一行状态的命令（delete、touch），found 为key存在时的响应
+func (cn *conn) command(keys []string, format, found string, args ...any) (map[string]bool, error) {
+	for _, key := range keys {
+		_, _ = fmt.Fprintf(cn.rw, format, append([]any{key}, args...)...)
+	}
+	lines, err := cn.roundTrip(len(keys))
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]bool, len(keys))
+	for i, key := range keys {
+		switch lines[i] {
+		case found:
+			out[key] = true
+		case "NOT_FOUND":
+			out[key] = false
+		default:
+			return nil, fmt.Errorf("unexpected response %q", lines[i])
+		}
+	}
+	return out, nil
+}
+
+// gets 一个命令查询多个key
+func (cn *conn) gets(keys []string) (map[string]Entry, error) {
+	_, _ = fmt.Fprintf(cn.rw, "gets %s\r\n", strings.Join(keys, " "))
+	if err := cn.rw.Flush(); err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]Entry, len(keys))
+	for {
+		line, err := cn.readLine()
+		if err != nil {
+			return nil, err
+		}
+		if line == "END" {
+			return out, nil
+		}
+
+		// VALUE <key> <flags> <bytes> <cas>
+		fields := strings.Fields(line)
+		if len(fields) != 5 || fields[0] != "VALUE" {
+			return nil, fmt.Errorf("unexpected response %q", line)
+		}
+		flags, err1 := strconv.ParseUint(fields[2], 10, 32)
+		size, err2 := strconv.Atoi(fields[3])
+		cas, err3 := strconv.ParseUint(fields[4], 10, 64)
+		if err = errors.Join(err1, err2, err3); err != nil {
+			return nil, fmt.Errorf("unexpected response %q: %w", line, err)
+		}
+		value, err := cn.readValue(size)
+		if err != nil {
+			return nil, err
+		}
+		out[fields[1]] = Entry{Value: value, Flags: uint32(flags), CAS: cas}
+	}
+}
+
+// metaGet 使用 meta 命令 mg 查询剩余过期时间，withValue 为true时同时返回数据，需要 memcached 1.6 及以上版本
+func (cn *conn) metaGet(keys []string, withValue bool) (map[string]client.Item, error) {
+	flags := "t"
+	if withValue {
+		flags = "t v"
+	}
+	for _, key := range keys {
+		_, _ = fmt.Fprintf(cn.rw, "mg %s %s\r\n", key, flags)
+	}
+	if err := cn.rw.Flush(); err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]client.Item, len(keys))
+	for _, key := range keys {
+		line, err := cn.readLine()
+		if err != nil {
+			return nil, err
+		}
+
+		// EN 不存在；HD <flags> 存在；VA <size> <flags> 存在且后面跟着数据
+		fields := strings.Fields(line)
+		if len(fields) == 0 || (fields[0] != "EN" && fields[0] != "HD" && fields[0] != "VA") {
+			return nil, fmt.Errorf("unexpected response %q", line)
+		}
+		if fields[0] == "EN" {
+			continue
+		}
+
+		item := client.Item{TTL: client.NoExpire}
+		if fields[0] == "VA" {
+			if len(fields) < 2 {
+				return nil, fmt.Errorf("unexpected response %q", line)
+			}
+			size, err := strconv.Atoi(fields[1])
+			if err != nil {
+				return nil, fmt.Errorf("unexpected response %q: %w", line, err)
+			}
+			if item.Value, err = cn.readValue(size); err != nil {
+				return nil, err
+			}
+		}
+		for _, f := range fields[1:] {
+			if ttl, ok := strings.CutPrefix(f, "t"); ok && ttl != "-1" {
+				sec, err := strconv.ParseInt(ttl, 10, 64)
+				if err != nil {
+					return nil, fmt.Errorf("unexpected response %q: %w", line, err)
+				}
+				item.TTL = time.Duration(sec) * time.Second
+			}
+		}
+		out[key] = item
+	}
+	return out, nil
+}
+
+// roundTrip 发送缓冲区中的命令，读取n行响应
+func (cn *conn) roundTrip(n int) ([]string, error) {
+	if err := cn.rw.Flush(); err != nil {
+		return nil, err
+	}
+	lines := make([]string, n)
+	for i := range lines {
+		line, err := cn.readLine()
+		if err != nil {
+			return nil, err
+		}
+		lines[i] = line
+	}
+	return lines, nil
+}
+
+// readLine 读取一行响应，ERROR、CLIENT_ERROR、SERVER_ERROR 转换为错误
+func (cn *conn) readLine() (string, error) {
+	line, err := cn.rw.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+	if line == "ERROR" || strings.HasPrefix(line, "CLIENT_ERROR") || strings.HasPrefix(line, "SERVER_ERROR") {
+		return "", errors.New(line)
+	}
+	return line, nil
+}
+
+// readValue 读取 size 字节的数据及结尾的 \r\n
+func (cn *conn) readValue(size int) ([]byte, error) {
+	buf := make([]byte, size+2)
+	if _, err := io.ReadFull(cn.rw, buf); err != nil {
+		return nil, err
+	}
+	if string(buf[size:]) != "\r\n" {
+		return nil, errors.New("malformed value")
+	}
+	return buf[:size], nil
+}
+
+// checkKey memcached 的key最长250字节，不能包含空白和控制字符
+func checkKey(key string) error {
+	if len(key) == 0 || len(key) > 250 {
+		return fmt.Errorf("memcached: invalid key length %d", len(key))
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] <= ' ' || key[i] == 0x7f {
+			return fmt.Errorf("memcached: invalid key %q", key)
+		}
+	}
+	return nil
+}
+
+// expireTime 转换为 memcached 的过期时间：秒数，不足1秒按1秒；超过30天使用 unix 时间戳；0表示不过期
+func expireTime(expire time.Duration) int64 {
+	if expire <= 0 {
+		return 0
+	}
+	if expire > maxRelativeExpire {
+		return time.Now().Add(expire).Unix()
+	}
+	return int64((expire + time.Second - 1) / time.Second)
+}
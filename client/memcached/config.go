@@ -0,0 +1,71 @@
+package memcached
+
+import (
+	"fmt"
+	"github.com/PycMono/go-cache/client"
+	"time"
+)
+
+// Config 配置文件
+type Config struct {
+	servers      []string            // memcached 地址列表，例如 127.0.0.1:11211，key按一致性哈希分布到各个地址
+	timeout      time.Duration       // 建立连接及每次读写的超时（默认1秒），ctx 设置了更早的截止时间时以ctx为准
+	maxIdleConns int                 // 每个地址保留的空闲连接数（默认2）
+	replicas     int                 // 一致性哈希中每个地址的虚拟节点数（默认160）
+	retryPolicy  *client.RetryPolicy // 适配器 Get/Set/Del 的重试策略，默认 client.DefaultRetryPolicy
+}
+
+func (c Config) WithServers(servers ...string) Config {
+	c.servers = servers
+	return c
+}
+
+func (c Config) WithTimeout(timeout time.Duration) Config {
+	c.timeout = timeout
+	return c
+}
+
+func (c Config) WithMaxIdleConns(maxIdleConns int) Config {
+	c.maxIdleConns = maxIdleConns
+	return c
+}
+
+// WithReplicas 设置每个地址的虚拟节点数，数量越多key分布越均匀
+func (c Config) WithReplicas(replicas int) Config {
+	c.replicas = replicas
+	return c
+}
+
+// WithRetryPolicy 设置适配器的重试策略，传入 client.RetryPolicy{} 关闭重试
+func (c Config) WithRetryPolicy(retryPolicy client.RetryPolicy) Config {
+	c.retryPolicy = &retryPolicy
+	return c
+}
+
+// getRetryPolicy 获取重试策略，未设置使用默认策略
+func (c *Config) getRetryPolicy() client.RetryPolicy {
+	if c.retryPolicy == nil {
+		return client.DefaultRetryPolicy()
+	}
+	return *c.retryPolicy
+}
+
+func (c Config) build() (Config, error) {
+	if len(c.servers) == 0 {
+		return c, fmt.Errorf("servers为空")
+	}
+	if c.timeout == 0 {
+		c.timeout = time.Second
+	}
+	if c.maxIdleConns == 0 {
+		c.maxIdleConns = 2
+	}
+	if c.replicas == 0 {
+		c.replicas = 160
+	}
+
+	if c.timeout < 0 || c.maxIdleConns < 0 || c.replicas < 0 {
+		return c, fmt.Errorf("timeout、maxIdleConns、replicas不能为负数")
+	}
+	return c, nil
+}
@@ -0,0 +1,173 @@
+package memcached
+
+import (
+	"context"
+	"github.com/PycMono/go-cache/client"
+	"time"
+)
+
+type Cache struct {
+	client *Client
+	retry  client.RetryPolicy // 重试策略，遵循ctx取消
+}
+
+func NewMemcachedAdaptor(client *Client) client.IAdaptor {
+	return &Cache{
+		client: client,
+		retry:  client.conf.getRetryPolicy(),
+	}
+}
+
+// Set 每个地址一个连接批量发送 set 命令
+func (m *Cache) Set(ctx context.Context, params map[string][]byte, expire time.Duration) error {
+	ops := storeOps(params, "set", expire)
+	return m.retry.Do(ctx, func() error {
+		_, err := m.store(ctx, ops)
+		return err
+	})
+}
+
+func (m *Cache) Get(ctx context.Context, k []string) (map[string][]byte, error) {
+	var out map[string][]byte
+	err := m.retry.Do(ctx, func() error {
+		entries, err := m.client.GetMulti(ctx, k)
+		if err != nil {
+			return err
+		}
+		out = make(map[string][]byte, len(entries))
+		for key, e := range entries {
+			out[key] = e.Value
+		}
+		return nil
+	})
+	return out, err
+}
+
+func (m *Cache) Del(ctx context.Context, k []string) error {
+	return m.retry.Do(ctx, func() error {
+		_, err := each(ctx, m.client, k, func(cn *conn, keys []string) (map[string]bool, error) {
+			return cn.command(keys, "delete %s\r\n", "DELETED")
+		})
+		return err
+	})
+}
+
+// GetWithTTL 使用 meta 命令 mg，需要 memcached 1.6 及以上版本
+func (m *Cache) GetWithTTL(ctx context.Context, k []string) (map[string]client.Item, error) {
+	return m.metaGet(ctx, k, true)
+}
+
+// TTL 使用 meta 命令 mg，需要 memcached 1.6 及以上版本，剩余过期时间精确到秒
+func (m *Cache) TTL(ctx context.Context, k []string) (map[string]time.Duration, error) {
+	items, err := m.metaGet(ctx, k, false)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]time.Duration, len(items))
+	for key, item := range items {
+		out[key] = item.TTL
+	}
+	return out, nil
+}
+
+// Expire 使用 touch 命令，expire 小于等于0时去掉过期时间
+func (m *Cache) Expire(ctx context.Context, k []string, expire time.Duration) error {
+	return m.retry.Do(ctx, func() error {
+		_, err := each(ctx, m.client, k, func(cn *conn, keys []string) (map[string]bool, error) {
+			return cn.command(keys, "touch %s %d\r\n", "TOUCHED", expireTime(expire))
+		})
+		return err
+	})
+}
+
+// SetIfAbsent 使用 add 命令，条件写入结果不确定时重试会误判，不重试
+func (m *Cache) SetIfAbsent(ctx context.Context, params map[string][]byte, expire time.Duration) (map[string]bool, error) {
+	return m.store(ctx, storeOps(params, "add", expire))
+}
+
+// SetIfPresent 使用 replace 命令，不重试
+func (m *Cache) SetIfPresent(ctx context.Context, params map[string][]byte, expire time.Duration) (map[string]bool, error) {
+	return m.store(ctx, storeOps(params, "replace", expire))
+}
+
+// CompareAndSwap 先用 gets 查询当前数据和cas唯一值，版本号一致时使用 cas 命令写入
+// 查询和写入之间数据被修改时 cas 命令返回 EXISTS，比较和写入仍然是原子的；Version 为空时使用 add，不重试
+func (m *Cache) CompareAndSwap(ctx context.Context, params map[string]client.Swap, expire time.Duration) (map[string]bool, error) {
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	entries, err := m.client.GetMulti(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		out = make(map[string]bool, len(params))
+		ops = make(map[string]storeOp, len(params))
+	)
+	for key, swap := range params {
+		e, ok := entries[key]
+		switch {
+		case swap.Version == "":
+			ops[key] = storeOp{cmd: "add", value: swap.Value, expire: expireTime(expire)}
+		case ok && client.Version(e.Value) == swap.Version:
+			ops[key] = storeOp{cmd: "cas", value: swap.Value, expire: expireTime(expire), cas: e.CAS}
+		default:
+			out[key] = false
+		}
+	}
+
+	stored, err := m.store(ctx, ops)
+	if err != nil {
+		return nil, err
+	}
+	for key, ok := range stored {
+		out[key] = ok
+	}
+	return out, nil
+}
+
+// Scan memcached 不支持遍历key
+func (m *Cache) Scan(ctx context.Context, pattern string) client.KeyIterator {
+	return client.ErrIterator(client.ErrNotSupported)
+}
+
+// DelPrefix memcached 不支持遍历key
+func (m *Cache) DelPrefix(ctx context.Context, prefix string) (int64, error) {
+	return 0, client.ErrNotSupported
+}
+
+func (m *Cache) Close() error {
+	return m.client.Close()
+}
+
+func (m *Cache) store(ctx context.Context, ops map[string]storeOp) (map[string]bool, error) {
+	keys := make([]string, 0, len(ops))
+	for key := range ops {
+		keys = append(keys, key)
+	}
+	return each(ctx, m.client, keys, func(cn *conn, keys []string) (map[string]bool, error) {
+		return cn.store(keys, ops)
+	})
+}
+
+func (m *Cache) metaGet(ctx context.Context, k []string, withValue bool) (map[string]client.Item, error) {
+	var out map[string]client.Item
+	err := m.retry.Do(ctx, func() (err error) {
+		out, err = each(ctx, m.client, k, func(cn *conn, keys []string) (map[string]client.Item, error) {
+			return cn.metaGet(keys, withValue)
+		})
+		return err
+	})
+	return out, err
+}
+
+func storeOps(params map[string][]byte, cmd string, expire time.Duration) map[string]storeOp {
+	ops := make(map[string]storeOp, len(params))
+	for key, value := range params {
+		ops[key] = storeOp{cmd: cmd, value: value, expire: expireTime(expire)}
+	}
+	return ops
+}
@@ -0,0 +1,312 @@
+package memcached
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"github.com/PycMono/go-cache/client"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeItem struct {
+	value    []byte
+	cas      uint64
+	expireAt time.Time // 零值表示不过期
+}
+
+// fakeServer 实现测试用到的 memcached 文本协议及 mg 命令
+type fakeServer struct {
+	ln    net.Listener
+	sm    sync.Mutex
+	items map[string]*fakeItem
+	cas   uint64
+}
+
+func newFakeServer() *fakeServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(err)
+	}
+	s := &fakeServer{ln: ln, items: make(map[string]*fakeItem)}
+	go func() {
+		for {
+			nc, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(nc)
+		}
+	}()
+	return s
+}
+
+func (s *fakeServer) addr() string {
+	return s.ln.Addr().String()
+}
+
+func (s *fakeServer) len() int {
+	s.sm.Lock()
+	defer s.sm.Unlock()
+
+	return len(s.items)
+}
+
+func (s *fakeServer) serve(nc net.Conn) {
+	defer nc.Close()
+	rw := bufio.NewReadWriter(bufio.NewReader(nc), bufio.NewWriter(nc))
+	for {
+		line, err := rw.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		var value []byte
+		switch fields[0] {
+		case "set", "add", "replace", "cas":
+			size, _ := strconv.Atoi(fields[4])
+			value = make([]byte, size+2)
+			if _, err = io.ReadFull(rw, value); err != nil {
+				return
+			}
+			value = value[:size]
+		}
+		s.handle(rw, fields, value)
+		if rw.Reader.Buffered() == 0 {
+			_ = rw.Flush()
+		}
+	}
+}
+
+func (s *fakeServer) handle(w *bufio.ReadWriter, fields []string, value []byte) {
+	s.sm.Lock()
+	defer s.sm.Unlock()
+
+	now := time.Now()
+	get := func(key string) *fakeItem {
+		item, ok := s.items[key]
+		if !ok || (!item.expireAt.IsZero() && !item.expireAt.After(now)) {
+			delete(s.items, key)
+			return nil
+		}
+		return item
+	}
+	expireAt := func(exp string) time.Time {
+		n, _ := strconv.ParseInt(exp, 10, 64)
+		switch {
+		case n <= 0:
+			return time.Time{}
+		case n > int64(maxRelativeExpire/time.Second):
+			return time.Unix(n, 0)
+		default:
+			return now.Add(time.Duration(n) * time.Second)
+		}
+	}
+
+	switch cmd := fields[0]; cmd {
+	case "gets":
+		for _, key := range fields[1:] {
+			if item := get(key); item != nil {
+				fmt.Fprintf(w, "VALUE %s 0 %d %d\r\n%s\r\n", key, len(item.value), item.cas, item.value)
+			}
+		}
+		w.WriteString("END\r\n")
+	case "set", "add", "replace", "cas":
+		item := get(fields[1])
+		switch {
+		case cmd == "add" && item != nil, cmd == "replace" && item == nil:
+			w.WriteString("NOT_STORED\r\n")
+			return
+		case cmd == "cas" && item == nil:
+			w.WriteString("NOT_FOUND\r\n")
+			return
+		case cmd == "cas" && fields[5] != strconv.FormatUint(item.cas, 10):
+			w.WriteString("EXISTS\r\n")
+			return
+		}
+		s.cas++
+		s.items[fields[1]] = &fakeItem{value: value, cas: s.cas, expireAt: expireAt(fields[3])}
+		w.WriteString("STORED\r\n")
+	case "delete":
+		if get(fields[1]) == nil {
+			w.WriteString("NOT_FOUND\r\n")
+			return
+		}
+		delete(s.items, fields[1])
+		w.WriteString("DELETED\r\n")
+	case "touch":
+		item := get(fields[1])
+		if item == nil {
+			w.WriteString("NOT_FOUND\r\n")
+			return
+		}
+		item.expireAt = expireAt(fields[2])
+		w.WriteString("TOUCHED\r\n")
+	case "mg":
+		item := get(fields[1])
+		if item == nil {
+			w.WriteString("EN\r\n")
+			return
+		}
+		ttl := int64(-1)
+		if !item.expireAt.IsZero() {
+			ttl = int64(item.expireAt.Sub(now).Round(time.Second) / time.Second)
+		}
+		if len(fields) > 3 && fields[3] == "v" {
+			fmt.Fprintf(w, "VA %d t%d\r\n%s\r\n", len(item.value), ttl, item.value)
+			return
+		}
+		fmt.Fprintf(w, "HD t%d\r\n", ttl)
+	default:
+		w.WriteString("ERROR\r\n")
+	}
+}
+
+func newTestAdaptor(servers ...*fakeServer) client.IAdaptor {
+	addrs := make([]string, len(servers))
+	for i, s := range servers {
+		addrs[i] = s.addr()
+	}
+	conf := Config{}.WithServers(addrs...).WithRetryPolicy(client.RetryPolicy{})
+	memcachedClient, err := NewMemcachedClient(&conf)
+	if err != nil {
+		panic(err)
+	}
+	return NewMemcachedAdaptor(memcachedClient)
+}
+
+func TestMemcached(t *testing.T) {
+	var (
+		s1, s2  = newFakeServer(), newFakeServer()
+		adaptor = newTestAdaptor(s1, s2)
+		ctx     = context.TODO()
+		kvMap   = make(map[string][]byte)
+		keys    []string
+	)
+	defer s1.ln.Close()
+	defer s2.ln.Close()
+
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("key:%d", i)
+		kvMap[key] = []byte(strconv.Itoa(i))
+		keys = append(keys, key)
+	}
+	if err := adaptor.Set(ctx, kvMap, time.Hour); err != nil {
+		panic(err)
+	}
+	// 一致性哈希把key分配到两个地址
+	if s1.len() == 0 || s2.len() == 0 || s1.len()+s2.len() != 100 {
+		t.Fatalf("distribution = %d/%d, want both > 0", s1.len(), s2.len())
+	}
+
+	respMap, err := adaptor.Get(ctx, append(keys, "missing"))
+	if err != nil {
+		panic(err)
+	}
+	if len(respMap) != 100 || string(respMap["key:42"]) != "42" {
+		t.Fatalf("len = %d, key:42 = %q, want 100 keys", len(respMap), respMap["key:42"])
+	}
+
+	ttl, _ := adaptor.TTL(ctx, []string{"key:1", "missing"})
+	if len(ttl) != 1 || ttl["key:1"] != time.Hour {
+		t.Fatalf("ttl = %v, want key:1 1h", ttl)
+	}
+	_ = adaptor.Expire(ctx, []string{"key:1"}, 0)
+	items, _ := adaptor.GetWithTTL(ctx, []string{"key:1"})
+	if items["key:1"].TTL != client.NoExpire || string(items["key:1"].Value) != "1" {
+		t.Fatalf("item = %+v, want 1 without expire", items["key:1"])
+	}
+
+	if err = adaptor.Del(ctx, []string{"key:1", "missing"}); err != nil {
+		panic(err)
+	}
+	if respMap, _ = adaptor.Get(ctx, []string{"key:1"}); len(respMap) != 0 {
+		t.Fatalf("respMap = %v, want empty", respMap)
+	}
+
+	if _, err = adaptor.Get(ctx, []string{"bad key"}); err == nil {
+		t.Fatalf("want error for key with space")
+	}
+	if err = adaptor.Close(); err != nil {
+		panic(err)
+	}
+	if _, err = adaptor.Get(ctx, keys); err != client.ErrClosed {
+		t.Fatalf("err = %v, want ErrClosed", err)
+	}
+}
+
+func TestConditional(t *testing.T) {
+	var (
+		s       = newFakeServer()
+		adaptor = newTestAdaptor(s)
+		ctx     = context.TODO()
+	)
+	defer s.ln.Close()
+	defer adaptor.Close()
+
+	ok, _ := adaptor.SetIfAbsent(ctx, map[string][]byte{"1": []byte("a")}, 0)
+	if !ok["1"] {
+		t.Fatalf("SetIfAbsent = %v, want stored", ok)
+	}
+	ok, _ = adaptor.SetIfAbsent(ctx, map[string][]byte{"1": []byte("b")}, 0)
+	if ok["1"] {
+		t.Fatalf("SetIfAbsent = %v, want not stored", ok)
+	}
+	ok, _ = adaptor.SetIfPresent(ctx, map[string][]byte{"1": []byte("b"), "2": []byte("b")}, 0)
+	if !ok["1"] || ok["2"] {
+		t.Fatalf("SetIfPresent = %v, want 1 only", ok)
+	}
+
+	ok, err := adaptor.CompareAndSwap(ctx, map[string]client.Swap{
+		"1": {Value: []byte("c"), Version: client.Version([]byte("b"))},
+		"2": {Value: []byte("c")},
+		"3": {Value: []byte("c"), Version: client.Version([]byte("x"))},
+	}, 0)
+	if err != nil {
+		panic(err)
+	}
+	if !ok["1"] || !ok["2"] || ok["3"] {
+		t.Fatalf("CompareAndSwap = %v, want 1 and 2", ok)
+	}
+	ok, _ = adaptor.CompareAndSwap(ctx, map[string]client.Swap{"1": {Value: []byte("d"), Version: client.Version([]byte("b"))}}, 0)
+	if ok["1"] {
+		t.Fatalf("CompareAndSwap with stale version = %v, want false", ok)
+	}
+
+	if _, err = adaptor.DelPrefix(ctx, "1"); err != client.ErrNotSupported {
+		t.Fatalf("err = %v, want ErrNotSupported", err)
+	}
+}
+
+func TestRing(t *testing.T) {
+	r := newRing([]string{"a:1", "b:1", "c:1"}, 160)
+	before := make(map[string]string)
+	for i := 0; i < 1000; i++ {
+		key := strconv.Itoa(i)
+		before[key] = r.pick(key)
+	}
+
+	// 增加一个地址，只有分配到新地址的key移动
+	r = newRing([]string{"a:1", "b:1", "c:1", "d:1"}, 160)
+	moved := 0
+	for key, addr := range before {
+		if now := r.pick(key); now != addr {
+			if now != "d:1" {
+				t.Fatalf("key %s moved from %s to %s", key, addr, now)
+			}
+			moved++
+		}
+	}
+	if moved == 0 || moved > 500 {
+		t.Fatalf("moved = %d, want about 250", moved)
+	}
+}
@@ -0,0 +1,49 @@
+package memcached
+
+import (
+	"hash/crc32"
+	"sort"
+	"strconv"
+)
+
+// ring 一致性哈希环，每个地址放置 replicas 个虚拟节点，增删地址时只有相邻区间的key移动
+type ring struct {
+	hashes []uint32
+	nodes  map[uint32]string
+}
+
+func newRing(servers []string, replicas int) *ring {
+	r := &ring{nodes: make(map[uint32]string, len(servers)*replicas)}
+	for _, addr := range servers {
+		for i := 0; i < replicas; i++ {
+			h := crc32.ChecksumIEEE([]byte(addr + "-" + strconv.Itoa(i)))
+			if _, ok := r.nodes[h]; ok {
+				continue // 哈希冲突时保留先放置的地址
+			}
+			r.nodes[h] = addr
+			r.hashes = append(r.hashes, h)
+		}
+	}
+	sort.Slice(r.hashes, func(i, j int) bool { return r.hashes[i] < r.hashes[j] })
+	return r
+}
+
+// pick 顺时针找到key所在的第一个虚拟节点
+func (r *ring) pick(key string) string {
+	h := crc32.ChecksumIEEE([]byte(key))
+	i := sort.Search(len(r.hashes), func(i int) bool { return r.hashes[i] >= h })
+	if i == len(r.hashes) {
+		i = 0
+	}
+	return r.nodes[r.hashes[i]]
+}
+
+// group 按地址分组
+func (r *ring) group(keys []string) map[string][]string {
+	out := make(map[string][]string)
+	for _, key := range keys {
+		addr := r.pick(key)
+		out[addr] = append(out[addr], key)
+	}
+	return out
+}
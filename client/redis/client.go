@@ -3,6 +3,7 @@ package redis
 import (
 	"context"
 	"fmt"
+	"github.com/PycMono/go-cache/client"
 	"github.com/redis/go-redis/v9"
 	"sync"
 	"time"
@@ -12,19 +13,25 @@ type Client struct {
 	redisClient redis.UniversalClient
 	conf        *Config
 	ctx         context.Context
+	cancel      context.CancelFunc // 关闭时取消ctx，通知监控协程退出
+	done        chan struct{}      // 监控协程退出信号
+	closed      bool
 	sm          sync.RWMutex
 }
 
 func NewRedisClient(conf *Config) (*Client, error) {
-	ctx := context.Background()
+	ctx, cancel := context.WithCancel(context.Background())
 	redisClient, err := connect(ctx, conf)
 	if err != nil {
+		cancel()
 		return nil, err
 	}
 
 	c := &Client{
 		conf:        conf,
 		ctx:         ctx,
+		cancel:      cancel,
+		done:        make(chan struct{}),
 		redisClient: redisClient, // 首次初始化不用加锁
 	}
 	go c.monitoring() // 监控
@@ -39,8 +46,36 @@ func (c *Client) GetRedisClient() redis.UniversalClient {
 	return c.redisClient
 }
 
-// monitoring 重连监控,无限循环，检查redis客服端是否断开连接，如果断开重新连接
+// getRedisClient 获取redis客户端，已关闭返回 client.ErrClosed
+func (c *Client) getRedisClient() (redis.UniversalClient, error) {
+	c.sm.RLock()
+	defer c.sm.RUnlock()
+
+	if c.closed {
+		return nil, client.ErrClosed
+	}
+	return c.redisClient, nil
+}
+
+// Close 停止重连监控并关闭redis连接，重复调用返回 client.ErrClosed
+func (c *Client) Close() error {
+	c.sm.Lock()
+	if c.closed {
+		c.sm.Unlock()
+		return client.ErrClosed
+	}
+	c.closed = true
+	c.sm.Unlock()
+
+	c.cancel()
+	<-c.done // 等待监控协程退出，避免和重连并发
+
+	return c.redisClient.Close()
+}
+
+// monitoring 重连监控,无限循环，检查redis客服端是否断开连接，如果断开重新连接，Close 后退出
 func (c *Client) monitoring() {
+	defer close(c.done)
 	defer func() {
 		if err := recover(); err != nil {
 			fmt.Println(err)
@@ -48,9 +83,15 @@ func (c *Client) monitoring() {
 		}
 	}()
 
+	ticker := time.NewTicker(30 * time.Second)
+	defer ticker.Stop()
 	for {
 		// 先休眠30秒
-		time.Sleep(30 * time.Second)
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+		}
 		if c.ping() {
 			continue
 		}
@@ -61,7 +102,7 @@ func (c *Client) monitoring() {
 }
 
 func (c *Client) ping() bool {
-	_, err := c.redisClient.Ping(c.ctx).Result()
+	_, err := c.GetRedisClient().Ping(c.ctx).Result()
 	return err == nil
 }
 
@@ -70,13 +111,14 @@ func (c *Client) reConnect() {
 	redisClient, err := connect(c.ctx, c.conf)
 	if err != nil {
 		fmt.Println("redis 重连失败...")
+		return
 	}
 
-	// 尝试关闭历史连接
-	c.redisClient.Close()
-
 	c.sm.Lock()
 	defer c.sm.Unlock()
+
+	// 尝试关闭历史连接
+	c.redisClient.Close()
 	c.redisClient = redisClient
 }
 
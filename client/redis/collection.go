@@ -0,0 +1,266 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"github.com/PycMono/go-cache/client"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+// NewRedisCollectionAdaptor 使用 redis 的 LIST、SET、ZSET 保存集合
+// 写入操作不重试，重复写入会改变列表内容和分数；读取按 WithRetryPolicy 重试
+func NewRedisCollectionAdaptor(client *Client) client.ICollectionAdaptor {
+	return &Cache{
+		client: client,
+		retry:  client.conf.getRetryPolicy(),
+	}
+}
+
+func (r *Cache) LPush(ctx context.Context, key string, values [][]byte, expire time.Duration) (int64, error) {
+	return r.write(ctx, key, expire, func(pipe redis.Pipeliner) *redis.IntCmd {
+		return pipe.LPush(ctx, key, toArgs(values)...)
+	})
+}
+
+func (r *Cache) RPush(ctx context.Context, key string, values [][]byte, expire time.Duration) (int64, error) {
+	return r.write(ctx, key, expire, func(pipe redis.Pipeliner) *redis.IntCmd {
+		return pipe.RPush(ctx, key, toArgs(values)...)
+	})
+}
+
+func (r *Cache) LRange(ctx context.Context, key string, start, stop int64) ([][]byte, error) {
+	var out [][]byte
+	err := r.retry.Do(ctx, func() error {
+		redisClient, err := r.client.getRedisClient()
+		if err != nil {
+			return err
+		}
+		values, err := redisClient.LRange(ctx, key, start, stop).Result()
+		out = toBytes(values)
+		return err
+	})
+	return out, err
+}
+
+func (r *Cache) LTrim(ctx context.Context, key string, start, stop int64) error {
+	return r.retry.Do(ctx, func() error {
+		redisClient, err := r.client.getRedisClient()
+		if err != nil {
+			return err
+		}
+		return redisClient.LTrim(ctx, key, start, stop).Err()
+	})
+}
+
+func (r *Cache) LLen(ctx context.Context, key string) (int64, error) {
+	return r.count(ctx, func(redisClient redis.UniversalClient) *redis.IntCmd {
+		return redisClient.LLen(ctx, key)
+	})
+}
+
+func (r *Cache) SAdd(ctx context.Context, key string, members [][]byte, expire time.Duration) (int64, error) {
+	return r.write(ctx, key, expire, func(pipe redis.Pipeliner) *redis.IntCmd {
+		return pipe.SAdd(ctx, key, toArgs(members)...)
+	})
+}
+
+func (r *Cache) SRem(ctx context.Context, key string, members [][]byte) (int64, error) {
+	return r.write(ctx, key, 0, func(pipe redis.Pipeliner) *redis.IntCmd {
+		return pipe.SRem(ctx, key, toArgs(members)...)
+	})
+}
+
+func (r *Cache) SIsMember(ctx context.Context, key string, members [][]byte) ([]bool, error) {
+	var out []bool
+	err := r.retry.Do(ctx, func() (err error) {
+		redisClient, err := r.client.getRedisClient()
+		if err != nil {
+			return err
+		}
+		out, err = redisClient.SMIsMember(ctx, key, toArgs(members)...).Result()
+		return err
+	})
+	return out, err
+}
+
+func (r *Cache) SMembers(ctx context.Context, key string) ([][]byte, error) {
+	var out [][]byte
+	err := r.retry.Do(ctx, func() error {
+		redisClient, err := r.client.getRedisClient()
+		if err != nil {
+			return err
+		}
+		values, err := redisClient.SMembers(ctx, key).Result()
+		out = toBytes(values)
+		return err
+	})
+	return out, err
+}
+
+func (r *Cache) SCard(ctx context.Context, key string) (int64, error) {
+	return r.count(ctx, func(redisClient redis.UniversalClient) *redis.IntCmd {
+		return redisClient.SCard(ctx, key)
+	})
+}
+
+func (r *Cache) ZAdd(ctx context.Context, key string, members []client.Z, expire time.Duration) (int64, error) {
+	return r.write(ctx, key, expire, func(pipe redis.Pipeliner) *redis.IntCmd {
+		zs := make([]redis.Z, len(members))
+		for i, m := range members {
+			zs[i] = redis.Z{Score: m.Score, Member: m.Member}
+		}
+		return pipe.ZAdd(ctx, key, zs...)
+	})
+}
+
+func (r *Cache) ZIncrBy(ctx context.Context, key string, member []byte, incr float64, expire time.Duration) (float64, error) {
+	redisClient, err := r.client.getRedisClient()
+	if err != nil {
+		return 0, err
+	}
+
+	var cmd *redis.FloatCmd
+	_, err = redisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		cmd = pipe.ZIncrBy(ctx, key, incr, string(member))
+		if expire > 0 {
+			pipe.PExpire(ctx, key, expire)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return cmd.Val(), nil
+}
+
+func (r *Cache) ZRem(ctx context.Context, key string, members [][]byte) (int64, error) {
+	return r.write(ctx, key, 0, func(pipe redis.Pipeliner) *redis.IntCmd {
+		return pipe.ZRem(ctx, key, toArgs(members)...)
+	})
+}
+
+func (r *Cache) ZRange(ctx context.Context, key string, start, stop int64, desc bool) ([]client.Z, error) {
+	var out []client.Z
+	err := r.retry.Do(ctx, func() error {
+		redisClient, err := r.client.getRedisClient()
+		if err != nil {
+			return err
+		}
+
+		zs, err := redisClient.ZRangeArgsWithScores(ctx, redis.ZRangeArgs{Key: key, Start: start, Stop: stop, Rev: desc}).Result()
+		if err != nil {
+			return err
+		}
+		out = make([]client.Z, len(zs))
+		for i, z := range zs {
+			member, _ := z.Member.(string)
+			out[i] = client.Z{Member: []byte(member), Score: z.Score}
+		}
+		return nil
+	})
+	return out, err
+}
+
+func (r *Cache) ZRank(ctx context.Context, key string, member []byte, desc bool) (int64, bool, error) {
+	var (
+		rank int64
+		ok   bool
+	)
+	err := r.retry.Do(ctx, func() error {
+		redisClient, err := r.client.getRedisClient()
+		if err != nil {
+			return err
+		}
+
+		cmd := redisClient.ZRank(ctx, key, string(member))
+		if desc {
+			cmd = redisClient.ZRevRank(ctx, key, string(member))
+		}
+		rank, err = cmd.Result()
+		ok = err == nil
+		if errors.Is(err, redis.Nil) {
+			return nil
+		}
+		return err
+	})
+	return rank, ok, err
+}
+
+func (r *Cache) ZScore(ctx context.Context, key string, member []byte) (float64, bool, error) {
+	var (
+		score float64
+		ok    bool
+	)
+	err := r.retry.Do(ctx, func() error {
+		redisClient, err := r.client.getRedisClient()
+		if err != nil {
+			return err
+		}
+
+		score, err = redisClient.ZScore(ctx, key, string(member)).Result()
+		ok = err == nil
+		if errors.Is(err, redis.Nil) {
+			return nil
+		}
+		return err
+	})
+	return score, ok, err
+}
+
+func (r *Cache) ZCard(ctx context.Context, key string) (int64, error) {
+	return r.count(ctx, func(redisClient redis.UniversalClient) *redis.IntCmd {
+		return redisClient.ZCard(ctx, key)
+	})
+}
+
+// write 同一个pipeline中执行写入命令和 PEXPIRE，不重试
+func (r *Cache) write(ctx context.Context, key string, expire time.Duration, f func(pipe redis.Pipeliner) *redis.IntCmd) (int64, error) {
+	redisClient, err := r.client.getRedisClient()
+	if err != nil {
+		return 0, err
+	}
+
+	var cmd *redis.IntCmd
+	_, err = redisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		cmd = f(pipe)
+		if expire > 0 {
+			pipe.PExpire(ctx, key, expire)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return cmd.Val(), nil
+}
+
+// count 执行返回整数的只读命令
+func (r *Cache) count(ctx context.Context, f func(redisClient redis.UniversalClient) *redis.IntCmd) (int64, error) {
+	var n int64
+	err := r.retry.Do(ctx, func() error {
+		redisClient, err := r.client.getRedisClient()
+		if err != nil {
+			return err
+		}
+		n, err = f(redisClient).Result()
+		return err
+	})
+	return n, err
+}
+
+func toArgs(values [][]byte) []interface{} {
+	args := make([]interface{}, len(values))
+	for i, v := range values {
+		args[i] = v
+	}
+	return args
+}
+
+func toBytes(values []string) [][]byte {
+	out := make([][]byte, len(values))
+	for i, v := range values {
+		out[i] = []byte(v)
+	}
+	return out
+}
@@ -2,20 +2,24 @@ package redis
 
 import (
 	"fmt"
+	"github.com/PycMono/go-cache/client"
 	"github.com/redis/go-redis/v9"
 	"time"
 )
 
 // Config 配置文件
 type Config struct {
-	name         string        // app name
-	addr         string        // redis addr，例如 127.0.0.1:6379
-	password     string        // redis password
-	db           int           // redis db
-	poolSize     int           // redis pool size
-	poolTimeout  time.Duration // redis 超时（单位秒,默认为0）
-	readTimeout  time.Duration // redis 超时（单位秒,默认为0）
-	writeTimeout time.Duration // redis 超时（单位秒,默认为0）
+	name         string              // app name
+	addr         string              // redis addr，例如 127.0.0.1:6379
+	password     string              // redis password
+	db           int                 // redis db
+	poolSize     int                 // redis pool size
+	poolTimeout  time.Duration       // redis 超时（单位秒,默认为0）
+	readTimeout  time.Duration       // redis 超时（单位秒,默认为0）
+	writeTimeout time.Duration       // redis 超时（单位秒,默认为0）
+	retryPolicy  *client.RetryPolicy // 适配器 Get/Set/Del 的重试策略，默认 client.DefaultRetryPolicy
+	scanCount    int64               // Scan、DelPrefix 每次 SCAN 的 COUNT（默认1000）
+	deleteRate   int                 // DelPrefix 每秒最多删除的key数量，默认不限制，避免大量删除影响redis延迟
 }
 
 func (c Config) WithName(name string) Config {
@@ -58,6 +62,40 @@ func (c Config) WithWriteTimeout(writeTimeout time.Duration) Config {
 	return c
 }
 
+// WithRetryPolicy 设置适配器的重试策略，传入 client.RetryPolicy{} 关闭重试
+func (c Config) WithRetryPolicy(retryPolicy client.RetryPolicy) Config {
+	c.retryPolicy = &retryPolicy
+	return c
+}
+
+// WithScanCount 设置每次 SCAN 的 COUNT
+func (c Config) WithScanCount(scanCount int64) Config {
+	c.scanCount = scanCount
+	return c
+}
+
+// WithDeleteRate 限制 DelPrefix 每秒删除的key数量
+func (c Config) WithDeleteRate(deleteRate int) Config {
+	c.deleteRate = deleteRate
+	return c
+}
+
+// getScanCount 获取 SCAN 的 COUNT，未设置使用1000
+func (c *Config) getScanCount() int64 {
+	if c.scanCount <= 0 {
+		return 1000
+	}
+	return c.scanCount
+}
+
+// getRetryPolicy 获取重试策略，未设置使用默认策略
+func (c *Config) getRetryPolicy() client.RetryPolicy {
+	if c.retryPolicy == nil {
+		return client.DefaultRetryPolicy()
+	}
+	return *c.retryPolicy
+}
+
 func (c Config) build() (*redis.Options, error) {
 	if len(c.name) == 0 {
 		return nil, fmt.Errorf("name为空")
@@ -4,26 +4,325 @@ import (
 	"context"
 	"errors"
 	"github.com/PycMono/go-cache/client"
-	"github.com/avast/retry-go"
 	"github.com/redis/go-redis/v9"
+	"net"
 	"time"
 )
 
 type Cache struct {
 	client *Client
+	retry  client.RetryPolicy // 重试策略，遵循ctx取消
 }
 
 func NewRedisAdaptor(client *Client) client.IAdaptor {
-	return &Cache{client: client}
+	return &Cache{
+		client: client,
+		retry:  client.conf.getRetryPolicy(),
+	}
 }
 
 func (r *Cache) Set(ctx context.Context, params map[string][]byte, expire time.Duration) error {
+	return r.retry.Do(ctx, func() error {
+		return r.set(ctx, params, expire)
+	})
+}
+
+func (r *Cache) Del(ctx context.Context, k []string) error {
+	return r.retry.Do(ctx, func() error {
+		redisClient, err := r.client.getRedisClient()
+		if err != nil {
+			return err
+		}
+		return redisClient.Del(ctx, k...).Err()
+	})
+}
+
+func (r *Cache) Get(ctx context.Context, k []string) (map[string][]byte, error) {
+	var out map[string][]byte
+	err := r.retry.Do(ctx, func() (err error) {
+		out, err = r.get(ctx, k)
+		return err
+	})
+	return out, err
+}
+
+func (r *Cache) GetWithTTL(ctx context.Context, k []string) (map[string]client.Item, error) {
+	var out map[string]client.Item
+	err := r.retry.Do(ctx, func() (err error) {
+		out, err = r.getWithTTL(ctx, k)
+		return err
+	})
+	return out, err
+}
+
+func (r *Cache) TTL(ctx context.Context, k []string) (map[string]time.Duration, error) {
+	var out map[string]time.Duration
+	err := r.retry.Do(ctx, func() (err error) {
+		out, err = r.ttl(ctx, k)
+		return err
+	})
+	return out, err
+}
+
+// Expire 使用 PEXPIRE 重新设置过期时间，expire 小于等于0时使用 PERSIST 去掉过期时间
+func (r *Cache) Expire(ctx context.Context, k []string, expire time.Duration) error {
+	return r.retry.Do(ctx, func() error {
+		redisClient, err := r.client.getRedisClient()
+		if err != nil {
+			return err
+		}
+
+		_, err = redisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for _, key := range k {
+				if expire > 0 {
+					pipe.PExpire(ctx, key, expire)
+				} else {
+					pipe.Persist(ctx, key)
+				}
+			}
+			return nil
+		})
+		return err
+	})
+}
+
+// SetIfAbsent 使用 SET NX，条件写入结果不确定时重试会误判，不重试
+func (r *Cache) SetIfAbsent(ctx context.Context, params map[string][]byte, expire time.Duration) (map[string]bool, error) {
+	return r.setIf(ctx, params, func(pipe redis.Pipeliner, key string, value []byte) *redis.BoolCmd {
+		return pipe.SetNX(ctx, key, value, expire)
+	})
+}
+
+// SetIfPresent 使用 SET XX，不重试
+func (r *Cache) SetIfPresent(ctx context.Context, params map[string][]byte, expire time.Duration) (map[string]bool, error) {
+	return r.setIf(ctx, params, func(pipe redis.Pipeliner, key string, value []byte) *redis.BoolCmd {
+		return pipe.SetXX(ctx, key, value, expire)
+	})
+}
+
+// IncrBy 同一个pipeline中先 SET NX 0 设置过期时间再 INCRBY，已存在的key不修改过期时间，不重试
+// 出错时返回 *client.IncrError，读取响应超时等无法确定是否执行的key不放入 NotApplied
+func (r *Cache) IncrBy(ctx context.Context, params map[string]int64, expire time.Duration) (map[string]int64, error) {
+	redisClient, err := r.client.getRedisClient()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		pipe = redisClient.Pipeline()
+		cmds = make(map[string]*redis.IntCmd, len(params))
+	)
+	for key, delta := range params {
+		if expire > 0 {
+			pipe.SetNX(ctx, key, 0, expire)
+		}
+		cmds[key] = pipe.IncrBy(ctx, key, delta)
+	}
+	// ctx 已经结束时 go-redis 在获取连接时就返回，命令没有发出
+	if err = ctx.Err(); err != nil {
+		return nil, client.NotAppliedError(nil, params, err)
+	}
+	_, _ = pipe.Exec(ctx) // 每个命令的错误在下面逐个判断
+
+	var (
+		out     = make(map[string]int64, len(params))
+		incrErr *client.IncrError
+	)
+	for key, cmd := range cmds {
+		n, err := cmd.Result()
+		if err == nil {
+			out[key] = n
+			continue
+		}
+		if incrErr == nil {
+			incrErr = &client.IncrError{Applied: out, Err: err}
+		}
+		if notSent(err) {
+			incrErr.NotApplied = append(incrErr.NotApplied, key)
+		}
+	}
+	if incrErr != nil {
+		return nil, incrErr
+	}
+	return out, nil
+}
+
+// notSent 命令确认没有执行：redis 返回了错误响应，或者连接没有建立
+// 其它网络错误（例如读取响应超时）无法确定命令是否已经执行
+func notSent(err error) bool {
+	var (
+		redisErr redis.Error
+		opErr    *net.OpError
+	)
+	if errors.As(err, &redisErr) {
+		return true
+	}
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// HSet 同一个pipeline中执行 HSET 和 PEXPIRE，expire 小于等于0时不修改过期时间
+func (r *Cache) HSet(ctx context.Context, params map[string]map[string][]byte, expire time.Duration) error {
+	return r.retry.Do(ctx, func() error {
+		redisClient, err := r.client.getRedisClient()
+		if err != nil {
+			return err
+		}
+
+		_, err = redisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for key, fields := range params {
+				values := make([]interface{}, 0, 2*len(fields))
+				for f, v := range fields {
+					values = append(values, f, v)
+				}
+				pipe.HSet(ctx, key, values...)
+				if expire > 0 {
+					pipe.PExpire(ctx, key, expire)
+				}
+			}
+			return nil
+		})
+		return err
+	})
+}
+
+// HGet fields 为空时使用 HGETALL，否则使用 HMGET
+func (r *Cache) HGet(ctx context.Context, k []string, fields []string) (map[string]map[string][]byte, error) {
+	var out map[string]map[string][]byte
+	err := r.retry.Do(ctx, func() (err error) {
+		out, err = r.hget(ctx, k, fields)
+		return err
+	})
+	return out, err
+}
+
+func (r *Cache) hget(ctx context.Context, k []string, fields []string) (map[string]map[string][]byte, error) {
+	redisClient, err := r.client.getRedisClient()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		pipe    = redisClient.Pipeline()
+		allCmds = make([]*redis.MapStringStringCmd, len(k))
+		cmds    = make([]*redis.SliceCmd, len(k))
+	)
+	for i, key := range k {
+		if len(fields) == 0 {
+			allCmds[i] = pipe.HGetAll(ctx, key)
+		} else {
+			cmds[i] = pipe.HMGet(ctx, key, fields...)
+		}
+	}
+	_, err = pipe.Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]map[string][]byte)
+	for i, key := range k {
+		values := make(map[string][]byte)
+		if len(fields) == 0 {
+			for f, v := range allCmds[i].Val() {
+				values[f] = []byte(v)
+			}
+		} else {
+			for j, v := range cmds[i].Val() {
+				if s, ok := v.(string); ok {
+					values[fields[j]] = []byte(s)
+				}
+			}
+		}
+		if len(values) > 0 {
+			out[key] = values
+		}
+	}
+	return out, nil
+}
+
+// casScript 比较当前数据的sha1和期望的版本号，一致时写入，ARGV[1] 为空表示期望key不存在
+var casScript = redis.NewScript(`
+local cur = redis.call('GET', KEYS[1])
+if ARGV[1] == '' then
+	if cur then return 0 end
+elseif not cur or redis.sha1hex(cur) ~= ARGV[1] then
+	return 0
+end
+if tonumber(ARGV[3]) > 0 then
+	redis.call('SET', KEYS[1], ARGV[2], 'PX', ARGV[3])
+else
+	redis.call('SET', KEYS[1], ARGV[2])
+end
+return 1
+`)
+
+// CompareAndSwap 每个key执行一次lua脚本，脚本只操作一个key，集群模式下同样可用，不重试
+func (r *Cache) CompareAndSwap(ctx context.Context, params map[string]client.Swap, expire time.Duration) (map[string]bool, error) {
+	redisClient, err := r.client.getRedisClient()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		pipe = redisClient.Pipeline()
+		cmds = make(map[string]*redis.Cmd, len(params))
+	)
+	for key, v := range params {
+		cmds[key] = casScript.Eval(ctx, pipe, []string{key}, v.Version, v.Value, expire.Milliseconds())
+	}
+	_, err = pipe.Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]bool, len(params))
+	for key, cmd := range cmds {
+		n, _ := cmd.Int64()
+		out[key] = n == 1
+	}
+	return out, nil
+}
+
+// setIf 在同一个pipeline中执行条件写入命令
+func (r *Cache) setIf(ctx context.Context, params map[string][]byte, f func(pipe redis.Pipeliner, key string, value []byte) *redis.BoolCmd) (map[string]bool, error) {
+	redisClient, err := r.client.getRedisClient()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		pipe = redisClient.Pipeline()
+		cmds = make(map[string]*redis.BoolCmd, len(params))
+	)
+	for key, value := range params {
+		cmds[key] = f(pipe, key, value)
+	}
+	_, err = pipe.Exec(ctx)
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+
+	out := make(map[string]bool, len(params))
+	for key, cmd := range cmds {
+		if err = cmd.Err(); err != nil && !errors.Is(err, redis.Nil) {
+			return nil, err
+		}
+		out[key] = cmd.Val()
+	}
+	return out, nil
+}
+
+func (r *Cache) set(ctx context.Context, params map[string][]byte, expire time.Duration) error {
+	redisClient, err := r.client.getRedisClient()
+	if err != nil {
+		return err
+	}
+
 	m := make(map[string]interface{})
 	for k, v := range params {
 		m[k] = string(v)
 	}
 
-	_, err := r.client.GetRedisClient().Pipelined(ctx, func(pipe redis.Pipeliner) error {
+	_, err = redisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
 		for key, value := range m {
 			err := pipe.Set(ctx, key, value, expire).Err()
 			if err != nil {
@@ -35,22 +334,13 @@ func (r *Cache) Set(ctx context.Context, params map[string][]byte, expire time.D
 	return err
 }
 
-func (r *Cache) Del(ctx context.Context, k []string) error {
-	return retry.Do(
-		func() error {
-			out := r.client.GetRedisClient().Del(ctx, k...)
-			return out.Err()
-		},
-		retry.RetryIf(func(err error) bool {
-			return err != nil
-		}),
-		retry.Delay(3*time.Second),
-		retry.Attempts(3),
-	)
-}
+func (r *Cache) get(ctx context.Context, k []string) (map[string][]byte, error) {
+	redisClient, err := r.client.getRedisClient()
+	if err != nil {
+		return nil, err
+	}
 
-func (r *Cache) Get(ctx context.Context, k []string) (map[string][]byte, error) {
-	pipe := r.client.GetRedisClient().Pipeline()
+	pipe := redisClient.Pipeline()
 	for _, key := range k {
 		_, _ = pipe.Get(ctx, key).Result()
 	}
@@ -79,3 +369,85 @@ func (r *Cache) Get(ctx context.Context, k []string) (map[string][]byte, error)
 	}
 	return out, nil
 }
+
+// getWithTTL 同一个pipeline中执行 GET 和 PTTL
+func (r *Cache) getWithTTL(ctx context.Context, k []string) (map[string]client.Item, error) {
+	redisClient, err := r.client.getRedisClient()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		pipe     = redisClient.Pipeline()
+		getCmds  = make([]*redis.StringCmd, len(k))
+		pttlCmds = make([]*redis.DurationCmd, len(k))
+	)
+	for i, key := range k {
+		getCmds[i] = pipe.Get(ctx, key)
+		pttlCmds[i] = pipe.PTTL(ctx, key)
+	}
+	_, err = pipe.Exec(ctx)
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+
+	out := make(map[string]client.Item)
+	for i, key := range k {
+		val, err := getCmds[i].Bytes()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		ttl, ok := pttl(pttlCmds[i].Val())
+		if !ok {
+			continue // GET 和 PTTL 之间过期
+		}
+		out[key] = client.Item{Value: val, TTL: ttl}
+	}
+	return out, nil
+}
+
+func (r *Cache) ttl(ctx context.Context, k []string) (map[string]time.Duration, error) {
+	redisClient, err := r.client.getRedisClient()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		pipe = redisClient.Pipeline()
+		cmds = make([]*redis.DurationCmd, len(k))
+	)
+	for i, key := range k {
+		cmds[i] = pipe.PTTL(ctx, key)
+	}
+	_, err = pipe.Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]time.Duration)
+	for i, key := range k {
+		if ttl, ok := pttl(cmds[i].Val()); ok {
+			out[key] = ttl
+		}
+	}
+	return out, nil
+}
+
+// pttl 转换 PTTL 的返回值，-2 表示key不存在，-1 表示不过期（go-redis 对这两个值不做单位换算）
+func pttl(d time.Duration) (time.Duration, bool) {
+	switch d {
+	case -2:
+		return 0, false
+	case -1:
+		return client.NoExpire, true
+	}
+	return d, true
+}
+
+// Close 关闭底层的redis客户端
+func (r *Cache) Close() error {
+	return r.client.Close()
+}
@@ -0,0 +1,124 @@
+package redis
+
+import (
+	"context"
+	"github.com/PycMono/go-cache/client"
+	"github.com/redis/go-redis/v9"
+	"sync"
+	"time"
+)
+
+// Scan 使用 SCAN 游标遍历，集群模式下依次遍历每个主节点
+func (r *Cache) Scan(ctx context.Context, pattern string) client.KeyIterator {
+	redisClient, err := r.client.getRedisClient()
+	if err != nil {
+		return client.ErrIterator(err)
+	}
+	nodes, err := masters(ctx, redisClient)
+	if err != nil {
+		return client.ErrIterator(err)
+	}
+
+	var (
+		count  = r.client.conf.getScanCount()
+		node   int
+		cursor uint64
+	)
+	return client.NewKeyIterator(func(ctx context.Context) ([]string, bool, error) {
+		var (
+			keys []string
+			next uint64
+		)
+		// 失败的尝试不修改游标，重试从同一个位置开始
+		err := r.retry.Do(ctx, func() (err error) {
+			keys, next, err = nodes[node].Scan(ctx, cursor, pattern, count).Result()
+			return err
+		})
+		if err != nil {
+			return nil, true, err
+		}
+		cursor = next
+		if cursor == 0 {
+			node++ // 当前节点遍历完成
+		}
+		return keys, node == len(nodes), nil
+	})
+}
+
+// DelPrefix 按 SCAN 的批次使用 UNLINK 删除，每个key单独一条命令放在同一个pipeline中，集群模式下按slot路由
+// 每一批 SCAN 和 UNLINK 使用 client.BatchContext 计时，设置了 WithDeleteRate 时每批删除后按速率等待
+func (r *Cache) DelPrefix(ctx context.Context, prefix string) (int64, error) {
+	var (
+		n    int64
+		rate = r.client.conf.deleteRate
+		it   = r.Scan(ctx, client.EscapePattern(prefix)+"*")
+	)
+	for {
+		batchCtx, cancel := client.BatchContext(ctx)
+		if !it.Next(batchCtx) {
+			cancel()
+			break
+		}
+		keys := it.Keys()
+		deleted, err := r.unlink(batchCtx, keys)
+		cancel()
+		n += deleted
+		if err != nil {
+			return n, err
+		}
+
+		if rate > 0 {
+			wait := time.Duration(len(keys)) * time.Second / time.Duration(rate)
+			select {
+			case <-ctx.Done():
+				return n, ctx.Err()
+			case <-time.After(wait):
+			}
+		}
+	}
+	return n, it.Err()
+}
+
+func (r *Cache) unlink(ctx context.Context, keys []string) (int64, error) {
+	redisClient, err := r.client.getRedisClient()
+	if err != nil {
+		return 0, err
+	}
+
+	var cmds []*redis.IntCmd
+	_, err = redisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, key := range keys {
+			cmds = append(cmds, pipe.Unlink(ctx, key))
+		}
+		return nil
+	})
+
+	var n int64
+	for _, cmd := range cmds {
+		n += cmd.Val()
+	}
+	return n, err
+}
+
+// masters 集群模式返回所有主节点，否则返回客户端本身
+func masters(ctx context.Context, redisClient redis.UniversalClient) ([]redis.Cmdable, error) {
+	cluster, ok := redisClient.(*redis.ClusterClient)
+	if !ok {
+		return []redis.Cmdable{redisClient}, nil
+	}
+
+	var (
+		sm    sync.Mutex
+		nodes []redis.Cmdable
+	)
+	err := cluster.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+		sm.Lock()
+		defer sm.Unlock()
+		nodes = append(nodes, node)
+		return nil
+	})
+	if len(nodes) == 0 && err == nil {
+		return nil, client.ErrClosed
+	}
+	return nodes, err
+}
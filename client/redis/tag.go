@@ -0,0 +1,102 @@
+package redis
+
+import (
+	"context"
+	"github.com/redis/go-redis/v9"
+	"strconv"
+	"time"
+)
+
+// tagScript 标签索引使用有序集合，成员为key，分数为key的过期时间（毫秒时间戳），不过期为 +inf
+// 每次写入先删除已过期的key，索引自身的过期时间不短于其中最晚过期的key
+// KEYS[1] 标签，ARGV[1] 当前时间，ARGV[2] 过期时间，ARGV[3:] key
+var tagScript = redis.NewScript(`
+local existed = redis.call('EXISTS', KEYS[1])
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', ARGV[1])
+for i = 3, #ARGV do
+	redis.call('ZADD', KEYS[1], ARGV[2], ARGV[i])
+end
+if ARGV[2] == '+inf' then
+	redis.call('PERSIST', KEYS[1])
+	return 1
+end
+local want = tonumber(ARGV[2]) - tonumber(ARGV[1])
+local ttl = redis.call('PTTL', KEYS[1])
+if existed == 0 or (ttl >= 0 and want > ttl) then
+	redis.call('PEXPIRE', KEYS[1], want)
+end
+return 1
+`)
+
+// AddTags 每个标签执行一次lua脚本，脚本只操作一个key，集群模式下同样可用
+func (r *Cache) AddTags(ctx context.Context, params map[string][]string, expire time.Duration) error {
+	keys := make(map[string][]interface{})
+	for key, tags := range params {
+		for _, tag := range tags {
+			keys[tag] = append(keys[tag], key)
+		}
+	}
+
+	return r.retry.Do(ctx, func() error {
+		redisClient, err := r.client.getRedisClient()
+		if err != nil {
+			return err
+		}
+
+		var (
+			now      = time.Now()
+			expireAt = "+inf"
+		)
+		if expire > 0 {
+			expireAt = strconv.FormatInt(now.Add(expire).UnixMilli(), 10)
+		}
+		pipe := redisClient.Pipeline()
+		for tag, members := range keys {
+			args := append([]interface{}{now.UnixMilli(), expireAt}, members...)
+			tagScript.Eval(ctx, pipe, []string{tag}, args...)
+		}
+		_, err = pipe.Exec(ctx)
+		return err
+	})
+}
+
+// TagKeys 使用 ZRANGEBYSCORE 只返回未过期的key
+func (r *Cache) TagKeys(ctx context.Context, tags []string) ([]string, error) {
+	var out []string
+	err := r.retry.Do(ctx, func() error {
+		redisClient, err := r.client.getRedisClient()
+		if err != nil {
+			return err
+		}
+
+		var (
+			pipe = redisClient.Pipeline()
+			cmds = make([]*redis.StringSliceCmd, len(tags))
+			from = "(" + strconv.FormatInt(time.Now().UnixMilli(), 10)
+		)
+		for i, tag := range tags {
+			cmds[i] = pipe.ZRangeByScore(ctx, tag, &redis.ZRangeBy{Min: from, Max: "+inf"})
+		}
+		_, err = pipe.Exec(ctx)
+		if err != nil {
+			return err
+		}
+
+		out = nil
+		seen := make(map[string]struct{})
+		for _, cmd := range cmds {
+			for _, key := range cmd.Val() {
+				if _, ok := seen[key]; !ok {
+					seen[key] = struct{}{}
+					out = append(out, key)
+				}
+			}
+		}
+		return nil
+	})
+	return out, err
+}
+
+func (r *Cache) DelTags(ctx context.Context, tags []string) error {
+	return r.Del(ctx, tags)
+}
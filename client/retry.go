@@ -0,0 +1,139 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy 重试策略，零值表示不重试
+type RetryPolicy struct {
+	maxAttempts    int                  // 最大尝试次数（包含第一次），小于等于1不重试
+	initialBackoff time.Duration        // 第一次重试前的等待时间
+	maxBackoff     time.Duration        // 等待时间上限，0不限制
+	multiplier     float64              // 每次重试等待时间的增长倍数，小于1按1处理
+	jitter         float64              // 随机抖动比例，取值[0, 1]，例如0.2表示等待时间在±20%范围内浮动
+	maxElapsed     time.Duration        // 从第一次调用开始的总耗时上限，超过后不再重试，0不限制
+	retryable      func(err error) bool // 判断错误是否可以重试，默认 IsRetryable
+}
+
+// DefaultRetryPolicy 默认重试策略：最多3次，50ms开始指数退避，最长等待1秒，±20%抖动
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{}.
+		WithMaxAttempts(3).
+		WithBackoff(50*time.Millisecond, time.Second, 2).
+		WithJitter(0.2)
+}
+
+func (p RetryPolicy) WithMaxAttempts(maxAttempts int) RetryPolicy {
+	p.maxAttempts = maxAttempts
+	return p
+}
+
+func (p RetryPolicy) WithBackoff(initialBackoff, maxBackoff time.Duration, multiplier float64) RetryPolicy {
+	p.initialBackoff = initialBackoff
+	p.maxBackoff = maxBackoff
+	p.multiplier = multiplier
+	return p
+}
+
+func (p RetryPolicy) WithJitter(jitter float64) RetryPolicy {
+	p.jitter = jitter
+	return p
+}
+
+func (p RetryPolicy) WithMaxElapsed(maxElapsed time.Duration) RetryPolicy {
+	p.maxElapsed = maxElapsed
+	return p
+}
+
+func (p RetryPolicy) WithRetryable(retryable func(err error) bool) RetryPolicy {
+	p.retryable = retryable
+	return p
+}
+
+// Do 按策略执行f，直到成功、错误不可重试、次数或耗时用完、ctx结束
+// ctx结束时返回的错误同时包含 ctx.Err() 和最后一次调用的错误
+func (p RetryPolicy) Do(ctx context.Context, f func() error) error {
+	var (
+		start   = time.Now()
+		backoff = p.initialBackoff
+	)
+	for attempt := 1; ; attempt++ {
+		err := f()
+		if err == nil || attempt >= p.maxAttempts || ctx.Err() != nil || !p.isRetryable(err) {
+			return err
+		}
+
+		wait := p.withJitter(backoff)
+		if p.maxElapsed > 0 && time.Since(start)+wait > p.maxElapsed {
+			return err
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(ctx.Err(), err)
+		case <-timer.C:
+		}
+
+		backoff = time.Duration(float64(backoff) * max(p.multiplier, 1))
+		if p.maxBackoff > 0 && backoff > p.maxBackoff {
+			backoff = p.maxBackoff
+		}
+	}
+}
+
+func (p RetryPolicy) isRetryable(err error) bool {
+	if p.retryable != nil {
+		return p.retryable(err)
+	}
+	return IsRetryable(err)
+}
+
+func (p RetryPolicy) withJitter(d time.Duration) time.Duration {
+	if p.jitter <= 0 || d <= 0 {
+		return d
+	}
+	delta := float64(d) * min(p.jitter, 1)
+	return time.Duration(float64(d) - delta + rand.Float64()*2*delta)
+}
+
+// retryablePrefixes redis 返回的临时性错误，集群迁移、主从切换、数据加载中等情况重试可恢复
+var retryablePrefixes = []string{"MOVED ", "ASK ", "TRYAGAIN ", "CLUSTERDOWN ", "LOADING ", "MASTERDOWN ", "READONLY "}
+
+// IsRetryable 默认的可重试错误判断：网络超时、连接重置/拒绝/断开，以及 redis 集群迁移等临时性错误
+// 调用方取消、ctx超时、适配器关闭、熔断打开不重试
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, ErrClosed) || errors.Is(err, ErrCircuitOpen) {
+		return false
+	}
+
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, os.ErrDeadlineExceeded) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	msg := err.Error()
+	for _, v := range retryablePrefixes {
+		if strings.HasPrefix(msg, v) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,150 @@
+package retry
+
+import (
+	"context"
+	"github.com/PycMono/go-cache/client"
+	"time"
+)
+
+// Cache 重试适配器，按 client.RetryPolicy 重试被包装适配器的读写操作
+// 条件写入（SetIfAbsent、SetIfPresent、CompareAndSwap）和计数 IncrBy 不重试：第一次可能已经写入成功，重试会得到错误的结果
+type Cache struct {
+	client.IAdaptor // 被包装的适配器，未覆盖的方法（如 Close）直接透传
+	policy          client.RetryPolicy
+}
+
+func NewRetryAdaptor(handler client.IAdaptor, policy client.RetryPolicy) client.IAdaptor {
+	return &Cache{
+		IAdaptor: handler,
+		policy:   policy,
+	}
+}
+
+func (r *Cache) Set(ctx context.Context, params map[string][]byte, expire time.Duration) error {
+	return r.policy.Do(ctx, func() error {
+		return r.IAdaptor.Set(ctx, params, expire)
+	})
+}
+
+func (r *Cache) Get(ctx context.Context, k []string) (map[string][]byte, error) {
+	var out map[string][]byte
+	err := r.policy.Do(ctx, func() (err error) {
+		out, err = r.IAdaptor.Get(ctx, k)
+		return err
+	})
+	return out, err
+}
+
+func (r *Cache) Del(ctx context.Context, k []string) error {
+	return r.policy.Do(ctx, func() error {
+		return r.IAdaptor.Del(ctx, k)
+	})
+}
+
+func (r *Cache) GetWithTTL(ctx context.Context, k []string) (map[string]client.Item, error) {
+	var out map[string]client.Item
+	err := r.policy.Do(ctx, func() (err error) {
+		out, err = r.IAdaptor.GetWithTTL(ctx, k)
+		return err
+	})
+	return out, err
+}
+
+func (r *Cache) TTL(ctx context.Context, k []string) (map[string]time.Duration, error) {
+	var out map[string]time.Duration
+	err := r.policy.Do(ctx, func() (err error) {
+		out, err = r.IAdaptor.TTL(ctx, k)
+		return err
+	})
+	return out, err
+}
+
+func (r *Cache) Expire(ctx context.Context, k []string, expire time.Duration) error {
+	return r.policy.Do(ctx, func() error {
+		return r.IAdaptor.Expire(ctx, k, expire)
+	})
+}
+
+// DelPrefix 删除是幂等的，失败后整体重试
+func (r *Cache) DelPrefix(ctx context.Context, prefix string) (int64, error) {
+	var n int64
+	err := r.policy.Do(ctx, func() (err error) {
+		n, err = r.IAdaptor.DelPrefix(ctx, prefix)
+		return err
+	})
+	return n, err
+}
+
+// Unwrap 返回被包装的适配器，用于 client.Supports 判断可选接口
+func (r *Cache) Unwrap() client.IAdaptor {
+	return r.IAdaptor
+}
+
+// IncrBy 直接透传不重试，被包装的适配器不支持计数时返回 client.ErrNotSupported
+func (r *Cache) IncrBy(ctx context.Context, params map[string]int64, expire time.Duration) (map[string]int64, error) {
+	counter, ok := r.IAdaptor.(client.ICounterAdaptor)
+	if !ok {
+		return nil, client.ErrNotSupported
+	}
+	return counter.IncrBy(ctx, params, expire)
+}
+
+// HSet 被包装的适配器不支持按字段读写时返回 client.ErrNotSupported
+func (r *Cache) HSet(ctx context.Context, params map[string]map[string][]byte, expire time.Duration) error {
+	hash, ok := r.IAdaptor.(client.IHashAdaptor)
+	if !ok {
+		return client.ErrNotSupported
+	}
+	return r.policy.Do(ctx, func() error {
+		return hash.HSet(ctx, params, expire)
+	})
+}
+
+func (r *Cache) HGet(ctx context.Context, k []string, fields []string) (map[string]map[string][]byte, error) {
+	hash, ok := r.IAdaptor.(client.IHashAdaptor)
+	if !ok {
+		return nil, client.ErrNotSupported
+	}
+
+	var out map[string]map[string][]byte
+	err := r.policy.Do(ctx, func() (err error) {
+		out, err = hash.HGet(ctx, k, fields)
+		return err
+	})
+	return out, err
+}
+
+// AddTags 被包装的适配器不支持标签时返回 client.ErrNotSupported
+func (r *Cache) AddTags(ctx context.Context, params map[string][]string, expire time.Duration) error {
+	tag, ok := r.IAdaptor.(client.ITagAdaptor)
+	if !ok {
+		return client.ErrNotSupported
+	}
+	return r.policy.Do(ctx, func() error {
+		return tag.AddTags(ctx, params, expire)
+	})
+}
+
+func (r *Cache) TagKeys(ctx context.Context, tags []string) ([]string, error) {
+	tag, ok := r.IAdaptor.(client.ITagAdaptor)
+	if !ok {
+		return nil, client.ErrNotSupported
+	}
+
+	var out []string
+	err := r.policy.Do(ctx, func() (err error) {
+		out, err = tag.TagKeys(ctx, tags)
+		return err
+	})
+	return out, err
+}
+
+func (r *Cache) DelTags(ctx context.Context, tags []string) error {
+	tag, ok := r.IAdaptor.(client.ITagAdaptor)
+	if !ok {
+		return client.ErrNotSupported
+	}
+	return r.policy.Do(ctx, func() error {
+		return tag.DelTags(ctx, tags)
+	})
+}
@@ -0,0 +1,58 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"github.com/PycMono/go-cache/client"
+	"syscall"
+	"testing"
+	"time"
+)
+
+type flakyAdaptor struct {
+	client.IAdaptor
+	errs  []error // 依次返回的错误，用完后返回成功
+	calls int
+}
+
+func (f *flakyAdaptor) Del(ctx context.Context, k []string) error {
+	f.calls++
+	if len(f.errs) == 0 {
+		return nil
+	}
+	err := f.errs[0]
+	f.errs = f.errs[1:]
+	return err
+}
+
+func TestRetry(t *testing.T) {
+	policy := client.RetryPolicy{}.
+		WithMaxAttempts(3).
+		WithBackoff(time.Millisecond, 10*time.Millisecond, 2).
+		WithJitter(0.2)
+
+	// 连接重置、集群迁移可以重试
+	handler := &flakyAdaptor{errs: []error{syscall.ECONNRESET, errors.New("MOVED 3999 127.0.0.1:6381")}}
+	err := NewRetryAdaptor(handler, policy).Del(context.TODO(), []string{"1"})
+	if err != nil || handler.calls != 3 {
+		t.Fatalf("err = %v, calls = %d, want success after 3 calls", err, handler.calls)
+	}
+
+	// 业务错误不重试
+	wrongType := errors.New("WRONGTYPE Operation against a key holding the wrong kind of value")
+	handler = &flakyAdaptor{errs: []error{wrongType}}
+	err = NewRetryAdaptor(handler, policy).Del(context.TODO(), []string{"1"})
+	if !errors.Is(err, wrongType) || handler.calls != 1 {
+		t.Fatalf("err = %v, calls = %d, want no retry", err, handler.calls)
+	}
+
+	// ctx 取消后不再等待
+	ctx, cancel := context.WithCancel(context.TODO())
+	handler = &flakyAdaptor{errs: []error{syscall.ECONNRESET, syscall.ECONNRESET}}
+	policy = policy.WithBackoff(time.Hour, time.Hour, 1)
+	time.AfterFunc(10*time.Millisecond, cancel)
+	err = NewRetryAdaptor(handler, policy).Del(ctx, []string{"1"})
+	if !errors.Is(err, context.Canceled) || !errors.Is(err, syscall.ECONNRESET) || handler.calls != 1 {
+		t.Fatalf("err = %v, calls = %d, want canceled", err, handler.calls)
+	}
+}
@@ -0,0 +1,201 @@
+package client
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// KeyIterator 逐批返回key的迭代器，用法：
+//
+//	for it.Next(ctx) {
+//		keys := it.Keys()
+//	}
+//	err := it.Err()
+//
+// 遍历不是快照，遍历期间写入或删除的key可能返回也可能不返回，同一个key可能返回多次
+type KeyIterator interface {
+	// Next 读取下一批key，没有更多数据或出错时返回false
+	Next(ctx context.Context) bool
+	// Keys 当前这一批key
+	Keys() []string
+	// Err 遍历过程中的错误
+	Err() error
+}
+
+// NewKeyIterator 使用 next 逐批读取key，next 返回 done 为true表示这是最后一批
+func NewKeyIterator(next func(ctx context.Context) (keys []string, done bool, err error)) KeyIterator {
+	return &keyIterator{next: next}
+}
+
+// ErrIterator 直接返回错误的迭代器
+func ErrIterator(err error) KeyIterator {
+	return &keyIterator{err: err, done: true}
+}
+
+type keyIterator struct {
+	next func(ctx context.Context) ([]string, bool, error)
+	keys []string
+	done bool
+	err  error
+}
+
+func (it *keyIterator) Next(ctx context.Context) bool {
+	for !it.done && it.err == nil {
+		if err := ctx.Err(); err != nil {
+			it.err = err
+			break
+		}
+
+		var keys []string
+		keys, it.done, it.err = it.next(ctx)
+		if it.err == nil && len(keys) > 0 {
+			it.keys = keys
+			return true
+		}
+	}
+	it.keys = nil
+	return false
+}
+
+func (it *keyIterator) Keys() []string {
+	return it.keys
+}
+
+func (it *keyIterator) Err() error {
+	return it.err
+}
+
+// MapKeys 对迭代器返回的每个key执行f，f 返回false的key丢弃
+func MapKeys(it KeyIterator, f func(key string) (string, bool)) KeyIterator {
+	return NewKeyIterator(func(ctx context.Context) ([]string, bool, error) {
+		if !it.Next(ctx) {
+			return nil, true, it.Err()
+		}
+		var out []string
+		for _, key := range it.Keys() {
+			if k, ok := f(key); ok {
+				out = append(out, k)
+			}
+		}
+		return out, false, nil
+	})
+}
+
+// SliceIterator 按批返回keys
+func SliceIterator(keys []string, batch int) KeyIterator {
+	return NewKeyIterator(func(ctx context.Context) ([]string, bool, error) {
+		n := min(batch, len(keys))
+		out := keys[:n]
+		keys = keys[n:]
+		return out, len(keys) == 0, nil
+	})
+}
+
+type batchTimeoutKey struct{}
+
+// WithBatchTimeout 设置 DelPrefix 每一批删除的超时时间，分批删除的适配器通过 BatchContext 对每一批单独计时
+// 删除的总耗时与key的数量有关，不适合整体使用一个超时时间
+func WithBatchTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	if timeout <= 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, batchTimeoutKey{}, timeout)
+}
+
+// BatchContext 派生一批删除（包括读取这一批key）使用的ctx，没有设置 WithBatchTimeout 时直接返回ctx
+func BatchContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	timeout, ok := ctx.Value(batchTimeoutKey{}).(time.Duration)
+	if !ok {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// EscapePattern 转义 glob 的特殊字符，用于把前缀拼接到 pattern 中
+func EscapePattern(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '*', '?', '[', ']', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// MatchPattern 判断key是否匹配 glob 模式，语义和 redis 的 SCAN MATCH 一致：
+// * 匹配任意个字符，? 匹配一个字符，[abc]、[^a]、[a-z] 匹配字符集合，\ 转义
+func MatchPattern(pattern, key string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for len(pattern) > 1 && pattern[1] == '*' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 1 {
+				return true
+			}
+			for i := 0; i <= len(key); i++ {
+				if MatchPattern(pattern[1:], key[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(key) == 0 {
+				return false
+			}
+			key = key[1:]
+			pattern = pattern[1:]
+		case '[':
+			if len(key) == 0 {
+				return false
+			}
+			var ok bool
+			if pattern, ok = matchClass(pattern[1:], key[0]); !ok {
+				return false
+			}
+			key = key[1:]
+		default:
+			if pattern[0] == '\\' && len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+			if len(key) == 0 || key[0] != pattern[0] {
+				return false
+			}
+			key = key[1:]
+			pattern = pattern[1:]
+		}
+	}
+	return len(key) == 0
+}
+
+// matchClass 匹配 [...] 字符集合，返回 ] 之后的pattern
+func matchClass(pattern string, c byte) (string, bool) {
+	not := len(pattern) > 0 && pattern[0] == '^'
+	if not {
+		pattern = pattern[1:]
+	}
+
+	matched := false
+	for len(pattern) > 0 && pattern[0] != ']' {
+		switch {
+		case pattern[0] == '\\' && len(pattern) > 1:
+			matched = matched || pattern[1] == c
+			pattern = pattern[2:]
+		case len(pattern) > 2 && pattern[1] == '-' && pattern[2] != ']':
+			lo, hi := min(pattern[0], pattern[2]), max(pattern[0], pattern[2])
+			matched = matched || (c >= lo && c <= hi)
+			pattern = pattern[3:]
+		default:
+			matched = matched || pattern[0] == c
+			pattern = pattern[1:]
+		}
+	}
+	if len(pattern) > 0 {
+		pattern = pattern[1:] // 跳过 ]
+	}
+	return pattern, matched != not
+}
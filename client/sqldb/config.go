@@ -0,0 +1,77 @@
+package sqldb
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// tableName 表名只允许字母、数字、下划线，可以带schema，例如 public.cache
+var tableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// Config 配置文件
+type Config struct {
+	db            *sql.DB       // 数据库连接，由调用方创建和关闭
+	dialect       Dialect       // 数据库类型
+	table         string        // 表名（默认cache）
+	batchSize     int           // 每条 SQL 最多包含的key数量（默认500）
+	purgeInterval time.Duration // 清理过期数据的间隔（默认1分钟），小于0不清理，例如由其它实例负责清理
+	createTable   bool          // 创建适配器时是否自动建表
+}
+
+func (c Config) WithDB(db *sql.DB) Config {
+	c.db = db
+	return c
+}
+
+func (c Config) WithDialect(dialect Dialect) Config {
+	c.dialect = dialect
+	return c
+}
+
+func (c Config) WithTable(table string) Config {
+	c.table = table
+	return c
+}
+
+func (c Config) WithBatchSize(batchSize int) Config {
+	c.batchSize = batchSize
+	return c
+}
+
+func (c Config) WithPurgeInterval(purgeInterval time.Duration) Config {
+	c.purgeInterval = purgeInterval
+	return c
+}
+
+// WithCreateTable 创建适配器时执行 CREATE TABLE IF NOT EXISTS
+func (c Config) WithCreateTable(createTable bool) Config {
+	c.createTable = createTable
+	return c
+}
+
+func (c Config) build() (Config, error) {
+	if c.db == nil {
+		return c, fmt.Errorf("db为空")
+	}
+	if c.dialect != Postgres && c.dialect != MySQL && c.dialect != SQLite {
+		return c, fmt.Errorf("不支持的dialect: %d", c.dialect)
+	}
+	if len(c.table) == 0 {
+		c.table = "cache"
+	}
+	if !tableName.MatchString(c.table) {
+		return c, fmt.Errorf("表名不合法: %s", c.table)
+	}
+	if c.batchSize == 0 {
+		c.batchSize = 500
+	}
+	if c.batchSize < 0 {
+		return c, fmt.Errorf("batchSize不能为负数")
+	}
+	if c.purgeInterval == 0 {
+		c.purgeInterval = time.Minute
+	}
+	return c, nil
+}
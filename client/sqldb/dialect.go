@@ -0,0 +1,91 @@
+package sqldb
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Dialect 数据库类型，决定建表语句、占位符和 upsert 语法
+type Dialect int
+
+const (
+	Postgres Dialect = iota + 1
+	MySQL
+	SQLite
+)
+
+// 表结构：cache_key 主键，cache_value 数据，expire_at 过期时间（unix 毫秒，0表示不过期）
+// cache_key 按字节比较排序（Postgres COLLATE "C"、MySQL VARBINARY、SQLite 默认 BINARY），Scan 依赖这个顺序
+const liveCond = "(expire_at = 0 OR expire_at > %s)"
+
+// createTable 建表及 expire_at 索引语句
+func (d Dialect) createTable(table string) []string {
+	index := strings.ReplaceAll(table, ".", "_") + "_expire_at"
+	switch d {
+	case Postgres:
+		return []string{
+			fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (cache_key VARCHAR(255) COLLATE "C" PRIMARY KEY, cache_value BYTEA NOT NULL, expire_at BIGINT NOT NULL DEFAULT 0)`, table),
+			fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s ON %s (expire_at)`, index, table),
+		}
+	case MySQL:
+		return []string{
+			fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (cache_key VARBINARY(255) NOT NULL PRIMARY KEY, cache_value LONGBLOB NOT NULL, expire_at BIGINT NOT NULL DEFAULT 0, INDEX idx_expire_at (expire_at))`, table),
+		}
+	default:
+		return []string{
+			fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (cache_key TEXT NOT NULL PRIMARY KEY, cache_value BLOB NOT NULL, expire_at INTEGER NOT NULL DEFAULT 0)`, table),
+			fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s ON %s (expire_at)`, index, table),
+		}
+	}
+}
+
+// placeholder 第i个参数（从1开始）的占位符
+func (d Dialect) placeholder(i int) string {
+	if d == Postgres {
+		return fmt.Sprintf("$%d", i)
+	}
+	return "?"
+}
+
+// placeholders 从第start个参数开始的n个占位符，逗号分隔
+func (d Dialect) placeholders(start, n int) string {
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(d.placeholder(start + i))
+	}
+	return b.String()
+}
+
+// upsert 写入n行，key已存在时覆盖数据和过期时间
+func (d Dialect) upsert(table string, n int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "INSERT INTO %s (cache_key, cache_value, expire_at) VALUES ", table)
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(&b, "(%s)", d.placeholders(3*i+1, 3))
+	}
+	if d == MySQL {
+		b.WriteString(" ON DUPLICATE KEY UPDATE cache_value = VALUES(cache_value), expire_at = VALUES(expire_at)")
+	} else {
+		b.WriteString(" ON CONFLICT (cache_key) DO UPDATE SET cache_value = excluded.cache_value, expire_at = excluded.expire_at")
+	}
+	return b.String()
+}
+
+// insertIgnore 写入一行，key已存在时不写入
+func (d Dialect) insertIgnore(table string) string {
+	if d == MySQL {
+		return fmt.Sprintf("INSERT IGNORE INTO %s (cache_key, cache_value, expire_at) VALUES (?, ?, ?)", table)
+	}
+	return fmt.Sprintf("INSERT INTO %s (cache_key, cache_value, expire_at) VALUES (%s) ON CONFLICT (cache_key) DO NOTHING", table, d.placeholders(1, 3))
+}
+
+// live 未过期的条件，第i个参数为当前时间
+func (d Dialect) live(i int) string {
+	return fmt.Sprintf(liveCond, d.placeholder(i))
+}
@@ -0,0 +1,460 @@
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/PycMono/go-cache/client"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cache 把数据保存在数据库表中，作为没有 redis 时多个实例共享的持久化缓存
+// 读取时过滤已过期的数据，后台定期删除过期数据
+type Cache struct {
+	conf Config
+
+	sm     sync.RWMutex
+	closed bool
+	done   chan struct{}
+	wg     sync.WaitGroup
+}
+
+func NewSQLAdaptor(conf *Config) (client.IAdaptor, error) {
+	c, err := conf.build()
+	if err != nil {
+		return nil, err
+	}
+
+	if c.createTable {
+		for _, stmt := range c.dialect.createTable(c.table) {
+			if _, err = c.db.ExecContext(context.Background(), stmt); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	cache := &Cache{conf: c, done: make(chan struct{})}
+	if c.purgeInterval > 0 {
+		cache.wg.Add(1)
+		go cache.purgeLoop()
+	}
+	return cache, nil
+}
+
+// Set 按 batchSize 分批，每批一条 upsert
+func (s *Cache) Set(ctx context.Context, params map[string][]byte, expire time.Duration) error {
+	if err := s.check(); err != nil {
+		return err
+	}
+
+	var (
+		keys     = sortedKeys(params)
+		expireAt = expireAt(expire)
+	)
+	return s.batches(keys, func(batch []string) error {
+		args := make([]any, 0, 3*len(batch))
+		for _, key := range batch {
+			args = append(args, key, params[key], expireAt)
+		}
+		_, err := s.conf.db.ExecContext(ctx, s.conf.dialect.upsert(s.conf.table, len(batch)), args...)
+		return err
+	})
+}
+
+func (s *Cache) Get(ctx context.Context, k []string) (map[string][]byte, error) {
+	items, err := s.read(ctx, k, true)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[string][]byte, len(items))
+	for key, item := range items {
+		out[key] = item.Value
+	}
+	return out, nil
+}
+
+func (s *Cache) GetWithTTL(ctx context.Context, k []string) (map[string]client.Item, error) {
+	return s.read(ctx, k, true)
+}
+
+func (s *Cache) TTL(ctx context.Context, k []string) (map[string]time.Duration, error) {
+	items, err := s.read(ctx, k, false)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]time.Duration, len(items))
+	for key, item := range items {
+		out[key] = item.TTL
+	}
+	return out, nil
+}
+
+func (s *Cache) Del(ctx context.Context, k []string) error {
+	if err := s.check(); err != nil {
+		return err
+	}
+
+	return s.batches(k, func(batch []string) error {
+		query := fmt.Sprintf("DELETE FROM %s WHERE cache_key IN (%s)", s.conf.table, s.conf.dialect.placeholders(1, len(batch)))
+		_, err := s.conf.db.ExecContext(ctx, query, toArgs(batch)...)
+		return err
+	})
+}
+
+// Expire 只修改 expire_at，已过期的key忽略
+func (s *Cache) Expire(ctx context.Context, k []string, expire time.Duration) error {
+	if err := s.check(); err != nil {
+		return err
+	}
+
+	var (
+		d        = s.conf.dialect
+		expireAt = expireAt(expire)
+	)
+	return s.batches(k, func(batch []string) error {
+		query := fmt.Sprintf("UPDATE %s SET expire_at = %s WHERE cache_key IN (%s) AND %s",
+			s.conf.table, d.placeholder(1), d.placeholders(2, len(batch)), d.live(len(batch)+2))
+		args := append(append([]any{expireAt}, toArgs(batch)...), time.Now().UnixMilli())
+		_, err := s.conf.db.ExecContext(ctx, query, args...)
+		return err
+	})
+}
+
+// SetIfAbsent 每个key先删除已过期的旧数据，再写入并忽略主键冲突，影响行数为1表示写入
+func (s *Cache) SetIfAbsent(ctx context.Context, params map[string][]byte, expire time.Duration) (map[string]bool, error) {
+	if err := s.check(); err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]bool, len(params))
+	for _, key := range sortedKeys(params) {
+		ok, err := s.insertIfAbsent(ctx, key, params[key], expireAt(expire))
+		if err != nil {
+			return nil, err
+		}
+		out[key] = ok
+	}
+	return out, nil
+}
+
+// SetIfPresent 每个key一条带未过期条件的 UPDATE
+// MySQL 默认按实际修改的行数返回影响行数，数据和过期时间都不变时返回未写入
+func (s *Cache) SetIfPresent(ctx context.Context, params map[string][]byte, expire time.Duration) (map[string]bool, error) {
+	if err := s.check(); err != nil {
+		return nil, err
+	}
+
+	var (
+		d     = s.conf.dialect
+		query = fmt.Sprintf("UPDATE %s SET cache_value = %s, expire_at = %s WHERE cache_key = %s AND %s",
+			s.conf.table, d.placeholder(1), d.placeholder(2), d.placeholder(3), d.live(4))
+		out = make(map[string]bool, len(params))
+	)
+	for _, key := range sortedKeys(params) {
+		ok, err := affected(s.conf.db.ExecContext(ctx, query, params[key], expireAt(expire), key, time.Now().UnixMilli()))
+		if err != nil {
+			return nil, err
+		}
+		out[key] = ok
+	}
+	return out, nil
+}
+
+// CompareAndSwap 查询当前数据比较版本号，一致时执行以旧数据为条件的 UPDATE，查询和写入之间数据被修改时不写入
+// Version 为空时和 SetIfAbsent 相同
+func (s *Cache) CompareAndSwap(ctx context.Context, params map[string]client.Swap, expire time.Duration) (map[string]bool, error) {
+	if err := s.check(); err != nil {
+		return nil, err
+	}
+
+	keys := sortedKeys(params)
+	items, err := s.read(ctx, keys, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		d     = s.conf.dialect
+		query = fmt.Sprintf("UPDATE %s SET cache_value = %s, expire_at = %s WHERE cache_key = %s AND cache_value = %s AND %s",
+			s.conf.table, d.placeholder(1), d.placeholder(2), d.placeholder(3), d.placeholder(4), d.live(5))
+		out = make(map[string]bool, len(params))
+	)
+	for _, key := range keys {
+		swap := params[key]
+		item, found := items[key]
+		switch {
+		case swap.Version == "":
+			out[key], err = s.insertIfAbsent(ctx, key, swap.Value, expireAt(expire))
+		case found && client.Version(item.Value) == swap.Version:
+			out[key], err = affected(s.conf.db.ExecContext(ctx, query, swap.Value, expireAt(expire), key, item.Value, time.Now().UnixMilli()))
+		default:
+			out[key] = false
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// Scan 从pattern中通配符之前的前缀开始按key顺序分页查询，在本地按pattern过滤，超出前缀范围后结束
+func (s *Cache) Scan(ctx context.Context, pattern string) client.KeyIterator {
+	if err := s.check(); err != nil {
+		return client.ErrIterator(err)
+	}
+
+	var (
+		d      = s.conf.dialect
+		prefix = literalPrefix(pattern)
+		last   = prefix
+		first  = true
+	)
+	return client.NewKeyIterator(func(ctx context.Context) ([]string, bool, error) {
+		if err := s.check(); err != nil {
+			return nil, true, err
+		}
+
+		op := ">"
+		if first {
+			op = ">="
+		}
+		query := fmt.Sprintf("SELECT cache_key FROM %s WHERE cache_key %s %s AND %s ORDER BY cache_key LIMIT %d",
+			s.conf.table, op, d.placeholder(1), d.live(2), s.conf.batchSize)
+		rows, err := s.conf.db.QueryContext(ctx, query, last, time.Now().UnixMilli())
+		if err != nil {
+			return nil, true, err
+		}
+		defer rows.Close()
+
+		var (
+			keys []string
+			n    int
+		)
+		for rows.Next() {
+			var key string
+			if err = rows.Scan(&key); err != nil {
+				return nil, true, err
+			}
+			n++
+			last = key
+			if !strings.HasPrefix(key, prefix) {
+				return keys, true, nil
+			}
+			if client.MatchPattern(pattern, key) {
+				keys = append(keys, key)
+			}
+		}
+		first = false
+		return keys, n < s.conf.batchSize, rows.Err()
+	})
+}
+
+func (s *Cache) DelPrefix(ctx context.Context, prefix string) (int64, error) {
+	var (
+		n  int64
+		it = s.Scan(ctx, client.EscapePattern(prefix)+"*")
+	)
+	for {
+		batchCtx, cancel := client.BatchContext(ctx)
+		if !it.Next(batchCtx) {
+			cancel()
+			break
+		}
+		keys := it.Keys()
+		err := s.Del(batchCtx, keys)
+		cancel()
+		if err != nil {
+			return n, err
+		}
+		n += int64(len(keys))
+	}
+	return n, it.Err()
+}
+
+// Purge 删除全部已过期的数据，返回删除的行数
+func (s *Cache) Purge(ctx context.Context) (int64, error) {
+	if err := s.check(); err != nil {
+		return 0, err
+	}
+
+	query := fmt.Sprintf("DELETE FROM %s WHERE expire_at <> 0 AND expire_at <= %s", s.conf.table, s.conf.dialect.placeholder(1))
+	res, err := s.conf.db.ExecContext(ctx, query, time.Now().UnixMilli())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// Close 停止后台清理，数据库连接由调用方关闭
+func (s *Cache) Close() error {
+	s.sm.Lock()
+	if s.closed {
+		s.sm.Unlock()
+		return client.ErrClosed
+	}
+	s.closed = true
+	close(s.done)
+	s.sm.Unlock()
+
+	s.wg.Wait()
+	return nil
+}
+
+func (s *Cache) check() error {
+	s.sm.RLock()
+	defer s.sm.RUnlock()
+
+	if s.closed {
+		return client.ErrClosed
+	}
+	return nil
+}
+
+// read 分批使用 IN 查询未过期的数据，withValue 为false时不查询数据
+func (s *Cache) read(ctx context.Context, k []string, withValue bool) (map[string]client.Item, error) {
+	if err := s.check(); err != nil {
+		return nil, err
+	}
+
+	var (
+		d       = s.conf.dialect
+		columns = "cache_key, expire_at"
+		out     = make(map[string]client.Item, len(k))
+		now     = time.Now().UnixMilli()
+	)
+	if withValue {
+		columns += ", cache_value"
+	}
+	err := s.batches(k, func(batch []string) error {
+		query := fmt.Sprintf("SELECT %s FROM %s WHERE cache_key IN (%s) AND %s",
+			columns, s.conf.table, d.placeholders(1, len(batch)), d.live(len(batch)+1))
+		rows, err := s.conf.db.QueryContext(ctx, query, append(toArgs(batch), now)...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var (
+				key      string
+				expireAt int64
+				value    []byte
+				dest     = []any{&key, &expireAt}
+			)
+			if withValue {
+				dest = append(dest, &value)
+			}
+			if err = rows.Scan(dest...); err != nil {
+				return err
+			}
+
+			item := client.Item{Value: value, TTL: client.NoExpire}
+			if expireAt > 0 {
+				item.TTL = time.Duration(expireAt-now) * time.Millisecond
+			}
+			out[key] = item
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// insertIfAbsent 删除key已过期的旧数据后写入，主键冲突时不写入
+func (s *Cache) insertIfAbsent(ctx context.Context, key string, value []byte, expireAt int64) (bool, error) {
+	d := s.conf.dialect
+	query := fmt.Sprintf("DELETE FROM %s WHERE cache_key = %s AND expire_at <> 0 AND expire_at <= %s", s.conf.table, d.placeholder(1), d.placeholder(2))
+	if _, err := s.conf.db.ExecContext(ctx, query, key, time.Now().UnixMilli()); err != nil {
+		return false, err
+	}
+	return affected(s.conf.db.ExecContext(ctx, d.insertIgnore(s.conf.table), key, value, expireAt))
+}
+
+// affected 影响行数大于0表示写入
+func affected(res sql.Result, err error) (bool, error) {
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// batches 按 batchSize 分批执行f
+func (s *Cache) batches(keys []string, f func(batch []string) error) error {
+	for i := 0; i < len(keys); i += s.conf.batchSize {
+		if err := f(keys[i:min(i+s.conf.batchSize, len(keys))]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// purgeLoop 定期删除过期数据，出错只打印日志
+func (s *Cache) purgeLoop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.conf.purgeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), s.conf.purgeInterval)
+		if _, err := s.Purge(ctx); err != nil {
+			fmt.Println(err)
+		}
+		cancel()
+	}
+}
+
+// literalPrefix pattern中第一个通配符之前的部分，转义的字符按原字符处理
+func literalPrefix(pattern string) string {
+	var b strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '*', '?', '[':
+			return b.String()
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+			}
+		}
+		b.WriteByte(pattern[i])
+	}
+	return b.String()
+}
+
+// sortedKeys 按key排序，并发写入时各个事务以相同顺序加锁，避免死锁
+func sortedKeys[V any](params map[string]V) []string {
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func toArgs(keys []string) []any {
+	args := make([]any, len(keys))
+	for i, key := range keys {
+		args[i] = key
+	}
+	return args
+}
+
+func expireAt(expire time.Duration) int64 {
+	if expire <= 0 {
+		return 0
+	}
+	return time.Now().Add(expire).UnixMilli()
+}
@@ -0,0 +1,182 @@
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/PycMono/go-cache/client"
+	_ "github.com/mattn/go-sqlite3"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestAdaptor(t *testing.T) (*Cache, *sql.DB) {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "cache.db"))
+	if err != nil {
+		panic(err)
+	}
+	conf := Config{}.WithDB(db).WithDialect(SQLite).WithBatchSize(7).WithPurgeInterval(-1).WithCreateTable(true)
+	adaptor, err := NewSQLAdaptor(&conf)
+	if err != nil {
+		panic(err)
+	}
+	return adaptor.(*Cache), db
+}
+
+func TestConfig(t *testing.T) {
+	db, _ := sql.Open("sqlite3", ":memory:")
+	defer db.Close()
+
+	if _, err := NewSQLAdaptor(&Config{}); err == nil {
+		t.Fatalf("want error for nil db")
+	}
+	conf := Config{}.WithDB(db)
+	if _, err := NewSQLAdaptor(&conf); err == nil {
+		t.Fatalf("want error for missing dialect")
+	}
+	conf = conf.WithDialect(SQLite).WithTable("cache; DROP TABLE x")
+	if _, err := NewSQLAdaptor(&conf); err == nil {
+		t.Fatalf("want error for invalid table name")
+	}
+}
+
+func TestDialect(t *testing.T) {
+	if q := Postgres.upsert("cache", 2); !strings.Contains(q, "($1, $2, $3), ($4, $5, $6) ON CONFLICT (cache_key)") {
+		t.Fatalf("postgres upsert = %s", q)
+	}
+	if q := MySQL.upsert("cache", 1); !strings.Contains(q, "(?, ?, ?) ON DUPLICATE KEY UPDATE") {
+		t.Fatalf("mysql upsert = %s", q)
+	}
+	if q := MySQL.insertIgnore("cache"); !strings.HasPrefix(q, "INSERT IGNORE") {
+		t.Fatalf("mysql insertIgnore = %s", q)
+	}
+	if p := literalPrefix(`user\*:*`); p != "user*:" {
+		t.Fatalf("literalPrefix = %q, want user*:", p)
+	}
+}
+
+func TestSQL(t *testing.T) {
+	var (
+		cache, db = newTestAdaptor(t)
+		ctx       = context.TODO()
+		kvMap     = make(map[string][]byte)
+		keys      []string
+	)
+	defer db.Close()
+
+	// 多于 batchSize 的key分批写入和查询
+	for i := 0; i < 20; i++ {
+		key := fmt.Sprintf("user:%02d", i)
+		kvMap[key] = []byte(fmt.Sprint(i))
+		keys = append(keys, key)
+	}
+	if err := cache.Set(ctx, kvMap, time.Hour); err != nil {
+		panic(err)
+	}
+	if err := cache.Set(ctx, map[string][]byte{"user:00": []byte("new"), "tmp": []byte("x")}, 50*time.Millisecond); err != nil {
+		panic(err)
+	}
+	respMap, err := cache.Get(ctx, append(keys, "missing"))
+	if err != nil {
+		panic(err)
+	}
+	if len(respMap) != 20 || string(respMap["user:00"]) != "new" || string(respMap["user:19"]) != "19" {
+		t.Fatalf("len = %d, user:00 = %q, want 20 keys", len(respMap), respMap["user:00"])
+	}
+	ttl, _ := cache.TTL(ctx, []string{"user:01"})
+	if ttl["user:01"] <= 59*time.Minute || ttl["user:01"] > time.Hour {
+		t.Fatalf("ttl = %v, want about 1h", ttl["user:01"])
+	}
+
+	// 过期数据读取时过滤，Purge 删除
+	time.Sleep(100 * time.Millisecond)
+	if respMap, _ = cache.Get(ctx, []string{"user:00", "tmp"}); len(respMap) != 0 {
+		t.Fatalf("respMap = %v, want expired", respMap)
+	}
+	if n, err := cache.Purge(ctx); err != nil || n != 2 {
+		t.Fatalf("purge = %d, %v, want 2", n, err)
+	}
+
+	_ = cache.Expire(ctx, []string{"user:01"}, 0)
+	items, _ := cache.GetWithTTL(ctx, []string{"user:01"})
+	if items["user:01"].TTL != client.NoExpire || string(items["user:01"].Value) != "1" {
+		t.Fatalf("item = %+v, want 1 without expire", items["user:01"])
+	}
+
+	// Scan 分页，只返回匹配的key
+	_ = cache.Set(ctx, map[string][]byte{"user": []byte("x"), "v": []byte("x")}, 0)
+	var scanned []string
+	it := cache.Scan(ctx, "user:1*")
+	for it.Next(ctx) {
+		scanned = append(scanned, it.Keys()...)
+	}
+	if it.Err() != nil || len(scanned) != 10 {
+		t.Fatalf("scanned = %v, %v, want user:10..19", scanned, it.Err())
+	}
+	if n, err := cache.DelPrefix(ctx, "user:"); err != nil || n != 19 {
+		t.Fatalf("DelPrefix = %d, %v, want 19", n, err)
+	}
+	if respMap, _ = cache.Get(ctx, []string{"user", "v", "user:05"}); len(respMap) != 2 {
+		t.Fatalf("respMap = %v, want user and v", respMap)
+	}
+
+	if err = cache.Del(ctx, []string{"user", "v"}); err != nil {
+		panic(err)
+	}
+	if err = cache.Close(); err != nil {
+		panic(err)
+	}
+	if _, err = cache.Get(ctx, []string{"user"}); err != client.ErrClosed {
+		t.Fatalf("err = %v, want ErrClosed", err)
+	}
+}
+
+func TestConditional(t *testing.T) {
+	var (
+		cache, db = newTestAdaptor(t)
+		ctx       = context.TODO()
+	)
+	defer db.Close()
+	defer cache.Close()
+
+	ok, _ := cache.SetIfAbsent(ctx, map[string][]byte{"1": []byte("a")}, 50*time.Millisecond)
+	if !ok["1"] {
+		t.Fatalf("SetIfAbsent = %v, want stored", ok)
+	}
+	ok, _ = cache.SetIfAbsent(ctx, map[string][]byte{"1": []byte("b")}, 0)
+	if ok["1"] {
+		t.Fatalf("SetIfAbsent = %v, want not stored", ok)
+	}
+	// 已过期的key按不存在处理
+	time.Sleep(100 * time.Millisecond)
+	if ok, _ = cache.SetIfPresent(ctx, map[string][]byte{"1": []byte("b")}, 0); ok["1"] {
+		t.Fatalf("SetIfPresent = %v, want not stored", ok)
+	}
+	if ok, _ = cache.SetIfAbsent(ctx, map[string][]byte{"1": []byte("b")}, 0); !ok["1"] {
+		t.Fatalf("SetIfAbsent = %v, want stored over expired", ok)
+	}
+	if ok, _ = cache.SetIfPresent(ctx, map[string][]byte{"1": []byte("c")}, 0); !ok["1"] {
+		t.Fatalf("SetIfPresent = %v, want stored", ok)
+	}
+
+	ok, err := cache.CompareAndSwap(ctx, map[string]client.Swap{
+		"1": {Value: []byte("d"), Version: client.Version([]byte("c"))},
+		"2": {Value: []byte("d")},
+		"3": {Value: []byte("d"), Version: client.Version([]byte("x"))},
+	}, 0)
+	if err != nil {
+		panic(err)
+	}
+	if !ok["1"] || !ok["2"] || ok["3"] {
+		t.Fatalf("CompareAndSwap = %v, want 1 and 2", ok)
+	}
+	ok, _ = cache.CompareAndSwap(ctx, map[string]client.Swap{"1": {Value: []byte("e"), Version: client.Version([]byte("c"))}}, 0)
+	if ok["1"] {
+		t.Fatalf("CompareAndSwap with stale version = %v, want false", ok)
+	}
+	if respMap, _ := cache.Get(ctx, []string{"1"}); string(respMap["1"]) != "d" {
+		t.Fatalf("respMap = %v, want 1=d", respMap)
+	}
+}
@@ -0,0 +1,148 @@
+package client
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ITagAdaptor 支持标签索引的适配器，标签索引记录每个标签下有哪些key，用于按标签批量删除
+type ITagAdaptor interface {
+	IAdaptor
+	// AddTags 把key加入标签索引，params 为 key→标签，expire 为key的过期时间，key过期后从索引中清理
+	AddTags(ctx context.Context, params map[string][]string, expire time.Duration) error
+	// TagKeys 查询标签下未过期的key，多个标签的结果合并去重
+	TagKeys(ctx context.Context, tags []string) ([]string, error)
+	// DelTags 删除标签索引，不删除数据
+	DelTags(ctx context.Context, tags []string) error
+}
+
+// TagIndex 进程内的标签索引，供内存缓存实现 ITagAdaptor，零值可以直接使用
+// 索引中记录每个key的过期时间，写入和查询时清理已过期的key；key被删除时由适配器调用 Remove 清理
+type TagIndex struct {
+	sm   sync.Mutex
+	tags map[string]map[string]int64    // 标签→key→过期时间（UnixNano），0表示不过期
+	keys map[string]map[string]struct{} // key→标签，用于删除key时清理索引
+}
+
+// Add 把key加入标签索引
+func (t *TagIndex) Add(params map[string][]string, expire time.Duration) {
+	now := time.Now().UnixNano()
+	var expireAt int64
+	if expire > 0 {
+		expireAt = now + int64(expire)
+	}
+
+	t.sm.Lock()
+	defer t.sm.Unlock()
+
+	if t.tags == nil {
+		t.tags = make(map[string]map[string]int64)
+		t.keys = make(map[string]map[string]struct{})
+	}
+	for key, tags := range params {
+		for _, tag := range tags {
+			if keys, ok := t.tags[tag]; ok {
+				t.prune(tag, keys, now, nil)
+			}
+			keys, ok := t.tags[tag]
+			if !ok {
+				keys = make(map[string]int64)
+				t.tags[tag] = keys
+			}
+			keys[key] = expireAt
+
+			tagSet, ok := t.keys[key]
+			if !ok {
+				tagSet = make(map[string]struct{})
+				t.keys[key] = tagSet
+			}
+			tagSet[tag] = struct{}{}
+		}
+	}
+}
+
+// Keys 查询标签下未过期的key，exists 不为nil时同时清理缓存中已不存在的key（例如被淘汰）
+func (t *TagIndex) Keys(tags []string, exists func(key string) bool) []string {
+	now := time.Now().UnixNano()
+
+	t.sm.Lock()
+	defer t.sm.Unlock()
+
+	var (
+		out  []string
+		seen = make(map[string]struct{})
+	)
+	for _, tag := range tags {
+		keys, ok := t.tags[tag]
+		if !ok {
+			continue
+		}
+		t.prune(tag, keys, now, exists)
+		for key := range keys {
+			if _, ok := seen[key]; !ok {
+				seen[key] = struct{}{}
+				out = append(out, key)
+			}
+		}
+	}
+	return out
+}
+
+// Remove 从所有标签中删除key，适配器删除数据后调用
+func (t *TagIndex) Remove(keys []string) {
+	t.sm.Lock()
+	defer t.sm.Unlock()
+
+	for _, key := range keys {
+		for tag := range t.keys[key] {
+			t.unlink(tag, key)
+		}
+	}
+}
+
+// Del 删除标签索引
+func (t *TagIndex) Del(tags []string) {
+	t.sm.Lock()
+	defer t.sm.Unlock()
+
+	for _, tag := range tags {
+		for key := range t.tags[tag] {
+			t.unlink(tag, key)
+		}
+	}
+}
+
+// Clear 清空全部标签索引
+func (t *TagIndex) Clear() {
+	t.sm.Lock()
+	defer t.sm.Unlock()
+
+	t.tags = nil
+	t.keys = nil
+}
+
+// prune 删除已过期或 exists 返回false的key，调用方需要持有锁
+func (t *TagIndex) prune(tag string, keys map[string]int64, now int64, exists func(key string) bool) {
+	for key, expireAt := range keys {
+		if (expireAt > 0 && expireAt <= now) || (exists != nil && !exists(key)) {
+			t.unlink(tag, key)
+		}
+	}
+}
+
+// unlink 删除标签和key的对应关系，标签下没有key时删除标签，调用方需要持有锁
+func (t *TagIndex) unlink(tag, key string) {
+	if keys, ok := t.tags[tag]; ok {
+		delete(keys, key)
+		if len(keys) == 0 {
+			delete(t.tags, tag)
+		}
+	}
+	if tags, ok := t.keys[key]; ok {
+		delete(tags, tag)
+		if len(tags) == 0 {
+			delete(t.keys, key)
+		}
+	}
+}
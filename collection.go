@@ -0,0 +1,98 @@
+package tmpcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/PycMono/go-cache/client"
+	"github.com/bytedance/sonic"
+	"time"
+)
+
+// CollectionOptions ListCache、SetCache、ZSetCache 的配置
+type CollectionOptions struct {
+	Base
+	Expire   time.Duration // 过期时间，每次写入重新设置整个集合的过期时间，为0时不过期
+	Timeouts Timeouts      // 写入使用 Set 超时，读取使用 Get 超时，删除使用 Del 超时
+}
+
+// collectionBase 集合缓存的公共部分：key构造、序列化、超时和关闭
+type collectionBase[T any] struct {
+	handler client.ICollectionAdaptor
+	opts    *CollectionOptions
+	lc      lifecycle
+}
+
+// Del 删除整个集合
+func (c *collectionBase[T]) Del(ctx context.Context, keys []string) error {
+	if err := c.lc.enter(); err != nil {
+		return err
+	}
+	defer c.lc.leave()
+
+	return callTier(ctx, 0, "del", c.opts.Timeouts.Del, func(ctx context.Context) error {
+		return c.handler.Del(ctx, c.opts.buildKeys(keys))
+	})
+}
+
+// Close 拒绝新的调用并在ctx到期前等待进行中的调用结束，之后关闭适配器
+func (c *collectionBase[T]) Close(ctx context.Context) error {
+	err := c.lc.shutdown(ctx)
+	if errors.Is(err, ErrClosed) {
+		return err
+	}
+
+	return errors.Join(err, c.handler.Close())
+}
+
+// read 在 Get 超时内执行读取
+func (c *collectionBase[T]) read(ctx context.Context, f func(ctx context.Context) error) error {
+	if err := c.lc.enter(); err != nil {
+		return err
+	}
+	defer c.lc.leave()
+
+	return callTier(ctx, 0, "get", c.opts.Timeouts.Get, f)
+}
+
+// write 在 Set 超时内执行写入
+func (c *collectionBase[T]) write(ctx context.Context, f func(ctx context.Context) error) error {
+	if err := c.lc.enter(); err != nil {
+		return err
+	}
+	defer c.lc.leave()
+
+	return callTier(ctx, 0, "set", c.opts.Timeouts.Set, f)
+}
+
+func (c *collectionBase[T]) marshal(values []T) ([][]byte, error) {
+	out := make([][]byte, len(values))
+	for i, v := range values {
+		b, err := sonic.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = b
+	}
+	return out, nil
+}
+
+func (c *collectionBase[T]) unmarshal(values [][]byte) ([]T, error) {
+	out := make([]T, len(values))
+	for i, v := range values {
+		if err := sonic.Unmarshal(v, &out[i]); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// pageRange 第page页（从1开始）的下标范围，size 必须大于0
+func pageRange(page, size int64) (int64, int64, error) {
+	if size <= 0 {
+		return 0, 0, fmt.Errorf("cache: page size must be positive, got %d", size)
+	}
+	page = max(page, 1)
+	start := (page - 1) * size
+	return start, start + size - 1, nil
+}
@@ -0,0 +1,89 @@
+package tmpcache
+
+import (
+	"context"
+	"github.com/PycMono/go-cache/client"
+	"github.com/PycMono/go-cache/client/collection"
+	"testing"
+	"time"
+)
+
+func newCollectionAdaptor() client.ICollectionAdaptor {
+	conf := collection.Config{}
+	adaptor, err := collection.NewCollectionAdaptor(&conf)
+	if err != nil {
+		panic(err)
+	}
+	return adaptor
+}
+
+func TestListCache(t *testing.T) {
+	type activity struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+	cache := NewListCache[*activity](newCollectionAdaptor(), &CollectionOptions{Base: Base{Prefix: "demo"}, Expire: time.Minute})
+
+	for i := 1; i <= 5; i++ {
+		if _, err := cache.LPush(context.TODO(), "recent", &activity{ID: i}); err != nil {
+			panic(err)
+		}
+	}
+	// 最近的在前面，只保留3条
+	if err := cache.Trim(context.TODO(), "recent", 0, 2); err != nil {
+		panic(err)
+	}
+	page, err := cache.Page(context.TODO(), "recent", 2, 2)
+	if err != nil {
+		panic(err)
+	}
+	if len(page) != 1 || page[0].ID != 3 {
+		t.Fatalf("page = %v", page)
+	}
+	if _, err = cache.Page(context.TODO(), "recent", 1, 0); err == nil {
+		t.Fatalf("want error for page size 0")
+	}
+}
+
+func TestSetCache(t *testing.T) {
+	cache := NewSetCache[int64](newCollectionAdaptor(), &CollectionOptions{Base: Base{Prefix: "demo"}})
+
+	if _, err := cache.Add(context.TODO(), "online", 1, 2, 3); err != nil {
+		panic(err)
+	}
+	ok, err := cache.IsMember(context.TODO(), "online", 2, 4)
+	if err != nil {
+		panic(err)
+	}
+	if !ok[0] || ok[1] {
+		t.Fatalf("ok = %v", ok)
+	}
+	members, _ := cache.Members(context.TODO(), "online")
+	if len(members) != 3 {
+		t.Fatalf("members = %v", members)
+	}
+}
+
+func TestZSetCache(t *testing.T) {
+	cache := NewZSetCache[string](newCollectionAdaptor(), &CollectionOptions{Base: Base{Prefix: "demo"}})
+
+	_, err := cache.Add(context.TODO(), "board", Scored[string]{Member: "a", Score: 10}, Scored[string]{Member: "b", Score: 20})
+	if err != nil {
+		panic(err)
+	}
+	if _, err = cache.IncrBy(context.TODO(), "board", "a", 15); err != nil {
+		panic(err)
+	}
+
+	top, err := cache.Page(context.TODO(), "board", 1, 10)
+	if err != nil {
+		panic(err)
+	}
+	if len(top) != 2 || top[0].Member != "a" || top[0].Score != 25 {
+		t.Fatalf("top = %v", top)
+	}
+	rank, ok, _ := cache.RevRank(context.TODO(), "board", "b")
+	if !ok || rank != 1 {
+		t.Fatalf("rank = %d, %v, want 1", rank, ok)
+	}
+}
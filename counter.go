@@ -0,0 +1,291 @@
+package tmpcache
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/PycMono/go-cache/client"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// CounterOptions 计数器配置
+type CounterOptions struct {
+	Base
+	Expire time.Duration // 计数第一次创建时设置的过期时间，之后加减不修改过期时间
+	// 本地缓冲的刷新间隔，为0时不缓冲，每次加减直接写入适配器
+	// 缓冲时加减只记录在本地，定时合并后写入，进程退出前需要调用 Close 写入未刷新的增量
+	FlushInterval time.Duration
+	MaxKnown      int      // 缓冲模式下记录刷新后计数值的key数量上限，默认10000，超过后淘汰最久未刷新的key
+	Timeouts      Timeouts // 加减使用 Set 超时，查询使用 Get 超时
+}
+
+// knownCount 刷新后的计数值
+type knownCount struct {
+	key string
+	n   int64
+}
+
+// Counter 原子计数器，key 的构造和 Cache 一致，可以和 Cache 共用同一个适配器
+type Counter struct {
+	handler client.ICounterAdaptor
+	opts    *CounterOptions
+	lc      lifecycle
+
+	sm      sync.Mutex
+	pending map[string]int64         // 未刷新的增量
+	known   map[string]*list.Element // 刷新后的值，按最近刷新的顺序淘汰
+	order   *list.List
+	done    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// NewCounter 创建计数器，handler 需要实现 client.ICounterAdaptor，否则返回 client.ErrNotSupported
+// 熔断、重试包装的适配器按被包装的适配器判断，见 client.Supports
+func NewCounter(handler client.IAdaptor, opts *CounterOptions) (*Counter, error) {
+	if !client.Supports[client.ICounterAdaptor](handler) {
+		return nil, client.ErrNotSupported
+	}
+	counter := handler.(client.ICounterAdaptor)
+
+	c := &Counter{
+		handler: counter,
+		opts:    opts,
+		pending: make(map[string]int64),
+		known:   make(map[string]*list.Element),
+		order:   list.New(),
+		done:    make(chan struct{}),
+	}
+	if opts.FlushInterval > 0 {
+		c.wg.Add(1)
+		go c.loop(opts.FlushInterval)
+	}
+	return c, nil
+}
+
+func (c *Counter) Incr(ctx context.Context, key string) (int64, error) {
+	return c.IncrBy(ctx, key, 1)
+}
+
+func (c *Counter) Decr(ctx context.Context, key string) (int64, error) {
+	return c.IncrBy(ctx, key, -1)
+}
+
+// IncrBy 加上delta并返回加上后的值
+// 缓冲模式下返回上次刷新后的值加上未刷新的增量，是近似值，超出 MaxKnown 被淘汰的key只返回未刷新的增量
+func (c *Counter) IncrBy(ctx context.Context, key string, delta int64) (int64, error) {
+	out, err := c.IncrByMulti(ctx, map[string]int64{key: delta})
+	if err != nil {
+		return 0, err
+	}
+	return out[key], nil
+}
+
+// IncrByMulti 批量加减，返回值的含义和 IncrBy 一致
+func (c *Counter) IncrByMulti(ctx context.Context, params map[string]int64) (map[string]int64, error) {
+	if err := c.lc.enter(); err != nil {
+		return nil, err
+	}
+	defer c.lc.leave()
+
+	if c.opts.FlushInterval > 0 {
+		return c.buffer(params), nil
+	}
+
+	kv := make(map[string]int64, len(params))
+	for k, v := range params {
+		kv[c.opts.buildKey(k)] = v
+	}
+	res, err := c.incrBy(ctx, kv)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]int64, len(res))
+	for k, v := range res {
+		out[c.opts.splitKey(k)] = v
+	}
+	return out, nil
+}
+
+// Get 查询计数，不存在的key不返回，缓冲模式下加上未刷新的增量
+func (c *Counter) Get(ctx context.Context, keys []string) (map[string]int64, error) {
+	if err := c.lc.enter(); err != nil {
+		return nil, err
+	}
+	defer c.lc.leave()
+
+	var (
+		tmpKeys = c.opts.buildKeys(keys)
+		kv      map[string][]byte
+	)
+	err := callTier(ctx, 0, "get", c.opts.Timeouts.Get, func(ctx context.Context) (err error) {
+		kv, err = c.handler.Get(ctx, tmpKeys)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]int64, len(kv))
+	for k, v := range kv {
+		n, err := strconv.ParseInt(string(v), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		out[c.opts.splitKey(k)] = n
+	}
+
+	c.sm.Lock()
+	for _, k := range tmpKeys {
+		if delta, ok := c.pending[k]; ok {
+			out[c.opts.splitKey(k)] += delta
+		}
+	}
+	c.sm.Unlock()
+
+	return out, nil
+}
+
+// Flush 立即写入未刷新的增量，确认没有写入的增量保留到下次刷新，见 client.IncrError
+func (c *Counter) Flush(ctx context.Context) error {
+	if err := c.lc.enter(); err != nil {
+		return err
+	}
+	defer c.lc.leave()
+
+	return c.flush(ctx)
+}
+
+// Close 停止后台刷新并写入未刷新的增量，不关闭适配器（适配器通常和 Cache 共用）
+// ctx 可能已经在等待进行中的调用时用完，写入使用不会被取消的新ctx，最多等待 Timeouts.Set（为0时5秒）
+func (c *Counter) Close(ctx context.Context) error {
+	err := c.lc.shutdown(ctx)
+	if errors.Is(err, ErrClosed) {
+		return err
+	}
+
+	close(c.done)
+	c.wg.Wait()
+
+	timeout := c.opts.Timeouts.Set
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	flushCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+	defer cancel()
+	return errors.Join(err, c.flush(flushCtx))
+}
+
+func (c *Counter) incrBy(ctx context.Context, kv map[string]int64) (map[string]int64, error) {
+	var out map[string]int64
+	err := callTier(ctx, 0, "incr", c.opts.Timeouts.Set, func(ctx context.Context) (err error) {
+		out, err = c.handler.IncrBy(ctx, kv, c.opts.Expire)
+		return err
+	})
+	return out, err
+}
+
+// buffer 记录增量，返回上次刷新后的值加上未刷新的增量
+func (c *Counter) buffer(params map[string]int64) map[string]int64 {
+	c.sm.Lock()
+	defer c.sm.Unlock()
+
+	out := make(map[string]int64, len(params))
+	for k, v := range params {
+		key := c.opts.buildKey(k)
+		c.pending[key] += v
+		out[k] = c.pending[key]
+		if e, ok := c.known[key]; ok {
+			out[k] += e.Value.(*knownCount).n
+		}
+	}
+	return out
+}
+
+// remember 合并刷新后的值，超过 MaxKnown 时淘汰最久未刷新的key，调用方需要持有 c.sm
+func (c *Counter) remember(kv map[string]int64) {
+	maxKnown := c.opts.MaxKnown
+	if maxKnown <= 0 {
+		maxKnown = 10000
+	}
+
+	for k, n := range kv {
+		if e, ok := c.known[k]; ok {
+			e.Value.(*knownCount).n = n
+			c.order.MoveToBack(e)
+			continue
+		}
+		c.known[k] = c.order.PushBack(&knownCount{key: k, n: n})
+	}
+	for c.order.Len() > maxKnown {
+		e := c.order.Front()
+		c.order.Remove(e)
+		delete(c.known, e.Value.(*knownCount).key)
+	}
+}
+
+func (c *Counter) flush(ctx context.Context) error {
+	c.sm.Lock()
+	pending := c.pending
+	c.pending = make(map[string]int64)
+	c.sm.Unlock()
+	if len(pending) == 0 {
+		return nil
+	}
+
+	out, err := c.incrBy(ctx, pending)
+
+	c.sm.Lock()
+	defer c.sm.Unlock()
+	if err == nil {
+		c.remember(out)
+		return nil
+	}
+
+	// 只重试确认没有加上的增量，无法确定是否加上的增量不再重试，避免重复计数
+	var (
+		incrErr *client.IncrError
+		retry   []string
+	)
+	switch {
+	case errors.As(err, &incrErr):
+		c.remember(incrErr.Applied)
+		retry = incrErr.NotApplied
+	case errors.Is(err, client.ErrCircuitOpen), errors.Is(err, client.ErrClosed), errors.Is(err, client.ErrNotSupported):
+		for k := range pending {
+			retry = append(retry, k)
+		}
+	}
+	for _, k := range retry {
+		c.pending[k] += pending[k]
+	}
+	lost := len(pending) - len(retry)
+	if incrErr != nil {
+		lost -= len(incrErr.Applied)
+	}
+	if lost > 0 {
+		err = errors.Join(err, fmt.Errorf("cache: %d counter increments in unknown state are not retried", lost))
+	}
+	return err
+}
+
+// loop 定时刷新，出错只打印日志，增量保留到下次刷新
+func (c *Counter) loop(interval time.Duration) {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := c.flush(context.Background()); err != nil {
+				fmt.Println(err)
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
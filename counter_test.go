@@ -0,0 +1,187 @@
+package tmpcache
+
+import (
+	"context"
+	"errors"
+	"github.com/PycMono/go-cache/client"
+	"github.com/PycMono/go-cache/client/evict"
+	"github.com/PycMono/go-cache/client/retry"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCounter(t *testing.T) {
+	memAdaptor := newMemAdaptor(1024 * 1024)
+	counter, err := NewCounter(memAdaptor, &CounterOptions{Base: Base{Prefix: "demo"}, Expire: time.Minute})
+	if err != nil {
+		panic(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := counter.Incr(context.TODO(), "views"); err != nil {
+				panic(err)
+			}
+		}()
+	}
+	wg.Wait()
+	n, err := counter.IncrBy(context.TODO(), "views", -10)
+	if err != nil {
+		panic(err)
+	}
+	if n != 90 {
+		t.Fatalf("n = %d, want 90", n)
+	}
+
+	// 计数可以直接通过 Cache 读取
+	kvMap, err := NewCache[int64](memAdaptor, &Options{Base: Base{Prefix: "demo"}}).Get(context.TODO(), []string{"views"})
+	if err != nil {
+		panic(err)
+	}
+	if kvMap["views"] != 90 {
+		t.Fatalf("kvMap = %v, want 90", kvMap)
+	}
+
+	// 过期时间只在创建时设置
+	ttl, _ := NewCache[int64](memAdaptor, &Options{Base: Base{Prefix: "demo"}}).TTL(context.TODO(), []string{"views"})
+	if ttl["views"] <= 0 || ttl["views"] > time.Minute {
+		t.Fatalf("ttl = %v", ttl["views"])
+	}
+
+	// 重试包装不支持计数的适配器
+	plain := retry.NewRetryAdaptor(struct{ client.IAdaptor }{memAdaptor}, client.RetryPolicy{})
+	if _, err = NewCounter(plain, &CounterOptions{}); !errors.Is(err, client.ErrNotSupported) {
+		t.Fatalf("err = %v, want ErrNotSupported", err)
+	}
+	if _, err = NewCounter(retry.NewRetryAdaptor(memAdaptor, client.RetryPolicy{}), &CounterOptions{}); err != nil {
+		t.Fatalf("err = %v, want nil", err)
+	}
+}
+
+func TestBufferedCounter(t *testing.T) {
+	adaptor, err := evict.NewEvictAdaptor(&evict.Config{})
+	if err != nil {
+		panic(err)
+	}
+	counter, err := NewCounter(adaptor, &CounterOptions{Base: Base{Prefix: "demo"}, FlushInterval: time.Hour})
+	if err != nil {
+		panic(err)
+	}
+
+	for i := 0; i < 10; i++ {
+		if _, err = counter.Decr(context.TODO(), "stock"); err != nil {
+			panic(err)
+		}
+	}
+	kv, _ := adaptor.Get(context.TODO(), []string{"demo_stock"})
+	if len(kv) != 0 {
+		t.Fatalf("kv = %v, want not flushed", kv)
+	}
+	n, err := counter.Get(context.TODO(), []string{"stock"})
+	if err != nil {
+		panic(err)
+	}
+	if n["stock"] != -10 {
+		t.Fatalf("n = %v, want -10 including pending", n)
+	}
+
+	// Close 写入未刷新的增量
+	if err = counter.Close(context.TODO()); err != nil {
+		panic(err)
+	}
+	kv, _ = adaptor.Get(context.TODO(), []string{"demo_stock"})
+	if string(kv["demo_stock"]) != "-10" {
+		t.Fatalf("kv = %s, want -10", kv["demo_stock"])
+	}
+}
+
+// failCounter 第一次 IncrBy 只加上 applied 中的key，其它key按 notApplied 返回 *client.IncrError
+type failCounter struct {
+	client.ICounterAdaptor
+	fail       bool
+	applied    map[string]bool
+	notApplied map[string]bool
+}
+
+func (f *failCounter) IncrBy(ctx context.Context, params map[string]int64, expire time.Duration) (map[string]int64, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, client.NotAppliedError(nil, params, err)
+	}
+	if !f.fail {
+		return f.ICounterAdaptor.IncrBy(ctx, params, expire)
+	}
+	f.fail = false
+
+	var (
+		apply   = make(map[string]int64)
+		incrErr = &client.IncrError{Err: errors.New("partial failure")}
+	)
+	for k, v := range params {
+		switch {
+		case f.applied[k]:
+			apply[k] = v
+		case f.notApplied[k]:
+			incrErr.NotApplied = append(incrErr.NotApplied, k)
+		}
+	}
+	out, err := f.ICounterAdaptor.IncrBy(ctx, apply, expire)
+	if err != nil {
+		panic(err)
+	}
+	incrErr.Applied = out
+	return nil, incrErr
+}
+
+func TestCounterPartialFlush(t *testing.T) {
+	adaptor, err := evict.NewEvictAdaptor(&evict.Config{})
+	if err != nil {
+		panic(err)
+	}
+	failing := &failCounter{
+		ICounterAdaptor: adaptor.(client.ICounterAdaptor),
+		fail:            true,
+		applied:         map[string]bool{"demo_a": true},
+		notApplied:      map[string]bool{"demo_b": true},
+	}
+	counter, err := NewCounter(failing, &CounterOptions{Base: Base{Prefix: "demo"}, FlushInterval: time.Hour, MaxKnown: 2})
+	if err != nil {
+		panic(err)
+	}
+	ctx := context.TODO()
+
+	// a 已加上，b 确认没有加上，c 状态未知
+	_, _ = counter.IncrByMulti(ctx, map[string]int64{"a": 1, "b": 2, "c": 3})
+	if err = counter.Flush(ctx); err == nil {
+		t.Fatalf("want flush error")
+	}
+	if err = counter.Flush(ctx); err != nil {
+		panic(err)
+	}
+	kv, _ := counter.Get(ctx, []string{"a", "b", "c"})
+	if kv["a"] != 1 || kv["b"] != 2 || len(kv) != 2 {
+		t.Fatalf("kv = %v, want a=1 b=2 without double counting", kv)
+	}
+
+	// 刷新后的值合并保留，不只是最近一次刷新的key
+	_, _ = counter.IncrBy(ctx, "c", 1)
+	_ = counter.Flush(ctx)
+	if n, _ := counter.IncrBy(ctx, "b", 1); n != 3 {
+		t.Fatalf("b = %d, want 3", n)
+	}
+	// 超过 MaxKnown 淘汰最久未刷新的a
+	if n, _ := counter.IncrBy(ctx, "a", 1); n != 1 {
+		t.Fatalf("a = %d, want only pending delta after eviction", n)
+	}
+
+	// 关闭使用的ctx已经取消，仍然写入未刷新的增量
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	_ = counter.Close(canceled)
+	if kv, _ := adaptor.Get(ctx, []string{"demo_a"}); string(kv["demo_a"]) != "2" {
+		t.Fatalf("a = %s, want 2 flushed on close", kv["demo_a"])
+	}
+}
@@ -0,0 +1,63 @@
+package tmpcache
+
+import (
+	"errors"
+	"fmt"
+)
+
+// FailurePolicy 多级缓存中某一级缓存出错时的处理策略
+type FailurePolicy int
+
+const (
+	FailFast        FailurePolicy = iota // 直接返回错误，默认策略
+	SkipAndContinue                      // 打印错误后跳过该级缓存继续处理，不返回错误
+	BestEffort                           // 跳过该级缓存继续处理，最后通过 errors.Join 返回全部错误，Get 会同时返回已查到的数据
+)
+
+// TierError、TimeoutError 中不对应 handlers 下标的 Tier
+const (
+	NoTier    = -1 // 不属于某一级缓存，例如 lookup、loader 超时
+	LocalTier = -2 // MultiCache 的进程内对象缓存，使用 FailurePolicy 作为出错策略
+)
+
+// TierError 某一级缓存的错误，Tier 为该缓存在 handlers 中的下标，进程内对象缓存为 LocalTier
+type TierError struct {
+	Tier int
+	Op   string // set、get、del
+	Err  error
+}
+
+func (e *TierError) Error() string {
+	if e.Tier == LocalTier {
+		return fmt.Sprintf("cache local tier %s: %v", e.Op, e.Err)
+	}
+	return fmt.Sprintf("cache tier %d %s: %v", e.Tier, e.Op, e.Err)
+}
+
+func (e *TierError) Unwrap() error {
+	return e.Err
+}
+
+// FailedTiers 从 MultiCache 返回的错误中提取出错的缓存下标
+func FailedTiers(err error) []int {
+	var tiers []int
+	walkTierErrors(err, func(e *TierError) {
+		tiers = append(tiers, e.Tier)
+	})
+	return tiers
+}
+
+func walkTierErrors(err error, f func(e *TierError)) {
+	switch x := err.(type) {
+	case nil:
+		return
+	case *TierError:
+		f(x)
+	case interface{ Unwrap() []error }:
+		for _, v := range x.Unwrap() {
+			walkTierErrors(v, f)
+		}
+	default:
+		walkTierErrors(errors.Unwrap(err), f)
+	}
+}
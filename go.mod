@@ -3,9 +3,9 @@ module github.com/PycMono/go-cache
 go 1.22.2
 
 require (
-	github.com/avast/retry-go v2.7.0+incompatible
 	github.com/bytedance/sonic v1.11.7
 	github.com/coocood/freecache v1.2.4
+	github.com/mattn/go-sqlite3 v1.14.33
 	github.com/redis/go-redis/v9 v9.5.1
 	golang.org/x/sync v0.7.0
 )
@@ -0,0 +1,237 @@
+package tmpcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/PycMono/go-cache/client"
+	"github.com/bytedance/sonic"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// HashOptions HashCache 配置
+type HashOptions struct {
+	Base
+	Expire   time.Duration // 过期时间，每次写入重新设置
+	Timeouts Timeouts      // 超时配置，默认不限制
+}
+
+// hashField 结构体字段和缓存字段的对应关系
+type hashField struct {
+	name  string // 缓存中的字段名
+	index []int
+}
+
+// HashCache 按字段保存对象，一个结构体字段对应 redis hash 的一个字段，可以只读写部分字段
+// 字段名取 `hash` 标签，没有时取 `json` 标签，都没有时使用结构体字段名，标签为 "-" 的字段不保存
+// T 必须是结构体或结构体指针，每个字段单独序列化
+type HashCache[T any] struct {
+	handler client.IHashAdaptor
+	opts    *HashOptions
+	ptr     bool // T 是否为指针
+	typ     reflect.Type
+	fields  []hashField
+	byName  map[string]hashField
+	lc      lifecycle
+}
+
+// NewHashCache handler 需要实现 client.IHashAdaptor，否则返回 client.ErrNotSupported
+// 熔断、重试包装的适配器按被包装的适配器判断，见 client.Supports
+func NewHashCache[T any](handler client.IAdaptor, opts *HashOptions) (*HashCache[T], error) {
+	if !client.Supports[client.IHashAdaptor](handler) {
+		return nil, client.ErrNotSupported
+	}
+	hash := handler.(client.IHashAdaptor)
+
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	ptr := typ.Kind() == reflect.Pointer
+	if ptr {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("cache: HashCache type %s is not a struct", typ)
+	}
+
+	c := &HashCache[T]{
+		handler: hash,
+		opts:    opts,
+		ptr:     ptr,
+		typ:     typ,
+		byName:  make(map[string]hashField),
+	}
+	for _, sf := range reflect.VisibleFields(typ) {
+		if !sf.IsExported() || sf.Anonymous || viaPointer(typ, sf.Index) {
+			continue
+		}
+		name := fieldName(sf)
+		if name == "-" {
+			continue
+		}
+		if _, ok := c.byName[name]; ok {
+			return nil, fmt.Errorf("cache: duplicate hash field %q in %s", name, typ)
+		}
+		f := hashField{name: name, index: sf.Index}
+		c.fields = append(c.fields, f)
+		c.byName[name] = f
+	}
+
+	return c, nil
+}
+
+// viaPointer 字段是否从嵌入的结构体指针提升而来，这类字段不保存
+func viaPointer(typ reflect.Type, index []int) bool {
+	for _, i := range index[:len(index)-1] {
+		typ = typ.Field(i).Type
+		if typ.Kind() == reflect.Pointer {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldName 依次取 hash、json 标签，都没有时使用字段名
+func fieldName(sf reflect.StructField) string {
+	for _, tag := range []string{"hash", "json"} {
+		if v, ok := sf.Tag.Lookup(tag); ok {
+			if name, _, _ := strings.Cut(v, ","); name != "" {
+				return name
+			}
+		}
+	}
+	return sf.Name
+}
+
+// Set 写入对象的全部字段
+func (c *HashCache[T]) Set(ctx context.Context, params map[string]T) error {
+	return c.SetFields(ctx, params, nil)
+}
+
+// SetFields 只写入指定字段，其它字段保留原值，fields 为空时写入全部字段
+func (c *HashCache[T]) SetFields(ctx context.Context, params map[string]T, fields []string) error {
+	if err := c.lc.enter(); err != nil {
+		return err
+	}
+	defer c.lc.leave()
+
+	selected, err := c.selectFields(fields)
+	if err != nil {
+		return err
+	}
+
+	kv := make(map[string]map[string][]byte, len(params))
+	for k, v := range params {
+		rv := reflect.ValueOf(&v).Elem()
+		if c.ptr {
+			if rv.IsNil() {
+				continue
+			}
+			rv = rv.Elem()
+		}
+
+		values := make(map[string][]byte, len(selected))
+		for _, f := range selected {
+			b, err := sonic.Marshal(rv.FieldByIndex(f.index).Interface())
+			if err != nil {
+				return err
+			}
+			values[f.name] = b
+		}
+		kv[c.opts.buildKey(k)] = values
+	}
+
+	return callTier(ctx, 0, "set", c.opts.Timeouts.Set, func(ctx context.Context) error {
+		return c.handler.HSet(ctx, kv, c.opts.Expire)
+	})
+}
+
+// Get 查询对象的全部字段
+func (c *HashCache[T]) Get(ctx context.Context, keys []string) (map[string]T, error) {
+	return c.GetFields(ctx, keys, nil)
+}
+
+// GetFields 只查询指定字段，其它字段为零值，fields 为空时查询全部字段
+func (c *HashCache[T]) GetFields(ctx context.Context, keys []string, fields []string) (map[string]T, error) {
+	if err := c.lc.enter(); err != nil {
+		return nil, err
+	}
+	defer c.lc.leave()
+
+	if _, err := c.selectFields(fields); err != nil {
+		return nil, err
+	}
+
+	var kv map[string]map[string][]byte
+	err := callTier(ctx, 0, "get", c.opts.Timeouts.Get, func(ctx context.Context) (err error) {
+		kv, err = c.handler.HGet(ctx, c.opts.buildKeys(keys), fields)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]T, len(kv))
+	for k, values := range kv {
+		var (
+			obj T
+			rv  = reflect.ValueOf(&obj).Elem()
+		)
+		if c.ptr {
+			rv.Set(reflect.New(c.typ))
+			rv = rv.Elem()
+		}
+		for name, b := range values {
+			f, ok := c.byName[name]
+			if !ok {
+				continue // 结构体已删除的字段
+			}
+			err = sonic.Unmarshal(b, rv.FieldByIndex(f.index).Addr().Interface())
+			if err != nil {
+				return nil, err
+			}
+		}
+		out[c.opts.splitKey(k)] = obj
+	}
+
+	return out, nil
+}
+
+// Del 删除整个对象
+func (c *HashCache[T]) Del(ctx context.Context, keys []string) error {
+	if err := c.lc.enter(); err != nil {
+		return err
+	}
+	defer c.lc.leave()
+
+	return callTier(ctx, 0, "del", c.opts.Timeouts.Del, func(ctx context.Context) error {
+		return c.handler.Del(ctx, c.opts.buildKeys(keys))
+	})
+}
+
+// Close 拒绝新的调用并在ctx到期前等待进行中的调用结束，之后关闭适配器
+func (c *HashCache[T]) Close(ctx context.Context) error {
+	err := c.lc.shutdown(ctx)
+	if errors.Is(err, ErrClosed) {
+		return err
+	}
+
+	return errors.Join(err, c.handler.Close())
+}
+
+// selectFields 检查字段名，fields 为空时返回全部字段
+func (c *HashCache[T]) selectFields(fields []string) ([]hashField, error) {
+	if len(fields) == 0 {
+		return c.fields, nil
+	}
+
+	selected := make([]hashField, 0, len(fields))
+	for _, name := range fields {
+		f, ok := c.byName[name]
+		if !ok {
+			return nil, fmt.Errorf("cache: unknown hash field %q", name)
+		}
+		selected = append(selected, f)
+	}
+	return selected, nil
+}
@@ -0,0 +1,67 @@
+package tmpcache
+
+import (
+	"context"
+	"errors"
+	"github.com/PycMono/go-cache/client"
+	"github.com/PycMono/go-cache/client/breaker"
+	"testing"
+	"time"
+)
+
+func TestHashCache(t *testing.T) {
+	type profile struct {
+		Name   string   `json:"name"`
+		Age    int      `hash:"age"`
+		Tags   []string `json:"tags,omitempty"`
+		Secret string   `json:"-"`
+	}
+	cache, err := NewHashCache[*profile](newMemAdaptor(1024*1024), &HashOptions{Base: Base{Prefix: "demo"}, Expire: time.Minute})
+	if err != nil {
+		panic(err)
+	}
+
+	err = cache.Set(context.TODO(), map[string]*profile{"1": {Name: "111", Age: 20, Tags: []string{"a"}, Secret: "x"}})
+	if err != nil {
+		panic(err)
+	}
+
+	// 只更新一个字段，其它字段保留
+	err = cache.SetFields(context.TODO(), map[string]*profile{"1": {Age: 21}}, []string{"age"})
+	if err != nil {
+		panic(err)
+	}
+	kvMap, err := cache.Get(context.TODO(), []string{"1", "2"})
+	if err != nil {
+		panic(err)
+	}
+	if len(kvMap) != 1 || kvMap["1"].Name != "111" || kvMap["1"].Age != 21 || len(kvMap["1"].Tags) != 1 || kvMap["1"].Secret != "" {
+		t.Fatalf("kvMap = %+v", kvMap["1"])
+	}
+
+	// 只查询部分字段
+	kvMap, err = cache.GetFields(context.TODO(), []string{"1"}, []string{"name"})
+	if err != nil {
+		panic(err)
+	}
+	if kvMap["1"].Name != "111" || kvMap["1"].Age != 0 {
+		t.Fatalf("kvMap = %+v, want only name", kvMap["1"])
+	}
+
+	if _, err = cache.GetFields(context.TODO(), []string{"1"}, []string{"unknown"}); err == nil {
+		t.Fatalf("want unknown field error")
+	}
+	if _, err = NewHashCache[int](newMemAdaptor(1024*1024), &HashOptions{}); err == nil {
+		t.Fatalf("want non-struct error")
+	}
+
+	// 熔断包装不支持按字段读写的适配器
+	conf := breaker.Config{}
+	plain, err := breaker.NewBreakerAdaptor(struct{ client.IAdaptor }{newMemAdaptor(1024 * 1024)}, &conf)
+	if err != nil {
+		panic(err)
+	}
+	if _, err = NewHashCache[*profile](plain, &HashOptions{}); !errors.Is(err, client.ErrNotSupported) {
+		t.Fatalf("err = %v, want ErrNotSupported", err)
+	}
+}
@@ -1,6 +1,28 @@
 package tmpcache
 
-import "context"
+import (
+	"context"
+	"github.com/PycMono/go-cache/client"
+	"time"
+)
+
+// NoExpire 表示数据没有设置过期时间
+const NoExpire = client.NoExpire
+
+// Item 数据及剩余过期时间
+type Item[T any] struct {
+	Value T
+	TTL   time.Duration // 剩余过期时间，不过期为 NoExpire
+}
+
+// Versioned 数据及版本号
+type Versioned[T any] struct {
+	Value T
+	// 序列化后数据的sha1摘要，见 client.Version
+	// 版本号由数据内容决定而不是每次写入递增：数据从A改成B再改回A后版本号和原来相同，CompareAndSwap 仍会成功（ABA）
+	// 需要检测中间发生过写入时，在T中自行维护递增的版本字段
+	Version string
+}
 
 // ICache 管理器接口
 type ICache[T any] interface {
@@ -9,4 +31,39 @@ type ICache[T any] interface {
 	GetAndSet(ctx context.Context, keys []string, f func(keys []string) (map[string]T, error)) (map[string]T, error)
 	GetAndSetSingle(ctx context.Context, k string, f func(k string) (T, bool, error)) (T, bool, error)
 	Del(ctx context.Context, keys []string) error
+	// Close 关闭缓存：拒绝新的调用，在ctx到期前等待进行中的调用结束，然后关闭底层适配器，关闭后再调用返回 ErrClosed
+	Close(ctx context.Context) error
+	// GetWithTTL 查询数据及剩余过期时间，多级缓存返回第一个命中的缓存中的剩余时间，不回写上一级缓存
+	GetWithTTL(ctx context.Context, keys []string) (map[string]Item[T], error)
+	// TTL 查询剩余过期时间，不存在的key不返回，多级缓存返回第一个命中的缓存中的剩余时间
+	TTL(ctx context.Context, keys []string) (map[string]time.Duration, error)
+	// Touch 把过期时间重新设置为配置的 Expire，不重写数据
+	Touch(ctx context.Context, keys []string) error
+	// Expire 重新设置过期时间，不重写数据，expire 小于等于0表示不过期，多级缓存对所有缓存生效
+	Expire(ctx context.Context, keys []string, expire time.Duration) error
+	// GetWithVersion 查询数据及版本号，多级缓存只查询最后一级缓存，上一级缓存的数据可能是旧的
+	GetWithVersion(ctx context.Context, keys []string) (map[string]Versioned[T], error)
+	// SetIfAbsent key不存在时才写入，返回每个key是否写入
+	// 多级缓存只在最后一级缓存判断和写入，写入成功的key从其它缓存中删除，不写入其它缓存
+	SetIfAbsent(ctx context.Context, params map[string]T) (map[string]bool, error)
+	// SetIfPresent key存在时才写入，返回每个key是否写入，多级缓存的行为同 SetIfAbsent
+	SetIfPresent(ctx context.Context, params map[string]T) (map[string]bool, error)
+	// CompareAndSwap 当前数据的版本号等于 Versioned.Version 时才写入，版本号为空表示期望key不存在，返回每个key是否写入
+	// 版本号按数据内容比较，不能发现 A→B→A 的中间写入，见 Versioned.Version
+	// 多级缓存只在最后一级缓存比较和写入，写入成功的key从其它缓存中删除，不写入其它缓存
+	CompareAndSwap(ctx context.Context, params map[string]Versioned[T]) (map[string]bool, error)
+	// SetWithTags 写入数据并给key打上标签，tags 为 key→标签，至少一级缓存需要实现 client.ITagAdaptor
+	SetWithTags(ctx context.Context, params map[string]T, tags map[string][]string) error
+	// DelByTag 删除标签下的全部key及标签索引，多级缓存从所有支持标签的缓存中查询key，再从所有缓存中删除
+	DelByTag(ctx context.Context, tags []string) error
+	// Scan 按 redis glob 模式遍历key，返回的key不带前缀，多级缓存遍历最后一级缓存
+	// Close 等待读取中的批次结束，关闭后迭代器返回 ErrClosed
+	Scan(ctx context.Context, pattern string) client.KeyIterator
+	// DelPrefix 删除以prefix开头的key，多级缓存从所有缓存中删除，返回最后一级缓存删除的数量
+	DelPrefix(ctx context.Context, prefix string) (int64, error)
+	// Invalidate 从所有缓存中删除key，并在 InvalidateOptions.Delay 后再删除一次，删除失败按 RetryInterval 重试直到成功
+	Invalidate(ctx context.Context, keys []string) error
+	// UpdateAndInvalidate 延迟双删：删除缓存、执行 update（例如更新数据库）、再次删除，并安排延迟删除
+	// 第一次删除失败不执行 update；update 成功后的删除失败只记录到重试队列，返回nil
+	UpdateAndInvalidate(ctx context.Context, keys []string, update func(ctx context.Context) error) error
 }
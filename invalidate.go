@@ -0,0 +1,389 @@
+package tmpcache
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/bytedance/sonic"
+	"os"
+	"sync"
+	"time"
+)
+
+// InvalidateOptions 延迟双删配置
+type InvalidateOptions struct {
+	Delay         time.Duration // 第二次删除的延迟，应大于主从同步延迟加一次读请求的耗时，默认500ms
+	RetryInterval time.Duration // 删除失败后的重试间隔，默认1秒，重试直到成功
+	// 持久化重试队列文件路径，为空时待删除的key只保存在内存中
+	// 设置后创建缓存时从文件恢复上次未完成的删除，Close 时未完成的删除保留在文件中
+	RetryQueue string
+}
+
+func (o InvalidateOptions) delay() time.Duration {
+	if o.Delay <= 0 {
+		return 500 * time.Millisecond
+	}
+	return o.Delay
+}
+
+func (o InvalidateOptions) retryInterval() time.Duration {
+	if o.RetryInterval <= 0 {
+		return time.Second
+	}
+	return o.RetryInterval
+}
+
+// pendingDel 持久化队列中的一条待删除记录
+type pendingDel struct {
+	Key string `json:"key"`
+	Due int64  `json:"due"` // 计划删除时间（UnixNano），0表示已删除
+}
+
+// invalidator 执行延迟删除和失败重试，key 为已经构造好的完整key
+type invalidator struct {
+	opts  InvalidateOptions
+	del   func(ctx context.Context, tmpKeys []string) error // 立即删除，按各级缓存的出错策略返回错误
+	retry func(ctx context.Context, tmpKeys []string) error // 延迟删除，任何一级删除失败（包括熔断打开）都返回错误
+
+	once    sync.Once
+	sm      sync.Mutex
+	queue   map[string]int64 // key→计划删除时间，同一个key只保留最晚的一次
+	file    *os.File         // 持久化文件，追加写入队列变化
+	records int              // 持久化文件中的记录数，超过队列长度的2倍时压缩
+	closed  bool
+	wake    chan struct{}
+	done    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// newInvalidator 设置了持久化队列时立即恢复并开始执行，否则第一次使用时启动
+func newInvalidator(opts InvalidateOptions, del, retry func(ctx context.Context, tmpKeys []string) error) *invalidator {
+	inv := &invalidator{
+		opts:  opts,
+		del:   del,
+		retry: retry,
+		queue: make(map[string]int64),
+		wake:  make(chan struct{}, 1),
+		done:  make(chan struct{}),
+	}
+	if opts.RetryQueue != "" {
+		inv.start()
+	}
+	return inv
+}
+
+func (inv *invalidator) start() {
+	inv.once.Do(func() {
+		if inv.opts.RetryQueue != "" {
+			if err := inv.load(); err != nil {
+				fmt.Println(err)
+			}
+			inv.sm.Lock()
+			inv.compact()
+			inv.sm.Unlock()
+		}
+		inv.wg.Add(1)
+		go inv.loop()
+	})
+}
+
+// invalidate 立即删除，并安排一次延迟删除，立即删除失败时由延迟删除重试
+func (inv *invalidator) invalidate(ctx context.Context, tmpKeys []string) error {
+	err := inv.del(ctx, tmpKeys)
+	inv.schedule(tmpKeys, time.Now().Add(inv.opts.delay()))
+	return err
+}
+
+// schedule 安排key在at之后删除
+func (inv *invalidator) schedule(tmpKeys []string, at time.Time) {
+	inv.start()
+
+	inv.sm.Lock()
+	defer inv.sm.Unlock()
+
+	if inv.closed {
+		return
+	}
+	records := make([]pendingDel, 0, len(tmpKeys))
+	for _, k := range tmpKeys {
+		inv.queue[k] = max(inv.queue[k], at.UnixNano())
+		records = append(records, pendingDel{Key: k, Due: inv.queue[k]})
+	}
+	inv.persist(records)
+
+	select {
+	case inv.wake <- struct{}{}:
+	default:
+	}
+}
+
+// pending 队列中待删除的key数量
+func (inv *invalidator) pending() int {
+	inv.sm.Lock()
+	defer inv.sm.Unlock()
+
+	return len(inv.queue)
+}
+
+// close 停止后台删除，没有持久化队列时立即删除剩余的key
+func (inv *invalidator) close(ctx context.Context) error {
+	inv.sm.Lock()
+	if inv.closed {
+		inv.sm.Unlock()
+		return nil
+	}
+	inv.closed = true
+	inv.sm.Unlock()
+
+	close(inv.done)
+	inv.wg.Wait()
+
+	if inv.opts.RetryQueue != "" {
+		inv.sm.Lock()
+		defer inv.sm.Unlock()
+		inv.compact()
+		if inv.file == nil {
+			return nil
+		}
+		return inv.file.Close()
+	}
+	keys := make([]string, 0, len(inv.queue))
+	for k := range inv.queue {
+		keys = append(keys, k)
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	return inv.del(ctx, keys)
+}
+
+func (inv *invalidator) loop() {
+	defer inv.wg.Done()
+
+	timer := time.NewTimer(0) // 先处理从持久化文件恢复的key
+	defer timer.Stop()
+	for {
+		select {
+		case <-timer.C:
+		case <-inv.wake:
+		case <-inv.done:
+			return
+		}
+
+		next := inv.run()
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(next)
+	}
+}
+
+// run 删除到期的key，失败的key按 RetryInterval 重新安排，返回距离下一个到期的时间
+func (inv *invalidator) run() time.Duration {
+	inv.sm.Lock()
+	var (
+		now  = time.Now().UnixNano()
+		due  = make(map[string]int64)
+		keys []string
+	)
+	for k, at := range inv.queue {
+		if at <= now {
+			due[k] = at
+			keys = append(keys, k)
+		}
+	}
+	inv.sm.Unlock()
+
+	if len(keys) > 0 {
+		err := inv.retry(context.Background(), keys)
+
+		inv.sm.Lock()
+		var (
+			retryAt = time.Now().Add(inv.opts.retryInterval()).UnixNano()
+			records = make([]pendingDel, 0, len(due))
+		)
+		for k, at := range due {
+			if inv.queue[k] != at {
+				continue // 等待期间重新安排过
+			}
+			if err != nil {
+				inv.queue[k] = retryAt
+				records = append(records, pendingDel{Key: k, Due: retryAt})
+				continue
+			}
+			delete(inv.queue, k)
+			records = append(records, pendingDel{Key: k})
+		}
+		inv.persist(records)
+		inv.sm.Unlock()
+		if err != nil {
+			fmt.Println(err)
+		}
+	}
+
+	inv.sm.Lock()
+	defer inv.sm.Unlock()
+	next := inv.opts.delay()
+	now = time.Now().UnixNano()
+	for _, at := range inv.queue {
+		next = min(next, time.Duration(at-now))
+	}
+	return max(next, time.Millisecond)
+}
+
+// persist 把队列变化追加到持久化文件，记录数超过队列长度的2倍时压缩，调用方需要持有 inv.sm
+func (inv *invalidator) persist(records []pendingDel) {
+	if inv.opts.RetryQueue == "" || len(records) == 0 {
+		return
+	}
+	if inv.file == nil || inv.records+len(records) > 2*len(inv.queue)+64 {
+		inv.compact()
+		return
+	}
+
+	err := func() error {
+		w := bufio.NewWriter(inv.file)
+		for _, d := range records {
+			b, err := sonic.Marshal(d)
+			if err != nil {
+				return err
+			}
+			_, _ = w.Write(b)
+			_ = w.WriteByte('\n')
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		return inv.file.Sync()
+	}()
+	inv.records += len(records)
+	if err != nil {
+		fmt.Println(err)
+	}
+}
+
+// compact 把当前队列重写到持久化文件并重新打开用于追加，调用方需要持有 inv.sm
+func (inv *invalidator) compact() {
+	if inv.opts.RetryQueue == "" {
+		return
+	}
+	if inv.file != nil {
+		_ = inv.file.Close()
+		inv.file = nil
+	}
+
+	err := func() error {
+		tmp := inv.opts.RetryQueue + ".tmp"
+		f, err := os.Create(tmp)
+		if err != nil {
+			return err
+		}
+		w := bufio.NewWriter(f)
+		for k, at := range inv.queue {
+			b, err := sonic.Marshal(pendingDel{Key: k, Due: at})
+			if err != nil {
+				_ = f.Close()
+				return err
+			}
+			_, _ = w.Write(b)
+			_ = w.WriteByte('\n')
+		}
+		if err = errors.Join(w.Flush(), f.Sync(), f.Close()); err != nil {
+			return err
+		}
+		if err = os.Rename(tmp, inv.opts.RetryQueue); err != nil {
+			return err
+		}
+		inv.file, err = os.OpenFile(inv.opts.RetryQueue, os.O_WRONLY|os.O_APPEND, 0o644)
+		return err
+	}()
+	inv.records = len(inv.queue)
+	if err != nil {
+		fmt.Println(err)
+	}
+}
+
+// load 从持久化文件回放队列变化，文件不存在时忽略
+// 不完整的记录（写入过程中进程退出）直接跳过，恢复后立即压缩重写文件
+func (inv *invalidator) load() error {
+	f, err := os.Open(inv.opts.RetryQueue)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	inv.sm.Lock()
+	defer inv.sm.Unlock()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var d pendingDel
+		if sonic.Unmarshal(scanner.Bytes(), &d) != nil {
+			continue
+		}
+		if d.Due == 0 {
+			delete(inv.queue, d.Key)
+			continue
+		}
+		inv.queue[d.Key] = d.Due
+	}
+	return scanner.Err()
+}
+
+// updateAndInvalidate 删除缓存、执行更新、再次删除，并安排延迟删除
+// 第一次删除失败直接返回不执行更新；更新成功后的删除失败由延迟删除重试，不返回错误
+func (inv *invalidator) updateAndInvalidate(ctx context.Context, tmpKeys []string, update func(ctx context.Context) error) error {
+	if err := inv.del(ctx, tmpKeys); err != nil {
+		return err
+	}
+	if err := update(ctx); err != nil {
+		return err
+	}
+
+	if err := inv.invalidate(ctx, tmpKeys); err != nil {
+		fmt.Println(err)
+	}
+	return nil
+}
+
+func (c *Cache[T]) Invalidate(ctx context.Context, keys []string) error {
+	if err := c.lc.enter(); err != nil {
+		return err
+	}
+	defer c.lc.leave()
+
+	return c.inv.invalidate(ctx, c.opts.buildKeys(keys))
+}
+
+func (c *Cache[T]) UpdateAndInvalidate(ctx context.Context, keys []string, update func(ctx context.Context) error) error {
+	if err := c.lc.enter(); err != nil {
+		return err
+	}
+	defer c.lc.leave()
+
+	return c.inv.updateAndInvalidate(ctx, c.opts.buildKeys(keys), update)
+}
+
+func (c *MultiCache[T]) Invalidate(ctx context.Context, keys []string) error {
+	if err := c.lc.enter(); err != nil {
+		return err
+	}
+	defer c.lc.leave()
+
+	return c.inv.invalidate(ctx, c.opts.buildKeys(keys))
+}
+
+func (c *MultiCache[T]) UpdateAndInvalidate(ctx context.Context, keys []string, update func(ctx context.Context) error) error {
+	if err := c.lc.enter(); err != nil {
+		return err
+	}
+	defer c.lc.leave()
+
+	return c.inv.updateAndInvalidate(ctx, c.opts.buildKeys(keys), update)
+}
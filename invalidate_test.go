@@ -0,0 +1,202 @@
+package tmpcache
+
+import (
+	"context"
+	"errors"
+	"github.com/PycMono/go-cache/client"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// failDel 删除失败指定次数的适配器，err 为空时返回 del failed
+type failDel struct {
+	client.IAdaptor
+	fails atomic.Int64
+	err   error
+}
+
+func (f *failDel) Del(ctx context.Context, k []string) error {
+	if f.fails.Add(-1) >= 0 {
+		if f.err != nil {
+			return f.err
+		}
+		return errors.New("del failed")
+	}
+	return f.IAdaptor.Del(ctx, k)
+}
+
+// Close 不关闭被包装的适配器，便于多个缓存共用
+func (f *failDel) Close() error {
+	return nil
+}
+
+func waitFor(f func() bool) bool {
+	deadline := time.Now().Add(2 * time.Second)
+	for !f() {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return true
+}
+
+func TestUpdateAndInvalidate(t *testing.T) {
+	opts := &MultiCacheOptions{
+		Base:       Base{Prefix: "demo"},
+		Expire:     time.Minute,
+		Invalidate: InvalidateOptions{Delay: 20 * time.Millisecond, RetryInterval: 10 * time.Millisecond},
+	}
+	handler := &failDel{IAdaptor: newMemAdaptor(1024 * 1024)}
+	cache := NewMultiCacheWithLocal[string](opts, newLocalAdaptor[string](), handler)
+	ctx := context.TODO()
+
+	_ = cache.Set(ctx, map[string]string{"1": "old"})
+	err := cache.UpdateAndInvalidate(ctx, []string{"1"}, func(ctx context.Context) error {
+		// 更新期间并发的读请求把旧数据写回缓存
+		return cache.Set(ctx, map[string]string{"1": "old"})
+	})
+	if err != nil {
+		panic(err)
+	}
+	// 模拟读到从库旧数据后回写
+	_ = cache.Set(ctx, map[string]string{"1": "old"})
+	ok := waitFor(func() bool {
+		kvMap, _ := cache.Get(ctx, []string{"1"})
+		return len(kvMap) == 0
+	})
+	if !ok {
+		t.Fatalf("stale value not removed by delayed delete")
+	}
+
+	// 第一次删除失败不执行更新
+	handler.fails.Store(1)
+	err = cache.UpdateAndInvalidate(ctx, []string{"1"}, func(ctx context.Context) error {
+		t.Fatalf("update called after failed delete")
+		return nil
+	})
+	if err == nil {
+		t.Fatalf("want delete error")
+	}
+
+	// 删除失败后重试直到成功
+	_ = cache.Set(ctx, map[string]string{"2": "v"})
+	handler.fails.Store(3)
+	if err = cache.Invalidate(ctx, []string{"2"}); err == nil {
+		t.Fatalf("want delete error")
+	}
+	inv := cache.(*MultiCache[string]).inv
+	if !waitFor(func() bool { return inv.pending() == 0 }) {
+		t.Fatalf("pending = %d, want 0", inv.pending())
+	}
+	if kvMap, _ := cache.Get(ctx, []string{"2"}); len(kvMap) != 0 {
+		t.Fatalf("kvMap = %v, want empty", kvMap)
+	}
+	_ = cache.Close(ctx)
+}
+
+func TestInvalidateRetryQueue(t *testing.T) {
+	var (
+		mem  = newMemAdaptor(1024 * 1024)
+		opts = &Options{
+			Base:   Base{Prefix: "demo"},
+			Expire: time.Minute,
+			Invalidate: InvalidateOptions{
+				Delay:      10 * time.Millisecond,
+				RetryQueue: filepath.Join(t.TempDir(), "invalidate.queue"),
+			},
+		}
+		ctx = context.TODO()
+	)
+	handler := &failDel{IAdaptor: mem}
+	handler.fails.Store(1 << 30)
+	cache := NewCache[string](handler, opts)
+	_ = cache.Set(ctx, map[string]string{"1": "v"})
+	_ = cache.Invalidate(ctx, []string{"1"})
+	if err := cache.Close(ctx); err != nil {
+		panic(err)
+	}
+
+	// 重启后从持久化队列恢复并删除
+	cache = NewCache[string](&failDel{IAdaptor: mem}, opts)
+	inv := cache.(*Cache[string]).inv
+	if !waitFor(func() bool { return inv.pending() == 0 }) {
+		t.Fatalf("pending = %d, want 0", inv.pending())
+	}
+	if kvMap, _ := cache.Get(ctx, []string{"1"}); len(kvMap) != 0 {
+		t.Fatalf("kvMap = %v, want empty", kvMap)
+	}
+	_ = cache.Close(ctx)
+}
+
+func TestInvalidateCircuitOpen(t *testing.T) {
+	opts := &MultiCacheOptions{
+		Base:              Base{Prefix: "demo"},
+		Expire:            time.Minute,
+		TierFailurePolicy: []FailurePolicy{SkipAndContinue},
+		Invalidate:        InvalidateOptions{Delay: 10 * time.Millisecond, RetryInterval: 10 * time.Millisecond},
+	}
+	handler := &failDel{IAdaptor: newMemAdaptor(1024 * 1024), err: client.ErrCircuitOpen}
+	cache := NewMultiCache[string](opts, handler)
+	ctx := context.TODO()
+
+	// 熔断打开时立即删除按 SkipAndContinue 不返回错误，延迟删除保留key直到删除成功
+	_ = cache.Set(ctx, map[string]string{"1": "v"})
+	handler.fails.Store(1 << 30)
+	if err := cache.Invalidate(ctx, []string{"1"}); err != nil {
+		panic(err)
+	}
+	inv := cache.(*MultiCache[string]).inv
+	time.Sleep(50 * time.Millisecond)
+	if inv.pending() != 1 {
+		t.Fatalf("pending = %d, want 1 while circuit is open", inv.pending())
+	}
+
+	handler.fails.Store(0)
+	if !waitFor(func() bool { return inv.pending() == 0 }) {
+		t.Fatalf("pending = %d, want 0", inv.pending())
+	}
+	if kvMap, _ := cache.Get(ctx, []string{"1"}); len(kvMap) != 0 {
+		t.Fatalf("kvMap = %v, want empty", kvMap)
+	}
+	_ = cache.Close(ctx)
+}
+
+func TestInvalidateQueueCompact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "invalidate.queue")
+	// 模拟上次写入到一半退出，不完整的记录被跳过
+	if err := os.WriteFile(path, []byte(`{"key":"a","due":1}`+"\n"+`{"key":"b","due":1}`+"\n"+`{"key":"a","due":0}`+"\n"+`{"key":"c"`), 0o644); err != nil {
+		panic(err)
+	}
+	var deleted atomic.Int64
+	del := func(ctx context.Context, tmpKeys []string) error {
+		deleted.Add(int64(len(tmpKeys)))
+		return errors.New("del failed")
+	}
+	inv := newInvalidator(InvalidateOptions{Delay: time.Hour, RetryInterval: time.Hour, RetryQueue: path}, del, del)
+	if !waitFor(func() bool { return deleted.Load() == 1 }) || inv.pending() != 1 {
+		t.Fatalf("deleted = %d, pending = %d, want b only", deleted.Load(), inv.pending())
+	}
+
+	// 同一个key反复安排只追加记录，记录过多时压缩
+	for i := 0; i < 1000; i++ {
+		inv.schedule([]string{"b"}, time.Now().Add(time.Hour))
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		panic(err)
+	}
+	if lines := strings.Count(string(b), "\n"); lines > 2*inv.pending()+64 {
+		t.Fatalf("lines = %d, want compacted", lines)
+	}
+	if err = inv.close(context.TODO()); err != nil {
+		panic(err)
+	}
+	if b, _ = os.ReadFile(path); strings.Count(string(b), "\n") != 1 {
+		t.Fatalf("queue file = %s, want b only", b)
+	}
+}
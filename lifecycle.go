@@ -0,0 +1,74 @@
+package tmpcache
+
+import (
+	"context"
+	"github.com/PycMono/go-cache/client"
+	"sync"
+)
+
+// ErrClosed 缓存关闭后继续调用返回该错误，与 client.ErrClosed 为同一个值，可直接用 errors.Is 判断
+var ErrClosed = client.ErrClosed
+
+// lifecycle 记录缓存是否关闭以及进行中的调用，关闭时等待进行中的调用（包括外部加载函数）结束
+type lifecycle struct {
+	sm     sync.RWMutex
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// enter 进入一次调用，已关闭返回 ErrClosed，成功后必须调用 leave
+func (l *lifecycle) enter() error {
+	l.sm.RLock()
+	defer l.sm.RUnlock()
+
+	if l.closed {
+		return ErrClosed
+	}
+	l.wg.Add(1)
+	return nil
+}
+
+// leave 结束一次调用
+func (l *lifecycle) leave() {
+	l.wg.Done()
+}
+
+// shutdown 标记关闭并等待进行中的调用结束，ctx 到期直接返回 ctx.Err()，重复调用返回 ErrClosed
+func (l *lifecycle) shutdown(ctx context.Context) error {
+	l.sm.Lock()
+	if l.closed {
+		l.sm.Unlock()
+		return ErrClosed
+	}
+	l.closed = true
+	l.sm.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		l.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// iterator 迭代器每读取一批key进入一次调用：关闭时等待读取中的批次结束，关闭后返回 ErrClosed，不再调用适配器
+// 不在整个遍历期间保持进入状态，调用方中途放弃迭代器时不会阻塞关闭
+func (l *lifecycle) iterator(it client.KeyIterator) client.KeyIterator {
+	return client.NewKeyIterator(func(ctx context.Context) ([]string, bool, error) {
+		if err := l.enter(); err != nil {
+			return nil, true, err
+		}
+		defer l.leave()
+
+		if !it.Next(ctx) {
+			return nil, true, it.Err()
+		}
+		return it.Keys(), false, nil
+	})
+}
@@ -0,0 +1,78 @@
+package tmpcache
+
+import (
+	"context"
+	"github.com/PycMono/go-cache/client"
+	"time"
+)
+
+// ListCache 列表缓存，对应 redis LIST，适合最近动态之类按插入顺序读取的数据
+type ListCache[T any] struct {
+	collectionBase[T]
+}
+
+func NewListCache[T any](handler client.ICollectionAdaptor, opts *CollectionOptions) *ListCache[T] {
+	return &ListCache[T]{collectionBase: collectionBase[T]{handler: handler, opts: opts}}
+}
+
+// LPush 依次插入到头部，返回插入后的长度
+func (c *ListCache[T]) LPush(ctx context.Context, key string, values ...T) (int64, error) {
+	return c.push(ctx, key, values, c.handler.LPush)
+}
+
+// RPush 依次插入到尾部，返回插入后的长度
+func (c *ListCache[T]) RPush(ctx context.Context, key string, values ...T) (int64, error) {
+	return c.push(ctx, key, values, c.handler.RPush)
+}
+
+// Range 返回 [start, stop] 范围内的元素，负数表示从末尾开始计算
+func (c *ListCache[T]) Range(ctx context.Context, key string, start, stop int64) ([]T, error) {
+	var values [][]byte
+	err := c.read(ctx, func(ctx context.Context) (err error) {
+		values, err = c.handler.LRange(ctx, c.opts.buildKey(key), start, stop)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c.unmarshal(values)
+}
+
+// Page 分页读取，page 从1开始
+func (c *ListCache[T]) Page(ctx context.Context, key string, page, size int64) ([]T, error) {
+	start, stop, err := pageRange(page, size)
+	if err != nil {
+		return nil, err
+	}
+	return c.Range(ctx, key, start, stop)
+}
+
+// Trim 只保留 [start, stop] 范围内的元素，常和 LPush 一起使用限制列表长度
+func (c *ListCache[T]) Trim(ctx context.Context, key string, start, stop int64) error {
+	return c.write(ctx, func(ctx context.Context) error {
+		return c.handler.LTrim(ctx, c.opts.buildKey(key), start, stop)
+	})
+}
+
+func (c *ListCache[T]) Len(ctx context.Context, key string) (int64, error) {
+	var n int64
+	err := c.read(ctx, func(ctx context.Context) (err error) {
+		n, err = c.handler.LLen(ctx, c.opts.buildKey(key))
+		return err
+	})
+	return n, err
+}
+
+func (c *ListCache[T]) push(ctx context.Context, key string, values []T, f func(ctx context.Context, key string, values [][]byte, expire time.Duration) (int64, error)) (int64, error) {
+	b, err := c.marshal(values)
+	if err != nil {
+		return 0, err
+	}
+
+	var n int64
+	err = c.write(ctx, func(ctx context.Context) (err error) {
+		n, err = f(ctx, c.opts.buildKey(key), b, c.opts.Expire)
+		return err
+	})
+	return n, err
+}
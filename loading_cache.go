@@ -0,0 +1,275 @@
+package tmpcache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LoaderOptions 单个加载函数的限制
+type LoaderOptions struct {
+	Concurrency int           // 同时执行的加载函数数量上限，0表示不限制，等待期间ctx结束直接返回
+	Timeout     time.Duration // 单次加载的超时，0表示不限制，超时返回 *TimeoutError
+}
+
+// LoadingOptions LoadingCache 配置，BatchLoader、SingleLoader 至少设置一个
+type LoadingOptions[T any] struct {
+	// BatchLoader 批量加载缓存miss的key，返回结果中不存在的key视为数据不存在
+	BatchLoader func(keys []string) (map[string]T, error)
+	// SingleLoader 加载单个key，同一个key并发加载时只调用一次
+	SingleLoader func(key string) (T, bool, error)
+	Batch        LoaderOptions
+	Single       LoaderOptions
+}
+
+// LoaderStats 加载函数的统计数据
+type LoaderStats struct {
+	Loads    uint64        // 调用次数
+	Errors   uint64        // 返回错误的次数（包括超时）
+	Timeouts uint64        // 超时次数
+	Rejected uint64        // 等待并发名额时ctx结束的次数
+	Keys     uint64        // 请求加载的key数量
+	Found    uint64        // 加载到数据的key数量
+	InFlight int64         // 正在执行的加载函数数量
+	Duration time.Duration // 加载总耗时
+}
+
+// LoadingStats LoadingCache 的统计数据
+type LoadingStats struct {
+	Batch  LoaderStats
+	Single LoaderStats
+}
+
+// loader 加载函数的并发限制和统计
+type loader struct {
+	sem     chan struct{}
+	timeout time.Duration
+
+	loads, errors, timeouts, rejected, keys, found atomic.Uint64
+	inFlight, duration                             atomic.Int64
+}
+
+func newLoader(opts LoaderOptions) *loader {
+	l := &loader{timeout: opts.Timeout}
+	if opts.Concurrency > 0 {
+		l.sem = make(chan struct{}, opts.Concurrency)
+	}
+	return l
+}
+
+func (l *loader) stats() LoaderStats {
+	return LoaderStats{
+		Loads:    l.loads.Load(),
+		Errors:   l.errors.Load(),
+		Timeouts: l.timeouts.Load(),
+		Rejected: l.rejected.Load(),
+		Keys:     l.keys.Load(),
+		Found:    l.found.Load(),
+		InFlight: l.inFlight.Load(),
+		Duration: time.Duration(l.duration.Load()),
+	}
+}
+
+// LoadingCache 注册了加载函数的缓存，Get 自动加载缓存miss的key，其它方法直接调用底层缓存
+type LoadingCache[T any] struct {
+	ICache[T]
+	opts   *LoadingOptions[T]
+	batch  *loader
+	single *loader
+	lc     lifecycle
+}
+
+// NewLoadingCache cache 可以是 Cache 或 MultiCache，关闭 LoadingCache 时一起关闭
+func NewLoadingCache[T any](cache ICache[T], opts *LoadingOptions[T]) (*LoadingCache[T], error) {
+	if opts.BatchLoader == nil && opts.SingleLoader == nil {
+		return nil, errors.New("cache: LoadingCache requires BatchLoader or SingleLoader")
+	}
+
+	return &LoadingCache[T]{
+		ICache: cache,
+		opts:   opts,
+		batch:  newLoader(opts.Batch),
+		single: newLoader(opts.Single),
+	}, nil
+}
+
+// Get 查询数据，缓存miss的key通过加载函数加载并写入缓存
+// 设置了 BatchLoader 时批量加载，否则每个key并发调用 SingleLoader
+func (c *LoadingCache[T]) Get(ctx context.Context, keys []string) (map[string]T, error) {
+	if err := c.lc.enter(); err != nil {
+		return nil, err
+	}
+	defer c.lc.leave()
+
+	if c.opts.BatchLoader != nil {
+		return c.ICache.GetAndSet(ctx, keys, func(keys []string) (map[string]T, error) {
+			return c.loadBatch(ctx, keys)
+		})
+	}
+	return c.getSingles(ctx, keys)
+}
+
+// GetSingle 查询单个key，设置了 SingleLoader 时使用单个加载，否则使用 BatchLoader
+func (c *LoadingCache[T]) GetSingle(ctx context.Context, key string) (T, bool, error) {
+	if err := c.lc.enter(); err != nil {
+		var zero T
+		return zero, false, err
+	}
+	defer c.lc.leave()
+
+	if c.opts.SingleLoader == nil {
+		kv, err := c.ICache.GetAndSet(ctx, []string{key}, func(keys []string) (map[string]T, error) {
+			return c.loadBatch(ctx, keys)
+		})
+		val, ok := kv[key]
+		return val, ok, err
+	}
+	return c.getSingle(ctx, key)
+}
+
+// Stats 加载函数的统计数据
+func (c *LoadingCache[T]) Stats() LoadingStats {
+	return LoadingStats{
+		Batch:  c.batch.stats(),
+		Single: c.single.stats(),
+	}
+}
+
+// Close 拒绝新的调用并等待进行中的加载结束，之后关闭底层缓存
+func (c *LoadingCache[T]) Close(ctx context.Context) error {
+	err := c.lc.shutdown(ctx)
+	if errors.Is(err, ErrClosed) {
+		return err
+	}
+
+	return errors.Join(err, c.ICache.Close(ctx))
+}
+
+func (c *LoadingCache[T]) getSingle(ctx context.Context, key string) (T, bool, error) {
+	return c.ICache.GetAndSetSingle(ctx, key, func(key string) (T, bool, error) {
+		res, err := load(ctx, &c.lc, c.single, 1, func() (single[T], error) {
+			val, ok, err := c.opts.SingleLoader(key)
+			return single[T]{val: val, ok: ok}, err
+		}, func(res single[T]) int {
+			if res.ok {
+				return 1
+			}
+			return 0
+		})
+		return res.val, res.ok, err
+	})
+}
+
+// getSingles 先批量查询缓存，miss的key再单独加载，同时加载的goroutine数量受 Single.Concurrency 限制，返回第一个错误
+// BestEffort 策略下查询缓存的错误和 GetAndSet 一样按miss处理，最后一起返回
+func (c *LoadingCache[T]) getSingles(ctx context.Context, keys []string) (map[string]T, error) {
+	out, tierErr := c.ICache.Get(ctx, keys)
+	if out == nil {
+		if tierErr != nil {
+			return nil, tierErr
+		}
+		out = make(map[string]T, len(keys))
+	}
+
+	var (
+		sm       sync.Mutex
+		wg       sync.WaitGroup
+		sem      chan struct{}
+		missKeys []string
+		firstErr error
+	)
+	for _, key := range keys {
+		if _, ok := out[key]; !ok {
+			missKeys = append(missKeys, key)
+		}
+	}
+	if n := c.opts.Single.Concurrency; n > 0 {
+		sem = make(chan struct{}, n)
+	}
+	for _, key := range missKeys {
+		if sem != nil {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				wg.Wait()
+				return nil, ctx.Err()
+			}
+		}
+
+		wg.Add(1)
+		go func(key string) {
+			defer wg.Done()
+			if sem != nil {
+				defer func() { <-sem }()
+			}
+
+			val, ok, err := c.getSingle(ctx, key)
+			sm.Lock()
+			defer sm.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				return
+			}
+			if ok {
+				out[key] = val
+			}
+		}(key)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return out, tierErr
+}
+
+func (c *LoadingCache[T]) loadBatch(ctx context.Context, keys []string) (map[string]T, error) {
+	return load(ctx, &c.lc, c.batch, len(keys), func() (map[string]T, error) {
+		return c.opts.BatchLoader(keys)
+	}, func(res map[string]T) int {
+		return len(res)
+	})
+}
+
+// load 在并发限制和超时限制内调用加载函数并记录统计，并发名额在加载函数真正返回后才释放
+func load[R any](ctx context.Context, lc *lifecycle, l *loader, keys int, f func() (R, error), found func(R) int) (R, error) {
+	var zero R
+	if l.sem != nil {
+		select {
+		case l.sem <- struct{}{}:
+		case <-ctx.Done():
+			l.rejected.Add(1)
+			return zero, ctx.Err()
+		}
+	}
+
+	l.loads.Add(1)
+	l.keys.Add(uint64(keys))
+	start := time.Now()
+	res, err := runLoader(ctx, lc, l.timeout, func() (R, error) {
+		l.inFlight.Add(1)
+		defer func() {
+			l.inFlight.Add(-1)
+			if l.sem != nil {
+				<-l.sem
+			}
+		}()
+		return f()
+	})
+	l.duration.Add(int64(time.Since(start)))
+
+	if err != nil {
+		l.errors.Add(1)
+		var timeoutErr *TimeoutError
+		if errors.As(err, &timeoutErr) {
+			l.timeouts.Add(1)
+		}
+		return zero, err
+	}
+	l.found.Add(uint64(found(res)))
+	return res, nil
+}
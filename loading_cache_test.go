@@ -0,0 +1,138 @@
+package tmpcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLoadingCache(t *testing.T) {
+	var calls atomic.Int64
+	cache, err := NewLoadingCache[string](
+		NewCache[string](newMemAdaptor(1024*1024), &Options{Base: Base{Prefix: "demo"}, Expire: time.Minute}),
+		&LoadingOptions[string]{
+			BatchLoader: func(keys []string) (map[string]string, error) {
+				calls.Add(1)
+				out := make(map[string]string)
+				for _, k := range keys {
+					if k != "404" {
+						out[k] = "v" + k
+					}
+				}
+				return out, nil
+			},
+		})
+	if err != nil {
+		panic(err)
+	}
+	ctx := context.TODO()
+
+	kvMap, err := cache.Get(ctx, []string{"1", "2", "404"})
+	if err != nil {
+		panic(err)
+	}
+	if len(kvMap) != 2 || kvMap["1"] != "v1" {
+		t.Fatalf("kvMap = %v, want 1 and 2", kvMap)
+	}
+	// 已加载的key从缓存读取
+	if _, err = cache.Get(ctx, []string{"1", "2"}); err != nil {
+		panic(err)
+	}
+	if val, ok, _ := cache.GetSingle(ctx, "3"); !ok || val != "v3" {
+		t.Fatalf("GetSingle = %q, %v, want v3", val, ok)
+	}
+	if n := calls.Load(); n != 2 {
+		t.Fatalf("calls = %d, want 2", n)
+	}
+
+	stats := cache.Stats().Batch
+	if stats.Loads != 2 || stats.Keys != 4 || stats.Found != 3 {
+		t.Fatalf("stats = %+v", stats)
+	}
+
+	if err = cache.Close(ctx); err != nil {
+		panic(err)
+	}
+	if _, err = cache.Get(ctx, []string{"1"}); !errors.Is(err, ErrClosed) {
+		t.Fatalf("err = %v, want ErrClosed", err)
+	}
+
+	if _, err = NewLoadingCache[string](cache, &LoadingOptions[string]{}); err == nil {
+		t.Fatalf("want error without loader")
+	}
+}
+
+func TestLoadingCacheLimits(t *testing.T) {
+	var (
+		running, peak atomic.Int64
+		sm            sync.Mutex
+	)
+	cache, err := NewLoadingCache[string](
+		NewLocalCache[string](newLocalAdaptor[string](), &Options{Base: Base{Prefix: "demo"}, Expire: time.Minute}),
+		&LoadingOptions[string]{
+			SingleLoader: func(key string) (string, bool, error) {
+				n := running.Add(1)
+				defer running.Add(-1)
+				sm.Lock()
+				if n > peak.Load() {
+					peak.Store(n)
+				}
+				sm.Unlock()
+
+				if key == "slow" {
+					time.Sleep(100 * time.Millisecond)
+				} else {
+					time.Sleep(5 * time.Millisecond)
+				}
+				return "v" + key, true, nil
+			},
+			Single: LoaderOptions{Concurrency: 2, Timeout: 50 * time.Millisecond},
+		})
+	if err != nil {
+		panic(err)
+	}
+	ctx := context.TODO()
+
+	var keys []string
+	for i := 0; i < 10; i++ {
+		keys = append(keys, fmt.Sprintf("%d", i))
+	}
+	kvMap, err := cache.Get(ctx, keys)
+	if err != nil {
+		panic(err)
+	}
+	if len(kvMap) != 10 {
+		t.Fatalf("kvMap = %v, want 10 keys", kvMap)
+	}
+	if n := peak.Load(); n > 2 {
+		t.Fatalf("peak = %d, want <= 2", n)
+	}
+	// 先批量查询缓存，只加载miss的key
+	if kvMap, err = cache.Get(ctx, append(keys, "10")); err != nil || len(kvMap) != 11 {
+		t.Fatalf("kvMap = %v, err = %v, want 11 keys", kvMap, err)
+	}
+	if stats := cache.Stats().Single; stats.Loads != 11 {
+		t.Fatalf("loads = %d, want 11", stats.Loads)
+	}
+
+	_, _, err = cache.GetSingle(ctx, "slow")
+	var timeoutErr *TimeoutError
+	if !errors.As(err, &timeoutErr) {
+		t.Fatalf("err = %v, want *TimeoutError", err)
+	}
+	if stats := cache.Stats().Single; stats.Timeouts != 1 || stats.Found != 11 {
+		t.Fatalf("stats = %+v", stats)
+	}
+
+	// Close 等待超时后仍在执行的加载函数
+	if err = cache.Close(ctx); err != nil {
+		panic(err)
+	}
+	if n := running.Load(); n != 0 {
+		t.Fatalf("running = %d after Close, want 0", n)
+	}
+}
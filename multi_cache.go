@@ -2,6 +2,7 @@ package tmpcache
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/PycMono/go-cache/client"
 	"github.com/bytedance/sonic"
@@ -15,6 +16,33 @@ type MultiCacheOptions struct {
 	EnableLog bool          // 是否输出日志
 	WriteNil  bool          // 缓存miss是否写入nil防止缓存穿透，默认不写入
 	Expire    time.Duration // 过期时间
+	Sliding   bool          // 滑动过期，Get 命中后把命中那一级缓存的过期时间刷新为 Expire
+	// 缓存出错时的处理策略，默认 FailFast
+	FailurePolicy FailurePolicy
+	// 按 handlers 下标单独设置出错策略，未设置的使用 FailurePolicy
+	TierFailurePolicy []FailurePolicy
+	// 超时配置，默认不限制
+	Timeouts Timeouts
+	// 按 handlers 下标单独设置 Get/Set/Del 超时，为0的使用 Timeouts
+	TierTimeouts []Timeouts
+	// 延迟双删配置，用于 Invalidate、UpdateAndInvalidate
+	Invalidate InvalidateOptions
+}
+
+// policy 获取第i级缓存的出错策略
+func (o *MultiCacheOptions) policy(i int) FailurePolicy {
+	if i >= 0 && i < len(o.TierFailurePolicy) {
+		return o.TierFailurePolicy[i]
+	}
+	return o.FailurePolicy
+}
+
+// timeouts 获取第i级缓存的超时配置
+func (o *MultiCacheOptions) timeouts(i int) Timeouts {
+	if i >= 0 && i < len(o.TierTimeouts) {
+		return o.Timeouts.merge(o.TierTimeouts[i])
+	}
+	return o.Timeouts
 }
 
 // MultiCache 多级缓存
@@ -25,19 +53,231 @@ type MultiCache[T any] struct {
 	// 若内存缓存未miss，直接返回
 	// 若内存缓存miss，从redis中查询，redis 缓存miss 再从数据库中查询，一定要回写内存缓存
 	handlers []client.IAdaptor
-	opts     *MultiCacheOptions // 基础配置
-	sf       singleflight.Group
+	// 可选的进程内对象缓存，位于 handlers 之前，命中时不需要反序列化，出错时 TierError.Tier 为 LocalTier
+	local client.ITypedAdaptor[T]
+	opts  *MultiCacheOptions // 基础配置
+	sf    singleflight.Group
+	lc    lifecycle
+	inv   *invalidator
 	// 还可以增加一些中间件比如日志输出、埋点之类的
 }
 
 func NewMultiCache[T any](opts *MultiCacheOptions, handlers ...client.IAdaptor) ICache[T] {
-	return &MultiCache[T]{
+	return NewMultiCacheWithLocal[T](opts, nil, handlers...)
+}
+
+// NewMultiCacheWithLocal 在 handlers 之前增加一级进程内对象缓存
+// 该级缓存直接保存对象，命中时跳过反序列化，其它缓存仍然序列化后写入
+func NewMultiCacheWithLocal[T any](opts *MultiCacheOptions, local client.ITypedAdaptor[T], handlers ...client.IAdaptor) ICache[T] {
+	c := &MultiCache[T]{
 		handlers: handlers,
+		local:    local,
 		opts:     opts,
 	}
+	c.inv = newInvalidator(opts.Invalidate, c.delKeys, c.delAllKeys)
+	return c
 }
 
 func (c *MultiCache[T]) Set(ctx context.Context, params map[string]T) error {
+	if err := c.lc.enter(); err != nil {
+		return err
+	}
+	defer c.lc.leave()
+
+	return c.set(ctx, params)
+}
+
+func (c *MultiCache[T]) Get(ctx context.Context, keys []string) (map[string]T, error) {
+	if err := c.lc.enter(); err != nil {
+		return nil, err
+	}
+	defer c.lc.leave()
+
+	return c.get(ctx, keys)
+}
+
+// GetAndSet 缓存 miss，支持调用f函数从其它db中获取数据
+func (c *MultiCache[T]) GetAndSet(ctx context.Context, keys []string, f func(keys []string) (map[string]T, error)) (map[string]T, error) {
+	if err := c.lc.enter(); err != nil {
+		return nil, err
+	}
+	defer c.lc.leave()
+
+	return c.getAndSet(ctx, keys, f)
+}
+
+func (c *MultiCache[T]) GetAndSetSingle(ctx context.Context, k string, f func(k string) (T, bool, error)) (T, bool, error) {
+	if err := c.lc.enter(); err != nil {
+		var val T
+		return val, false, err
+	}
+	defer c.lc.leave()
+
+	return c.getAndSetSingle(ctx, k, f)
+}
+
+func (c *MultiCache[T]) Del(ctx context.Context, keys []string) error {
+	if err := c.lc.enter(); err != nil {
+		return err
+	}
+	defer c.lc.leave()
+
+	return c.del(ctx, keys)
+}
+
+func (c *MultiCache[T]) GetWithTTL(ctx context.Context, keys []string) (map[string]Item[T], error) {
+	if err := c.lc.enter(); err != nil {
+		return nil, err
+	}
+	defer c.lc.leave()
+
+	return c.getWithTTL(ctx, keys)
+}
+
+func (c *MultiCache[T]) TTL(ctx context.Context, keys []string) (map[string]time.Duration, error) {
+	if err := c.lc.enter(); err != nil {
+		return nil, err
+	}
+	defer c.lc.leave()
+
+	return c.ttl(ctx, keys)
+}
+
+func (c *MultiCache[T]) Touch(ctx context.Context, keys []string) error {
+	if err := c.lc.enter(); err != nil {
+		return err
+	}
+	defer c.lc.leave()
+
+	return c.expire(ctx, keys, c.opts.Expire)
+}
+
+func (c *MultiCache[T]) Expire(ctx context.Context, keys []string, expire time.Duration) error {
+	if err := c.lc.enter(); err != nil {
+		return err
+	}
+	defer c.lc.leave()
+
+	return c.expire(ctx, keys, expire)
+}
+
+func (c *MultiCache[T]) GetWithVersion(ctx context.Context, keys []string) (map[string]Versioned[T], error) {
+	if err := c.lc.enter(); err != nil {
+		return nil, err
+	}
+	defer c.lc.leave()
+
+	return c.getWithVersion(ctx, keys)
+}
+
+func (c *MultiCache[T]) SetIfAbsent(ctx context.Context, params map[string]T) (map[string]bool, error) {
+	if err := c.lc.enter(); err != nil {
+		return nil, err
+	}
+	defer c.lc.leave()
+
+	return c.setIf(ctx, params, "setIfAbsent", func(key string, old T, found bool) bool {
+		return !found
+	}, client.IAdaptor.SetIfAbsent)
+}
+
+func (c *MultiCache[T]) SetIfPresent(ctx context.Context, params map[string]T) (map[string]bool, error) {
+	if err := c.lc.enter(); err != nil {
+		return nil, err
+	}
+	defer c.lc.leave()
+
+	return c.setIf(ctx, params, "setIfPresent", func(key string, old T, found bool) bool {
+		return found
+	}, client.IAdaptor.SetIfPresent)
+}
+
+func (c *MultiCache[T]) CompareAndSwap(ctx context.Context, params map[string]Versioned[T]) (map[string]bool, error) {
+	if err := c.lc.enter(); err != nil {
+		return nil, err
+	}
+	defer c.lc.leave()
+
+	if len(c.handlers) == 0 {
+		var (
+			values   = make(map[string]T, len(params))
+			versions = make(map[string]string, len(params))
+		)
+		for k, v := range params {
+			values[k], versions[c.opts.buildKey(k)] = v.Value, v.Version
+		}
+		return c.updateLocal(ctx, values, "compareAndSwap", func(key string, old T, found bool) bool {
+			version := versions[key]
+			if !found {
+				return version == ""
+			}
+			b, err := sonic.Marshal(old)
+			return err == nil && version == client.Version(b)
+		})
+	}
+
+	kv, err := buildSwap(&c.opts.Base, params)
+	if err != nil {
+		return nil, err
+	}
+	return c.writeLast(ctx, "compareAndSwap", func(ctx context.Context, cli client.IAdaptor) (map[string]bool, error) {
+		return cli.CompareAndSwap(ctx, kv, c.opts.Expire)
+	})
+}
+
+func (c *MultiCache[T]) SetWithTags(ctx context.Context, params map[string]T, tags map[string][]string) error {
+	if err := c.lc.enter(); err != nil {
+		return err
+	}
+	defer c.lc.leave()
+
+	return c.setWithTags(ctx, params, tags)
+}
+
+func (c *MultiCache[T]) DelByTag(ctx context.Context, tags []string) error {
+	if err := c.lc.enter(); err != nil {
+		return err
+	}
+	defer c.lc.leave()
+
+	return c.delByTag(ctx, tags)
+}
+
+// Close 拒绝新的调用并在ctx到期前等待进行中的调用（包括加载函数）结束，之后按顺序关闭全部适配器
+// 某个适配器关闭失败不影响后续适配器关闭，错误通过 errors.Join 合并返回
+func (c *MultiCache[T]) Close(ctx context.Context) error {
+	err := c.lc.shutdown(ctx)
+	if errors.Is(err, ErrClosed) {
+		return err
+	}
+
+	errs := []error{err, c.inv.close(ctx)}
+	if c.local != nil {
+		errs = append(errs, c.local.Close())
+	}
+	for _, v := range c.handlers {
+		errs = append(errs, v.Close())
+	}
+
+	return errors.Join(errs...)
+}
+
+func (c *MultiCache[T]) set(ctx context.Context, params map[string]T) error {
+	var errs []error
+	if c.local != nil {
+		typed := make(map[string]T)
+		for k, v := range params {
+			typed[c.opts.buildKey(k)] = v
+		}
+		err := c.local.Set(ctx, typed, c.opts.Expire)
+		if err = c.handleErr(LocalTier, "set", err, &errs); err != nil {
+			return err
+		}
+	}
+	if len(c.handlers) == 0 {
+		return errors.Join(errs...)
+	}
+
 	kv := make(map[string][]byte)
 	for k, v := range params {
 		key := c.opts.buildKey(k)
@@ -48,17 +288,17 @@ func (c *MultiCache[T]) Set(ctx context.Context, params map[string]T) error {
 		kv[key] = b
 	}
 
-	for _, v := range c.handlers {
-		err := v.Set(ctx, kv, c.opts.Expire)
-		if err != nil {
+	for i, v := range c.handlers {
+		err := c.setTier(ctx, i, v, kv)
+		if err = c.handleErr(i, "set", err, &errs); err != nil {
 			return err
 		}
 	}
 
-	return nil
+	return errors.Join(errs...)
 }
 
-func (c *MultiCache[T]) Get(ctx context.Context, keys []string) (map[string]T, error) {
+func (c *MultiCache[T]) get(ctx context.Context, keys []string) (map[string]T, error) {
 	var (
 		tmpKeys = c.opts.buildKeys(keys)
 	)
@@ -66,44 +306,77 @@ func (c *MultiCache[T]) Get(ctx context.Context, keys []string) (map[string]T, e
 	// 多级缓存查询
 	// 思路：第一个client先查找，若miss，将miss的key集合投递下一个client查找，直到所有client查找完成，或者keys全部找到
 	var (
+		out       = make(map[string]T)
 		kvMap     = make(map[string][]byte)
 		missKeys  = tmpKeys
 		preClient client.IAdaptor
+		preIndex  int
+		errs      []error
 	)
-	for _, cli := range c.handlers {
+	if c.local != nil {
+		localKv, err := c.local.Get(ctx, tmpKeys)
+		if err = c.handleErr(LocalTier, "get", err, &errs); err != nil {
+			return nil, err
+		}
+		missKeys = nil
+		for _, key := range tmpKeys {
+			if v, ok := localKv[key]; ok {
+				out[c.opts.splitKey(key)] = v
+				continue
+			}
+			missKeys = append(missKeys, key)
+		}
+		if c.opts.Sliding && len(localKv) > 0 {
+			c.slide(ctx, LocalTier, nil, hitKeys(localKv))
+		}
+	}
+	lookupCtx, cancel := withTimeout(ctx, c.opts.Timeouts.Lookup)
+	defer cancel()
+	for i, cli := range c.handlers {
 		if len(missKeys) == 0 {
 			break // 退出循环
 		}
 
-		tmpKvMap, err := cli.Get(ctx, missKeys)
+		tmpKvMap, err := c.getTier(lookupCtx, i, cli, missKeys)
+		err = wrapTimeout(ctx, lookupCtx, err, "lookup", NoTier, c.opts.Timeouts.Lookup)
+		if errors.Is(err, client.ErrCircuitOpen) {
+			continue // 熔断的缓存直接跳过，继续查询下一级缓存，也不作为回写对象
+		}
 		if err != nil {
-			return nil, err
+			if err = c.handleErr(i, "get", err, &errs); err != nil {
+				return nil, err
+			}
+			continue // 出错的缓存同样跳过，也不作为回写对象
 		}
 		for k, v := range tmpKvMap {
 			kvMap[k] = v
 		}
+		if c.opts.Sliding && len(tmpKvMap) > 0 {
+			c.slide(ctx, i, cli, hitKeys(tmpKvMap))
+		}
 
-		missKeys = []string{} // 重新设置值
-		for _, key := range tmpKeys {
+		var tmpMissKeys []string // 重新设置值
+		for _, key := range missKeys {
 			if _, ok := tmpKvMap[key]; !ok {
-				missKeys = append(missKeys, key)
+				tmpMissKeys = append(tmpMissKeys, key)
 			}
 		}
+		missKeys = tmpMissKeys
 
 		if len(tmpKvMap) == 0 || preClient == nil {
-			preClient = cli
+			preClient, preIndex = cli, i
 			continue
 		}
 
-		err = preClient.Set(ctx, tmpKvMap, c.opts.Expire) // 如果1级缓存miss了，2级缓存加载后，回写1级缓存
+		err = c.setTier(ctx, preIndex, preClient, tmpKvMap) // 如果1级缓存miss了，2级缓存加载后，回写1级缓存
 		if err != nil {
 			fmt.Println(err)
 		}
-		preClient = cli
+		preClient, preIndex = cli, i
 	}
 
 	// 处理数据返回
-	out := make(map[string]T)
+	typed := make(map[string]T)
 	for k, v := range kvMap {
 		var obj T
 		err := sonic.Unmarshal(v, &obj)
@@ -113,16 +386,156 @@ func (c *MultiCache[T]) Get(ctx context.Context, keys []string) (map[string]T, e
 
 		key := c.opts.splitKey(k) // 切割key
 		out[key] = obj
+		typed[k] = obj
+	}
+
+	// 下层缓存查到的数据回写进程内缓存
+	if c.local != nil && len(typed) > 0 {
+		err := c.local.Set(ctx, typed, c.opts.Expire)
+		if err != nil {
+			fmt.Println(err)
+		}
 	}
 
-	return out, nil
+	return out, errors.Join(errs...)
 }
 
-// GetAndSet 缓存 miss，支持调用f函数从其它db中获取数据
-func (c *MultiCache[T]) GetAndSet(ctx context.Context, k []string, f func(k []string) (map[string]T, error)) (map[string]T, error) {
-	kvMap, err := c.Get(ctx, k)
-	if err != nil {
-		return nil, err
+// getWithTTL 按顺序查询各级缓存的数据和剩余过期时间，不回写上一级缓存，避免改变数据的过期时间
+func (c *MultiCache[T]) getWithTTL(ctx context.Context, keys []string) (map[string]Item[T], error) {
+	var (
+		tmpKeys  = c.opts.buildKeys(keys)
+		out      = make(map[string]Item[T])
+		missKeys = tmpKeys
+		errs     []error
+	)
+	if c.local != nil {
+		localKv, err := c.local.Get(ctx, tmpKeys)
+		if err == nil && len(localKv) > 0 {
+			var ttl map[string]time.Duration
+			ttl, err = c.local.TTL(ctx, hitKeys(localKv))
+			for k, v := range localKv {
+				if d, ok := ttl[k]; ok {
+					out[c.opts.splitKey(k)] = Item[T]{Value: v, TTL: d}
+				}
+			}
+		}
+		if err = c.handleErr(LocalTier, "get", err, &errs); err != nil {
+			return nil, err
+		}
+		missKeys = nil
+		for _, key := range tmpKeys {
+			if _, ok := out[c.opts.splitKey(key)]; !ok {
+				missKeys = append(missKeys, key)
+			}
+		}
+	}
+	lookupCtx, cancel := withTimeout(ctx, c.opts.Timeouts.Lookup)
+	defer cancel()
+	for i, cli := range c.handlers {
+		if len(missKeys) == 0 {
+			break
+		}
+
+		timeout := c.opts.timeouts(i).Get
+		getCtx, cancelGet := withTimeout(lookupCtx, timeout)
+		kv, err := cli.GetWithTTL(getCtx, missKeys)
+		err = wrapTimeout(lookupCtx, getCtx, err, "get", i, timeout)
+		cancelGet()
+		err = wrapTimeout(ctx, lookupCtx, err, "lookup", NoTier, c.opts.Timeouts.Lookup)
+		if errors.Is(err, client.ErrCircuitOpen) {
+			continue
+		}
+		if err != nil {
+			if err = c.handleErr(i, "get", err, &errs); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		var tmpMissKeys []string
+		for _, key := range missKeys {
+			v, ok := kv[key]
+			if !ok {
+				tmpMissKeys = append(tmpMissKeys, key)
+				continue
+			}
+			var obj T
+			if err = sonic.Unmarshal(v.Value, &obj); err != nil {
+				return nil, err
+			}
+			out[c.opts.splitKey(key)] = Item[T]{Value: obj, TTL: v.TTL}
+		}
+		missKeys = tmpMissKeys
+	}
+
+	return out, errors.Join(errs...)
+}
+
+// ttl 依次查询每一级缓存的剩余过期时间，不读取数据
+func (c *MultiCache[T]) ttl(ctx context.Context, keys []string) (map[string]time.Duration, error) {
+	var (
+		tmpKeys  = c.opts.buildKeys(keys)
+		out      = make(map[string]time.Duration)
+		missKeys = tmpKeys
+		errs     []error
+	)
+	if c.local != nil {
+		kv, err := c.local.TTL(ctx, tmpKeys)
+		if err = c.handleErr(LocalTier, "get", err, &errs); err != nil {
+			return nil, err
+		}
+		missKeys = nil
+		for _, key := range tmpKeys {
+			if d, ok := kv[key]; ok {
+				out[c.opts.splitKey(key)] = d
+				continue
+			}
+			missKeys = append(missKeys, key)
+		}
+	}
+	lookupCtx, cancel := withTimeout(ctx, c.opts.Timeouts.Lookup)
+	defer cancel()
+	for i, cli := range c.handlers {
+		if len(missKeys) == 0 {
+			break
+		}
+
+		timeout := c.opts.timeouts(i).Get
+		getCtx, cancelGet := withTimeout(lookupCtx, timeout)
+		kv, err := cli.TTL(getCtx, missKeys)
+		err = wrapTimeout(lookupCtx, getCtx, err, "get", i, timeout)
+		cancelGet()
+		err = wrapTimeout(ctx, lookupCtx, err, "lookup", NoTier, c.opts.Timeouts.Lookup)
+		if errors.Is(err, client.ErrCircuitOpen) {
+			continue
+		}
+		if err != nil {
+			if err = c.handleErr(i, "get", err, &errs); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		var tmpMissKeys []string
+		for _, key := range missKeys {
+			d, ok := kv[key]
+			if !ok {
+				tmpMissKeys = append(tmpMissKeys, key)
+				continue
+			}
+			out[c.opts.splitKey(key)] = d
+		}
+		missKeys = tmpMissKeys
+	}
+
+	return out, errors.Join(errs...)
+}
+
+func (c *MultiCache[T]) getAndSet(ctx context.Context, k []string, f func(k []string) (map[string]T, error)) (map[string]T, error) {
+	// BestEffort 策略下出错的缓存按miss处理，继续回源，最后把缓存错误一起返回
+	kvMap, tierErr := c.get(ctx, k)
+	if kvMap == nil {
+		return nil, tierErr
 	}
 
 	var (
@@ -135,10 +548,12 @@ func (c *MultiCache[T]) GetAndSet(ctx context.Context, k []string, f func(k []st
 		missKeys = append(missKeys, v)
 	}
 	if len(missKeys) == 0 {
-		return kvMap, nil
+		return kvMap, tierErr
 	}
 
-	tmpKvMap, err := f(missKeys)
+	tmpKvMap, err := runLoader(ctx, &c.lc, c.opts.Timeouts.Loader, func() (map[string]T, error) {
+		return f(missKeys)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -157,47 +572,47 @@ func (c *MultiCache[T]) GetAndSet(ctx context.Context, k []string, f func(k []st
 		}
 	}
 	if len(tmpKvMap) > 0 {
-		err = c.Set(ctx, tmpKvMap)
+		err = c.set(ctx, tmpKvMap)
 		if err != nil {
 			// todo 打印日志就好了，不影响后续流程，下次请求再次尝试加载到缓存
 			fmt.Println(err)
 		}
 	}
 
-	return kvMap, nil
+	return kvMap, tierErr
 }
 
-func (c *MultiCache[T]) GetAndSetSingle(ctx context.Context, k string, f func(k string) (T, bool, error)) (T, bool, error) {
+func (c *MultiCache[T]) getAndSetSingle(ctx context.Context, k string, f func(k string) (T, bool, error)) (T, bool, error) {
 	var (
 		val T
 		ok  bool
 	)
 
-	kvMap, err := c.Get(ctx, []string{k})
-	if err != nil {
-		return val, false, err
+	kvMap, tierErr := c.get(ctx, []string{k})
+	if kvMap == nil {
+		return val, false, tierErr
 	}
 	val, ok = kvMap[k]
 	if ok {
-		return val, true, nil
+		return val, true, tierErr
 	}
 
 	// 缓存miss 从外部查询
 	if f == nil {
-		return val, false, nil
+		return val, false, tierErr
 	}
 
 	// 单飞查询
-	_, err, _ = c.sf.Do(k, func() (interface{}, error) {
-		val, ok, err = f(k)
-		if err != nil {
-			return nil, err
-		}
-		return val, nil
+	res, err, _ := c.sf.Do(k, func() (interface{}, error) {
+		return runLoader(ctx, &c.lc, c.opts.Timeouts.Loader, func() (single[T], error) {
+			val, ok, err := f(k)
+			return single[T]{val: val, ok: ok}, err
+		})
 	})
 	if err != nil {
 		return val, false, err
 	}
+	val, ok = res.(single[T]).val, res.(single[T]).ok
 
 	// 写入缓存
 	var (
@@ -207,25 +622,361 @@ func (c *MultiCache[T]) GetAndSetSingle(ctx context.Context, k string, f func(k
 	// 写入缓存条件：1、数据存在；2、数据不存在并且WriteNil为true
 	if ok || (c.opts.WriteNil && !ok) {
 		tmpKvMap[k] = val
-		err = c.Set(ctx, tmpKvMap)
+		err = c.set(ctx, tmpKvMap)
 		if err != nil {
 			fmt.Println(err)
 		}
 	}
 
-	return val, ok, nil
+	return val, ok, tierErr
+}
+
+func (c *MultiCache[T]) del(ctx context.Context, k []string) error {
+	return c.delKeys(ctx, c.opts.buildKeys(k))
+}
+
+// delKeys 从所有缓存中删除已经构造好的key
+func (c *MultiCache[T]) delKeys(ctx context.Context, tmpKeys []string) error {
+	var errs []error
+	if c.local != nil {
+		err := c.local.Del(ctx, tmpKeys)
+		if err = c.handleErr(LocalTier, "del", err, &errs); err != nil {
+			return err
+		}
+	}
+	for i, v := range c.handlers {
+		err := c.delTier(ctx, i, v, tmpKeys)
+		if err = c.handleErr(i, "del", err, &errs); err != nil {
+			return err
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// delAllKeys 从每一级缓存删除，不使用出错策略，任何一级删除失败都返回错误，供延迟删除重试
+func (c *MultiCache[T]) delAllKeys(ctx context.Context, tmpKeys []string) error {
+	var errs []error
+	if c.local != nil {
+		if err := c.local.Del(ctx, tmpKeys); err != nil {
+			errs = append(errs, &TierError{Tier: LocalTier, Op: "del", Err: err})
+		}
+	}
+	for i, v := range c.handlers {
+		if err := c.delTier(ctx, i, v, tmpKeys); err != nil {
+			errs = append(errs, &TierError{Tier: i, Op: "del", Err: err})
+		}
+	}
+
+	return errors.Join(errs...)
 }
 
-func (c *MultiCache[T]) Del(ctx context.Context, k []string) error {
+// taggers 返回支持 client.ITagAdaptor 的缓存下标，熔断、重试包装的适配器按被包装的适配器判断
+func (c *MultiCache[T]) taggers() []int {
+	var out []int
+	for i, cli := range c.handlers {
+		if client.Supports[client.ITagAdaptor](cli) {
+			out = append(out, i)
+		}
+	}
+	return out
+}
+
+// setWithTags 写入数据后在每一级支持标签的缓存中记录标签索引，记录失败按出错策略处理
+func (c *MultiCache[T]) setWithTags(ctx context.Context, params map[string]T, tags map[string][]string) error {
+	taggers := c.taggers()
+	if len(taggers) == 0 {
+		return client.ErrNotSupported
+	}
+	if err := c.set(ctx, params); err != nil {
+		return err
+	}
+
 	var (
-		tmpKeys = c.opts.buildKeys(k)
+		tmpTags = c.opts.buildTags(tags)
+		errs    []error
 	)
-	for _, v := range c.handlers {
-		err := v.Del(ctx, tmpKeys)
+	for _, i := range taggers {
+		timeout := c.opts.timeouts(i).Set
+		err := callTier(ctx, i, "set", timeout, func(ctx context.Context) error {
+			return c.handlers[i].(client.ITagAdaptor).AddTags(ctx, tmpTags, c.opts.Expire)
+		})
+		if err = c.handleErr(i, "tag", err, &errs); err != nil {
+			return err
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// delByTag 合并所有支持标签的缓存中记录的key，从所有缓存（包括进程内缓存）中删除，最后删除标签索引
+func (c *MultiCache[T]) delByTag(ctx context.Context, tags []string) error {
+	taggers := c.taggers()
+	if len(taggers) == 0 {
+		return client.ErrNotSupported
+	}
+
+	var (
+		tagKeys = c.opts.buildTagKeys(tags)
+		keys    []string
+		seen    = make(map[string]struct{})
+		errs    []error
+	)
+	for _, i := range taggers {
+		var tmpKeys []string
+		timeout := c.opts.timeouts(i).Get
+		err := callTier(ctx, i, "get", timeout, func(ctx context.Context) (err error) {
+			tmpKeys, err = c.handlers[i].(client.ITagAdaptor).TagKeys(ctx, tagKeys)
+			return err
+		})
+		if err = c.handleErr(i, "tag", err, &errs); err != nil {
+			return err
+		}
+		for _, key := range tmpKeys {
+			if _, ok := seen[key]; !ok {
+				seen[key] = struct{}{}
+				keys = append(keys, key)
+			}
+		}
+	}
+
+	// 删除数据出错时保留标签索引，便于重试
+	if len(keys) > 0 {
+		if err := c.delKeys(ctx, keys); err != nil {
+			return errors.Join(append(errs, err)...)
+		}
+	}
+
+	for _, i := range taggers {
+		timeout := c.opts.timeouts(i).Del
+		err := callTier(ctx, i, "del", timeout, func(ctx context.Context) error {
+			return c.handlers[i].(client.ITagAdaptor).DelTags(ctx, tagKeys)
+		})
+		if err = c.handleErr(i, "tag", err, &errs); err != nil {
+			return err
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// getWithVersion 从最后一级缓存查询数据并计算版本号，只有进程内缓存时查询进程内缓存，没有任何缓存时返回 client.ErrNotSupported
+func (c *MultiCache[T]) getWithVersion(ctx context.Context, keys []string) (map[string]Versioned[T], error) {
+	tmpKeys := c.opts.buildKeys(keys)
+	if len(c.handlers) == 0 {
+		if c.local == nil {
+			return nil, client.ErrNotSupported
+		}
+		kv, err := c.local.Get(ctx, tmpKeys)
 		if err != nil {
+			return nil, &TierError{Tier: LocalTier, Op: "get", Err: err}
+		}
+
+		out := make(map[string]Versioned[T], len(kv))
+		for k, v := range kv {
+			b, err := sonic.Marshal(v)
+			if err != nil {
+				return nil, err
+			}
+			out[c.opts.splitKey(k)] = Versioned[T]{Value: v, Version: client.Version(b)}
+		}
+		return out, nil
+	}
+
+	i := len(c.handlers) - 1
+	kv, err := c.getTier(ctx, i, c.handlers[i], tmpKeys)
+	if err != nil {
+		return nil, &TierError{Tier: i, Op: "get", Err: err}
+	}
+	return versioned[T](&c.opts.Base, kv)
+}
+
+// setIf 序列化后在最后一级缓存执行条件写入，只有进程内缓存时在进程内缓存中判断
+func (c *MultiCache[T]) setIf(ctx context.Context, params map[string]T, op string, ok func(key string, old T, found bool) bool,
+	f func(cli client.IAdaptor, ctx context.Context, params map[string][]byte, expire time.Duration) (map[string]bool, error)) (map[string]bool, error) {
+	if len(c.handlers) == 0 {
+		return c.updateLocal(ctx, params, op, ok)
+	}
+
+	kv := make(map[string][]byte, len(params))
+	for k, v := range params {
+		b, err := sonic.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		kv[c.opts.buildKey(k)] = b
+	}
+	return c.writeLast(ctx, op, func(ctx context.Context, cli client.IAdaptor) (map[string]bool, error) {
+		return f(cli, ctx, kv, c.opts.Expire)
+	})
+}
+
+// writeLast 条件写入以最后一级缓存为准，写入成功的key从其它缓存中删除，避免读到旧数据
+// 最后一级缓存出错直接返回，删除其它缓存出错按出错策略处理
+func (c *MultiCache[T]) writeLast(ctx context.Context, op string, f func(ctx context.Context, cli client.IAdaptor) (map[string]bool, error)) (map[string]bool, error) {
+	var (
+		i       = len(c.handlers) - 1
+		timeout = c.opts.timeouts(i).Set
+		written map[string]bool
+	)
+	err := callTier(ctx, i, op, timeout, func(ctx context.Context) (err error) {
+		written, err = f(ctx, c.handlers[i])
+		return err
+	})
+	if err != nil {
+		return nil, &TierError{Tier: i, Op: op, Err: err}
+	}
+
+	var keys []string
+	for k, ok := range written {
+		if ok {
+			keys = append(keys, k)
+		}
+	}
+	var errs []error
+	if len(keys) > 0 && c.local != nil {
+		err = c.local.Del(ctx, keys)
+		if err = c.handleErr(LocalTier, "del", err, &errs); err != nil {
+			return nil, err
+		}
+	}
+	for j, cli := range c.handlers[:i] {
+		if len(keys) == 0 {
+			break
+		}
+		err = c.delTier(ctx, j, cli, keys)
+		if err = c.handleErr(j, "del", err, &errs); err != nil {
+			return nil, err
+		}
+	}
+
+	return c.opts.splitResult(written), errors.Join(errs...)
+}
+
+// updateLocal 只有进程内缓存时，在进程内缓存的锁内判断是否写入，没有任何缓存时返回 client.ErrNotSupported
+func (c *MultiCache[T]) updateLocal(ctx context.Context, params map[string]T, op string, ok func(key string, old T, found bool) bool) (map[string]bool, error) {
+	if c.local == nil {
+		return nil, client.ErrNotSupported
+	}
+
+	var (
+		keys   = make([]string, 0, len(params))
+		values = make(map[string]T, len(params))
+	)
+	for k, v := range params {
+		key := c.opts.buildKey(k)
+		keys = append(keys, key)
+		values[key] = v
+	}
+
+	written, err := c.local.Update(ctx, keys, func(key string, old T, found bool) (T, bool) {
+		return values[key], ok(key, old, found)
+	}, c.opts.Expire)
+	if err != nil {
+		return nil, &TierError{Tier: LocalTier, Op: op, Err: err}
+	}
+	return c.opts.splitResult(written), nil
+}
+
+// expire 修改全部缓存的过期时间，出错处理和 del 一致
+func (c *MultiCache[T]) expire(ctx context.Context, k []string, expire time.Duration) error {
+	var (
+		tmpKeys = c.opts.buildKeys(k)
+	)
+	var errs []error
+	if c.local != nil {
+		err := c.local.Expire(ctx, tmpKeys, expire)
+		if err = c.handleErr(LocalTier, "expire", err, &errs); err != nil {
+			return err
+		}
+	}
+	for i, v := range c.handlers {
+		err := c.expireTier(ctx, i, v, tmpKeys, expire)
+		if err = c.handleErr(i, "expire", err, &errs); err != nil {
 			return err
 		}
 	}
 
+	return errors.Join(errs...)
+}
+
+// slide 滑动过期，刷新第i级缓存中命中数据的过期时间，失败只打印日志
+func (c *MultiCache[T]) slide(ctx context.Context, i int, cli client.IAdaptor, keys []string) {
+	var err error
+	if i == LocalTier {
+		err = c.local.Expire(ctx, keys, c.opts.Expire)
+	} else {
+		err = c.expireTier(ctx, i, cli, keys, c.opts.Expire)
+	}
+	if err != nil {
+		fmt.Println(err)
+	}
+}
+
+// setTier 在第i级缓存的超时限制内写入
+func (c *MultiCache[T]) setTier(ctx context.Context, i int, cli client.IAdaptor, kv map[string][]byte) error {
+	timeout := c.opts.timeouts(i).Set
+	setCtx, cancel := withTimeout(ctx, timeout)
+	defer cancel()
+
+	err := cli.Set(setCtx, kv, c.opts.Expire)
+	return wrapTimeout(ctx, setCtx, err, "set", i, timeout)
+}
+
+// getTier 在第i级缓存的超时限制内查询
+func (c *MultiCache[T]) getTier(ctx context.Context, i int, cli client.IAdaptor, keys []string) (map[string][]byte, error) {
+	timeout := c.opts.timeouts(i).Get
+	getCtx, cancel := withTimeout(ctx, timeout)
+	defer cancel()
+
+	kv, err := cli.Get(getCtx, keys)
+	return kv, wrapTimeout(ctx, getCtx, err, "get", i, timeout)
+}
+
+// delTier 在第i级缓存的超时限制内删除
+func (c *MultiCache[T]) delTier(ctx context.Context, i int, cli client.IAdaptor, keys []string) error {
+	timeout := c.opts.timeouts(i).Del
+	delCtx, cancel := withTimeout(ctx, timeout)
+	defer cancel()
+
+	err := cli.Del(delCtx, keys)
+	return wrapTimeout(ctx, delCtx, err, "del", i, timeout)
+}
+
+// expireTier 在第i级缓存的超时限制内修改过期时间，使用 Set 超时
+func (c *MultiCache[T]) expireTier(ctx context.Context, i int, cli client.IAdaptor, keys []string, expire time.Duration) error {
+	timeout := c.opts.timeouts(i).Set
+	setCtx, cancel := withTimeout(ctx, timeout)
+	defer cancel()
+
+	err := cli.Expire(setCtx, keys, expire)
+	return wrapTimeout(ctx, setCtx, err, "expire", i, timeout)
+}
+
+// handleErr 按第i级缓存的出错策略处理错误，返回非nil表示需要立即返回
+// BestEffort 的错误收集到errs中，由调用方最后统一返回
+func (c *MultiCache[T]) handleErr(i int, op string, err error, errs *[]error) error {
+	if err == nil {
+		return nil
+	}
+
+	err = &TierError{Tier: i, Op: op, Err: err}
+	switch c.opts.policy(i) {
+	case SkipAndContinue:
+		fmt.Println(err)
+	case BestEffort:
+		*errs = append(*errs, err)
+	default:
+		return err
+	}
 	return nil
 }
+
+// hitKeys 返回kv中的全部key
+func hitKeys[V any](kv map[string]V) []string {
+	keys := make([]string, 0, len(kv))
+	for k := range kv {
+		keys = append(keys, k)
+	}
+	return keys
+}
@@ -2,8 +2,13 @@ package tmpcache
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"github.com/PycMono/go-cache/client"
+	"github.com/PycMono/go-cache/client/breaker"
 	"github.com/bytedance/sonic"
+	"reflect"
+	"sort"
 	"testing"
 	"time"
 )
@@ -90,3 +95,522 @@ func TestMultiCacheGetAndSet(t *testing.T) {
 	fmt.Println(string(b))
 	fmt.Println("------------2")
 }
+
+// errAdaptor 所有操作都返回错误的适配器
+type errAdaptor struct {
+	err error
+}
+
+func (e *errAdaptor) Set(ctx context.Context, params map[string][]byte, expire time.Duration) error {
+	return e.err
+}
+
+func (e *errAdaptor) Get(ctx context.Context, k []string) (map[string][]byte, error) {
+	return nil, e.err
+}
+
+func (e *errAdaptor) Del(ctx context.Context, k []string) error {
+	return e.err
+}
+
+func (e *errAdaptor) Close() error {
+	return nil
+}
+
+func (e *errAdaptor) GetWithTTL(ctx context.Context, k []string) (map[string]client.Item, error) {
+	return nil, e.err
+}
+
+func (e *errAdaptor) TTL(ctx context.Context, k []string) (map[string]time.Duration, error) {
+	return nil, e.err
+}
+
+func (e *errAdaptor) Expire(ctx context.Context, k []string, expire time.Duration) error {
+	return e.err
+}
+
+func (e *errAdaptor) Scan(ctx context.Context, pattern string) client.KeyIterator {
+	return client.ErrIterator(e.err)
+}
+
+func (e *errAdaptor) DelPrefix(ctx context.Context, prefix string) (int64, error) {
+	return 0, e.err
+}
+
+func (e *errAdaptor) SetIfAbsent(ctx context.Context, params map[string][]byte, expire time.Duration) (map[string]bool, error) {
+	return nil, e.err
+}
+
+func (e *errAdaptor) SetIfPresent(ctx context.Context, params map[string][]byte, expire time.Duration) (map[string]bool, error) {
+	return nil, e.err
+}
+
+func (e *errAdaptor) CompareAndSwap(ctx context.Context, params map[string]client.Swap, expire time.Duration) (map[string]bool, error) {
+	return nil, e.err
+}
+
+func TestMultiCacheFailurePolicy(t *testing.T) {
+	type person struct {
+		Name string `json:"name"`
+		Age  int    `json:"age"`
+	}
+	downErr := errors.New("redis down")
+	cache := NewMultiCache[*person](&MultiCacheOptions{
+		Base:              Base{Prefix: "demo"},
+		Expire:            time.Minute,
+		TierFailurePolicy: []FailurePolicy{BestEffort},
+	}, &errAdaptor{err: downErr}, newMemAdaptor(1024*1024))
+
+	err := cache.Set(context.TODO(), map[string]*person{"1": {Name: "111", Age: 20}})
+	if !errors.Is(err, downErr) {
+		panic(err)
+	}
+	if tiers := FailedTiers(err); len(tiers) != 1 || tiers[0] != 0 {
+		t.Fatalf("failed tiers = %v, want [0]", tiers)
+	}
+
+	// 第0级出错，从第1级查到数据，未查到的key回源
+	kvMap, err := cache.GetAndSet(context.TODO(), []string{"1", "2"}, func(k []string) (map[string]*person, error) {
+		return map[string]*person{"2": {Name: "222"}}, nil
+	})
+	if !errors.Is(err, downErr) {
+		panic(err)
+	}
+	if len(kvMap) != 2 || kvMap["1"].Name != "111" || kvMap["2"].Name != "222" {
+		b, _ := sonic.Marshal(kvMap)
+		t.Fatalf("kvMap = %s", b)
+	}
+
+	// 默认 FailFast 直接返回错误
+	cache = NewMultiCache[*person](&MultiCacheOptions{Base: Base{Prefix: "demo"}, Expire: time.Minute},
+		&errAdaptor{err: downErr}, newMemAdaptor(1024*1024))
+	if kvMap, err = cache.Get(context.TODO(), []string{"1"}); err == nil || kvMap != nil {
+		t.Fatalf("kvMap = %v, err = %v, want fail fast", kvMap, err)
+	}
+
+	// 熔断打开：读跳过该级，写和删除按失败策略报告
+	cache = NewMultiCache[*person](&MultiCacheOptions{
+		Base:              Base{Prefix: "demo"},
+		Expire:            time.Minute,
+		TierFailurePolicy: []FailurePolicy{BestEffort},
+	}, &errAdaptor{err: client.ErrCircuitOpen}, newMemAdaptor(1024*1024))
+	if _, err = cache.Get(context.TODO(), []string{"1"}); err != nil {
+		t.Fatalf("Get err = %v, want nil", err)
+	}
+	for _, err = range []error{
+		cache.Set(context.TODO(), map[string]*person{"1": {Name: "111"}}),
+		cache.Del(context.TODO(), []string{"1"}),
+	} {
+		if !errors.Is(err, client.ErrCircuitOpen) {
+			t.Fatalf("err = %v, want ErrCircuitOpen", err)
+		}
+		if tiers := FailedTiers(err); len(tiers) != 1 || tiers[0] != 0 {
+			t.Fatalf("failed tiers = %v, want [0]", tiers)
+		}
+	}
+}
+
+// slowAdaptor 等待ctx结束才返回的适配器
+type slowAdaptor struct {
+	errAdaptor
+}
+
+func (s *slowAdaptor) Get(ctx context.Context, k []string) (map[string][]byte, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+// DelPrefix 分3批删除，每批耗时15ms
+func (s *slowAdaptor) DelPrefix(ctx context.Context, prefix string) (int64, error) {
+	var n int64
+	for i := 0; i < 3; i++ {
+		batchCtx, cancel := client.BatchContext(ctx)
+		select {
+		case <-batchCtx.Done():
+			cancel()
+			return n, batchCtx.Err()
+		case <-time.After(15 * time.Millisecond):
+		}
+		cancel()
+		n++
+	}
+	return n, nil
+}
+
+func TestMultiCacheTimeout(t *testing.T) {
+	type person struct {
+		Name string `json:"name"`
+		Age  int    `json:"age"`
+	}
+	cache := NewMultiCache[*person](&MultiCacheOptions{
+		Base:          Base{Prefix: "demo"},
+		Expire:        time.Minute,
+		FailurePolicy: BestEffort,
+		Timeouts:      Timeouts{Loader: 20 * time.Millisecond},
+		TierTimeouts:  []Timeouts{{Get: 20 * time.Millisecond}},
+	}, &slowAdaptor{}, newMemAdaptor(1024*1024))
+
+	// 第0级超时，继续查询第1级
+	kvMap, err := cache.GetAndSet(context.TODO(), []string{"1"}, func(k []string) (map[string]*person, error) {
+		return map[string]*person{"1": {Name: "111"}}, nil
+	})
+	var timeoutErr *TimeoutError
+	if !errors.As(err, &timeoutErr) || timeoutErr.Op != "get" || timeoutErr.Tier != 0 {
+		t.Fatalf("err = %v, want tier 0 get timeout", err)
+	}
+	if kvMap["1"] == nil || kvMap["1"].Name != "111" {
+		t.Fatalf("kvMap = %v", kvMap)
+	}
+
+	// 加载函数超时
+	_, _, err = cache.GetAndSetSingle(context.TODO(), "2", func(k string) (*person, bool, error) {
+		time.Sleep(100 * time.Millisecond)
+		return nil, false, nil
+	})
+	if !errors.As(err, &timeoutErr) || timeoutErr.Op != "loader" || timeoutErr.Tier != NoTier || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want loader timeout", err)
+	}
+
+	// DelPrefix 的删除超时按批计算，总耗时超过超时时间不算超时
+	cache = NewMultiCache[*person](&MultiCacheOptions{
+		Base:         Base{Prefix: "demo"},
+		TierTimeouts: []Timeouts{{Del: 30 * time.Millisecond}, {Del: time.Millisecond}},
+	}, &slowAdaptor{}, &slowAdaptor{})
+	n, err := cache.DelPrefix(context.TODO(), "1")
+	if !errors.As(err, &timeoutErr) || timeoutErr.Op != "del" || timeoutErr.Tier != 1 || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("n = %d, err = %v, want tier 1 del timeout", n, err)
+	}
+}
+
+func TestMultiCacheTTL(t *testing.T) {
+	type person struct {
+		Name string `json:"name"`
+		Age  int    `json:"age"`
+	}
+	opts := &MultiCacheOptions{Base: Base{Prefix: "demo"}, Expire: time.Minute}
+	cache := NewMultiCacheWithLocal[*person](opts, newLocalAdaptor[*person](), newMemAdaptor(1024*1024))
+	err := cache.Set(context.TODO(), map[string]*person{"1": {Name: "111"}})
+	if err != nil {
+		panic(err)
+	}
+
+	items, err := cache.GetWithTTL(context.TODO(), []string{"1", "2"})
+	if err != nil {
+		panic(err)
+	}
+	if len(items) != 1 || items["1"].Value.Name != "111" || items["1"].TTL <= 0 || items["1"].TTL > time.Minute {
+		t.Fatalf("items = %v", items)
+	}
+
+	// 修改过期时间
+	if err = cache.Expire(context.TODO(), []string{"1"}, time.Hour); err != nil {
+		panic(err)
+	}
+	ttl, err := cache.TTL(context.TODO(), []string{"1"})
+	if err != nil {
+		panic(err)
+	}
+	if ttl["1"] <= time.Minute {
+		t.Fatalf("ttl = %v, want > 1m", ttl["1"])
+	}
+
+	// Touch 恢复为配置的过期时间
+	if err = cache.Touch(context.TODO(), []string{"1"}); err != nil {
+		panic(err)
+	}
+	ttl, _ = cache.TTL(context.TODO(), []string{"1"})
+	if ttl["1"] <= 0 || ttl["1"] > time.Minute {
+		t.Fatalf("ttl = %v, want <= 1m", ttl["1"])
+	}
+
+	// 永不过期
+	if err = cache.Expire(context.TODO(), []string{"1"}, NoExpire); err != nil {
+		panic(err)
+	}
+	ttl, _ = cache.TTL(context.TODO(), []string{"1"})
+	if ttl["1"] != NoExpire {
+		t.Fatalf("ttl = %v, want NoExpire", ttl["1"])
+	}
+
+	// TTL 只查询过期时间，不读取数据
+	cache = NewMultiCache[*person](opts, &ttlOnly{IAdaptor: newMemAdaptor(1024 * 1024)})
+	_ = cache.Set(context.TODO(), map[string]*person{"1": {Name: "111"}})
+	if ttl, err = cache.TTL(context.TODO(), []string{"1", "2"}); err != nil || len(ttl) != 1 || ttl["1"] <= 0 {
+		t.Fatalf("ttl = %v, err = %v, want 1 only", ttl, err)
+	}
+}
+
+// ttlOnly 读取数据都返回错误的适配器
+type ttlOnly struct {
+	client.IAdaptor
+}
+
+func (o *ttlOnly) Get(ctx context.Context, k []string) (map[string][]byte, error) {
+	return nil, errors.New("unexpected get")
+}
+
+func (o *ttlOnly) GetWithTTL(ctx context.Context, k []string) (map[string]client.Item, error) {
+	return nil, errors.New("unexpected get")
+}
+
+func TestSlidingExpire(t *testing.T) {
+	type person struct {
+		Name string `json:"name"`
+		Age  int    `json:"age"`
+	}
+	cache := NewCache[*person](newMemAdaptor(1024*1024), &Options{Base: Base{Prefix: "demo"}, Expire: time.Hour, Sliding: true})
+	err := cache.Set(context.TODO(), map[string]*person{"1": {Name: "111"}})
+	if err != nil {
+		panic(err)
+	}
+	if err = cache.Expire(context.TODO(), []string{"1"}, 10*time.Second); err != nil {
+		panic(err)
+	}
+
+	// 命中后过期时间刷新为 Expire
+	if _, err = cache.Get(context.TODO(), []string{"1"}); err != nil {
+		panic(err)
+	}
+	ttl, err := cache.TTL(context.TODO(), []string{"1"})
+	if err != nil {
+		panic(err)
+	}
+	if ttl["1"] <= 10*time.Second {
+		t.Fatalf("ttl = %v, want refreshed to 1h", ttl["1"])
+	}
+}
+
+func TestMultiCacheConditionalWrite(t *testing.T) {
+	type person struct {
+		Name string `json:"name"`
+		Age  int    `json:"age"`
+	}
+	var (
+		opts       = &MultiCacheOptions{Base: Base{Prefix: "demo"}, Expire: time.Minute}
+		memAdaptor = newMemAdaptor(1024 * 1024)
+		cache      = NewMultiCacheWithLocal[*person](opts, newLocalAdaptor[*person](), newMemAdaptor(1024*1024), memAdaptor)
+	)
+
+	written, err := cache.SetIfPresent(context.TODO(), map[string]*person{"1": {Name: "111"}})
+	if err != nil {
+		panic(err)
+	}
+	if written["1"] {
+		t.Fatalf("SetIfPresent wrote missing key")
+	}
+	written, err = cache.SetIfAbsent(context.TODO(), map[string]*person{"1": {Name: "111"}, "2": {Name: "222"}})
+	if err != nil {
+		panic(err)
+	}
+	if !written["1"] || !written["2"] {
+		t.Fatalf("written = %v", written)
+	}
+	written, _ = cache.SetIfAbsent(context.TODO(), map[string]*person{"1": {Name: "new"}})
+	if written["1"] {
+		t.Fatalf("SetIfAbsent overwrote existing key")
+	}
+
+	// 读取进上一级缓存后再做 CompareAndSwap，写入成功后上一级缓存被删除
+	if _, err = cache.Get(context.TODO(), []string{"1"}); err != nil {
+		panic(err)
+	}
+	items, err := cache.GetWithVersion(context.TODO(), []string{"1"})
+	if err != nil {
+		panic(err)
+	}
+	old := items["1"]
+	written, err = cache.CompareAndSwap(context.TODO(), map[string]Versioned[*person]{"1": {Value: &person{Name: "333"}, Version: old.Version}})
+	if err != nil {
+		panic(err)
+	}
+	if !written["1"] {
+		t.Fatalf("CompareAndSwap with current version failed")
+	}
+	kvMap, _ := cache.Get(context.TODO(), []string{"1"})
+	if kvMap["1"] == nil || kvMap["1"].Name != "333" {
+		t.Fatalf("kvMap = %v, want updated value", kvMap)
+	}
+
+	// 旧版本号写入失败
+	written, _ = cache.CompareAndSwap(context.TODO(), map[string]Versioned[*person]{"1": {Value: &person{Name: "444"}, Version: old.Version}})
+	if written["1"] {
+		t.Fatalf("CompareAndSwap with stale version succeeded")
+	}
+
+	// 只有进程内缓存
+	local := NewLocalCache[*person](newLocalAdaptor[*person](), &Options{Base: Base{Prefix: "demo"}, Expire: time.Minute})
+	written, _ = local.CompareAndSwap(context.TODO(), map[string]Versioned[*person]{"1": {Value: &person{Name: "111"}}})
+	if !written["1"] {
+		t.Fatalf("CompareAndSwap with empty version on missing key failed")
+	}
+	items, _ = local.GetWithVersion(context.TODO(), []string{"1"})
+	written, _ = local.CompareAndSwap(context.TODO(), map[string]Versioned[*person]{"1": {Value: &person{Name: "222"}, Version: items["1"].Version}})
+	if !written["1"] {
+		t.Fatalf("local CompareAndSwap with current version failed")
+	}
+
+	// 没有任何缓存
+	empty := NewMultiCache[*person](opts)
+	if _, err = empty.SetIfAbsent(context.TODO(), map[string]*person{"1": {}}); !errors.Is(err, client.ErrNotSupported) {
+		t.Fatalf("SetIfAbsent err = %v, want ErrNotSupported", err)
+	}
+	if _, err = empty.CompareAndSwap(context.TODO(), map[string]Versioned[*person]{"1": {}}); !errors.Is(err, client.ErrNotSupported) {
+		t.Fatalf("CompareAndSwap err = %v, want ErrNotSupported", err)
+	}
+	if _, err = empty.GetWithVersion(context.TODO(), []string{"1"}); !errors.Is(err, client.ErrNotSupported) {
+		t.Fatalf("GetWithVersion err = %v, want ErrNotSupported", err)
+	}
+}
+
+func TestMultiCacheDelByTag(t *testing.T) {
+	type product struct {
+		Name string `json:"name"`
+	}
+	opts := &MultiCacheOptions{Base: Base{Prefix: "demo"}, Expire: time.Minute}
+	cache := NewMultiCacheWithLocal[*product](opts, newLocalAdaptor[*product](), newMemAdaptor(1024*1024), newMemAdaptor(1024*1024))
+
+	err := cache.SetWithTags(context.TODO(), map[string]*product{
+		"1": {Name: "111"},
+		"2": {Name: "222"},
+		"3": {Name: "333"},
+	}, map[string][]string{
+		"1": {"product:42", "tenant:a"},
+		"2": {"product:42"},
+		"3": {"tenant:b"},
+	})
+	if err != nil {
+		panic(err)
+	}
+	// 查询一次让数据进入进程内缓存
+	if _, err = cache.Get(context.TODO(), []string{"1", "2", "3"}); err != nil {
+		panic(err)
+	}
+
+	if err = cache.DelByTag(context.TODO(), []string{"product:42"}); err != nil {
+		panic(err)
+	}
+	kvMap, err := cache.Get(context.TODO(), []string{"1", "2", "3"})
+	if err != nil {
+		panic(err)
+	}
+	if len(kvMap) != 1 || kvMap["3"] == nil {
+		t.Fatalf("kvMap = %v, want only 3", kvMap)
+	}
+
+	// 熔断包装的适配器不支持标签时跳过，不按出错处理
+	conf := breaker.Config{}
+	plain, err := breaker.NewBreakerAdaptor(struct{ client.IAdaptor }{newMemAdaptor(1024 * 1024)}, &conf)
+	if err != nil {
+		panic(err)
+	}
+	wrapped := NewMultiCache[*product](opts, plain, newMemAdaptor(1024*1024))
+	if err = wrapped.SetWithTags(context.TODO(), map[string]*product{"1": {Name: "111"}}, map[string][]string{"1": {"product:42"}}); err != nil {
+		t.Fatalf("SetWithTags = %v, want nil", err)
+	}
+	if err = wrapped.DelByTag(context.TODO(), []string{"product:42"}); err != nil {
+		t.Fatalf("DelByTag = %v, want nil", err)
+	}
+	if kvMap, _ = wrapped.Get(context.TODO(), []string{"1"}); len(kvMap) != 0 {
+		t.Fatalf("kvMap = %v, want deleted", kvMap)
+	}
+	single := NewCache[*product](plain, &Options{Base: Base{Prefix: "demo"}})
+	if err = single.DelByTag(context.TODO(), []string{"product:42"}); !errors.Is(err, client.ErrNotSupported) {
+		t.Fatalf("err = %v, want ErrNotSupported", err)
+	}
+
+	// 不支持标签的缓存
+	local := NewLocalCache[*product](newLocalAdaptor[*product](), &Options{Base: Base{Prefix: "demo"}})
+	if err = local.DelByTag(context.TODO(), []string{"product:42"}); !errors.Is(err, client.ErrNotSupported) {
+		t.Fatalf("err = %v, want ErrNotSupported", err)
+	}
+}
+
+// keyspaceTagger 把标签索引和数据保存在同一个keyspace中的适配器，和 redis 一致
+type keyspaceTagger struct {
+	client.IAdaptor
+}
+
+func (k *keyspaceTagger) AddTags(ctx context.Context, params map[string][]string, expire time.Duration) error {
+	index := make(map[string][]byte)
+	for key, tags := range params {
+		for _, tag := range tags {
+			index[tag] = append(index[tag], key...)
+		}
+	}
+	return k.Set(ctx, index, expire)
+}
+
+func (k *keyspaceTagger) TagKeys(ctx context.Context, tags []string) ([]string, error) {
+	return nil, nil
+}
+
+func (k *keyspaceTagger) DelTags(ctx context.Context, tags []string) error {
+	return k.Del(ctx, tags)
+}
+
+func TestScanAndDelPrefix(t *testing.T) {
+	opts := &MultiCacheOptions{Base: Base{Prefix: "demo"}, Expire: time.Minute}
+	mem := newMemAdaptor(1024 * 1024)
+	cache := NewMultiCacheWithLocal[string](opts, newLocalAdaptor[string](), mem)
+	ctx := context.TODO()
+
+	err := cache.Set(ctx, map[string]string{"user:1": "1", "user:2": "2", "order:1": "1"})
+	if err != nil {
+		panic(err)
+	}
+	// 其它前缀的数据不受影响
+	other := NewMultiCache[string](&MultiCacheOptions{Base: Base{Prefix: "other"}, Expire: time.Minute}, mem)
+	if err = other.Set(ctx, map[string]string{"user:1": "1"}); err != nil {
+		panic(err)
+	}
+	_, _ = cache.Get(ctx, []string{"user:1", "user:2"})
+
+	var keys []string
+	it := cache.Scan(ctx, "user:*")
+	for it.Next(ctx) {
+		keys = append(keys, it.Keys()...)
+	}
+	if err = it.Err(); err != nil {
+		panic(err)
+	}
+	sort.Strings(keys)
+	if !reflect.DeepEqual(keys, []string{"user:1", "user:2"}) {
+		t.Fatalf("keys = %v, want [user:1 user:2]", keys)
+	}
+
+	n, err := cache.DelPrefix(ctx, "user:")
+	if err != nil || n != 2 {
+		t.Fatalf("DelPrefix = %d, %v, want 2", n, err)
+	}
+	// 进程内缓存同样被删除
+	kvMap, err := cache.Get(ctx, []string{"user:1", "user:2", "order:1"})
+	if err != nil {
+		panic(err)
+	}
+	if len(kvMap) != 1 {
+		t.Fatalf("kvMap = %v, want only order:1", kvMap)
+	}
+	if kvMap, _ = other.Get(ctx, []string{"user:1"}); len(kvMap) != 1 {
+		t.Fatalf("other prefix deleted")
+	}
+
+	// 标签索引不作为数据返回
+	tagged := NewMultiCache[string](opts, &keyspaceTagger{newMemAdaptor(1024 * 1024)})
+	if err = tagged.SetWithTags(ctx, map[string]string{"1": "1"}, map[string][]string{"1": {"a"}}); err != nil {
+		panic(err)
+	}
+	keys = nil
+	it = tagged.Scan(ctx, "*")
+	for it.Next(ctx) {
+		keys = append(keys, it.Keys()...)
+	}
+	if it.Err() != nil || !reflect.DeepEqual(keys, []string{"1"}) {
+		t.Fatalf("keys = %v, %v, want [1]", keys, it.Err())
+	}
+
+	// 没有任何缓存
+	it = NewMultiCache[string](opts).Scan(ctx, "*")
+	if it.Next(ctx) || !errors.Is(it.Err(), client.ErrNotSupported) {
+		t.Fatalf("err = %v, want ErrNotSupported", it.Err())
+	}
+}
@@ -0,0 +1,106 @@
+package tmpcache
+
+import (
+	"context"
+	"errors"
+	"github.com/PycMono/go-cache/client"
+	"strings"
+)
+
+// buildPattern 构造 Scan 的匹配模式，前缀中的通配符按普通字符匹配
+func (c *Base) buildPattern(pattern string) string {
+	if len(c.Prefix) > 0 {
+		return client.EscapePattern(c.Prefix+"_") + pattern
+	}
+	return pattern
+}
+
+// scanKeys 遍历key并去掉前缀，跳过和数据在同一个keyspace中的标签索引（如 redis）
+func (c *Base) scanKeys(ctx context.Context, cli interface {
+	Scan(ctx context.Context, pattern string) client.KeyIterator
+}, pattern string) client.KeyIterator {
+	prefix := ""
+	if len(c.Prefix) > 0 {
+		prefix = c.Prefix + "_"
+	}
+	return client.MapKeys(cli.Scan(ctx, c.buildPattern(pattern)), func(key string) (string, bool) {
+		key, ok := strings.CutPrefix(key, prefix)
+		return key, ok && !strings.HasPrefix(key, tagPrefix)
+	})
+}
+
+func (c *Cache[T]) Scan(ctx context.Context, pattern string) client.KeyIterator {
+	if err := c.lc.enter(); err != nil {
+		return client.ErrIterator(err)
+	}
+	defer c.lc.leave()
+
+	return c.lc.iterator(c.opts.scanKeys(ctx, c.handler, pattern))
+}
+
+func (c *Cache[T]) DelPrefix(ctx context.Context, prefix string) (int64, error) {
+	if err := c.lc.enter(); err != nil {
+		return 0, err
+	}
+	defer c.lc.leave()
+
+	var n int64
+	err := callBatches(ctx, 0, "del", c.opts.Timeouts.Del, func(ctx context.Context) (err error) {
+		n, err = c.handler.DelPrefix(ctx, c.opts.buildKey(prefix))
+		return err
+	})
+	return n, err
+}
+
+func (c *MultiCache[T]) Scan(ctx context.Context, pattern string) client.KeyIterator {
+	if err := c.lc.enter(); err != nil {
+		return client.ErrIterator(err)
+	}
+	defer c.lc.leave()
+
+	if len(c.handlers) == 0 {
+		if c.local == nil {
+			return client.ErrIterator(client.ErrNotSupported)
+		}
+		return c.lc.iterator(c.opts.scanKeys(ctx, c.local, pattern))
+	}
+	return c.lc.iterator(c.opts.scanKeys(ctx, c.handlers[len(c.handlers)-1], pattern))
+}
+
+func (c *MultiCache[T]) DelPrefix(ctx context.Context, prefix string) (int64, error) {
+	if err := c.lc.enter(); err != nil {
+		return 0, err
+	}
+	defer c.lc.leave()
+
+	return c.delPrefix(ctx, prefix)
+}
+
+// delPrefix 从所有缓存（包括进程内缓存）中删除前缀下的key，返回最后一级缓存删除的数量
+func (c *MultiCache[T]) delPrefix(ctx context.Context, prefix string) (int64, error) {
+	var (
+		tmpPrefix = c.opts.buildKey(prefix)
+		n         int64
+		errs      []error
+	)
+	if c.local != nil {
+		deleted, err := c.local.DelPrefix(ctx, tmpPrefix)
+		if err = c.handleErr(LocalTier, "del", err, &errs); err != nil {
+			return 0, err
+		}
+		n = deleted
+	}
+	for i, v := range c.handlers {
+		var deleted int64
+		err := callBatches(ctx, i, "del", c.opts.timeouts(i).Del, func(ctx context.Context) (err error) {
+			deleted, err = v.DelPrefix(ctx, tmpPrefix)
+			return err
+		})
+		if err = c.handleErr(i, "del", err, &errs); err != nil {
+			return 0, err
+		}
+		n = deleted
+	}
+
+	return n, errors.Join(errs...)
+}
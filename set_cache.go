@@ -0,0 +1,82 @@
+package tmpcache
+
+import (
+	"context"
+	"github.com/PycMono/go-cache/client"
+)
+
+// SetCache 集合缓存，对应 redis SET，成员按序列化后的字节比较
+type SetCache[T any] struct {
+	collectionBase[T]
+}
+
+func NewSetCache[T any](handler client.ICollectionAdaptor, opts *CollectionOptions) *SetCache[T] {
+	return &SetCache[T]{collectionBase: collectionBase[T]{handler: handler, opts: opts}}
+}
+
+// Add 返回新增的成员数
+func (c *SetCache[T]) Add(ctx context.Context, key string, members ...T) (int64, error) {
+	b, err := c.marshal(members)
+	if err != nil {
+		return 0, err
+	}
+
+	var n int64
+	err = c.write(ctx, func(ctx context.Context) (err error) {
+		n, err = c.handler.SAdd(ctx, c.opts.buildKey(key), b, c.opts.Expire)
+		return err
+	})
+	return n, err
+}
+
+// Remove 返回删除的成员数
+func (c *SetCache[T]) Remove(ctx context.Context, key string, members ...T) (int64, error) {
+	b, err := c.marshal(members)
+	if err != nil {
+		return 0, err
+	}
+
+	var n int64
+	err = c.write(ctx, func(ctx context.Context) (err error) {
+		n, err = c.handler.SRem(ctx, c.opts.buildKey(key), b)
+		return err
+	})
+	return n, err
+}
+
+// IsMember 按 members 的顺序返回是否为集合成员
+func (c *SetCache[T]) IsMember(ctx context.Context, key string, members ...T) ([]bool, error) {
+	b, err := c.marshal(members)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []bool
+	err = c.read(ctx, func(ctx context.Context) (err error) {
+		out, err = c.handler.SIsMember(ctx, c.opts.buildKey(key), b)
+		return err
+	})
+	return out, err
+}
+
+// Members 返回全部成员，顺序不固定
+func (c *SetCache[T]) Members(ctx context.Context, key string) ([]T, error) {
+	var values [][]byte
+	err := c.read(ctx, func(ctx context.Context) (err error) {
+		values, err = c.handler.SMembers(ctx, c.opts.buildKey(key))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c.unmarshal(values)
+}
+
+func (c *SetCache[T]) Len(ctx context.Context, key string) (int64, error) {
+	var n int64
+	err := c.read(ctx, func(ctx context.Context) (err error) {
+		n, err = c.handler.SCard(ctx, c.opts.buildKey(key))
+		return err
+	})
+	return n, err
+}
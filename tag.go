@@ -0,0 +1,22 @@
+package tmpcache
+
+// tagPrefix 标签索引的key为 buildKey(tagPrefix + 标签)，业务key不要使用该前缀
+const tagPrefix = "tag:"
+
+// buildTagKeys 构造标签索引的key
+func (c *Base) buildTagKeys(tags []string) []string {
+	out := make([]string, len(tags))
+	for i, tag := range tags {
+		out[i] = c.buildKey(tagPrefix + tag)
+	}
+	return out
+}
+
+// buildTags 构造 key→标签索引key
+func (c *Base) buildTags(tags map[string][]string) map[string][]string {
+	out := make(map[string][]string, len(tags))
+	for k, v := range tags {
+		out[c.buildKey(k)] = c.buildTagKeys(v)
+	}
+	return out
+}
@@ -0,0 +1,142 @@
+package tmpcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/PycMono/go-cache/client"
+	"os"
+	"time"
+)
+
+// Timeouts 超时配置，0表示不限制，超时后返回 *TimeoutError
+type Timeouts struct {
+	Get    time.Duration // 单级缓存查询超时
+	Set    time.Duration // 单级缓存写入超时（包括回写上一级缓存）
+	Del    time.Duration // 单级缓存删除超时，DelPrefix 为每一批删除的超时
+	Lookup time.Duration // 查询阶段的总预算，多级缓存时为依次查询所有缓存的总耗时，超时后按对应缓存的出错策略处理
+	Loader time.Duration // 缓存miss后调用加载函数的超时
+}
+
+// merge 用o中非0的配置覆盖t
+func (t Timeouts) merge(o Timeouts) Timeouts {
+	if o.Get > 0 {
+		t.Get = o.Get
+	}
+	if o.Set > 0 {
+		t.Set = o.Set
+	}
+	if o.Del > 0 {
+		t.Del = o.Del
+	}
+	return t
+}
+
+// TimeoutError 超时错误，可以通过 errors.Is(err, context.DeadlineExceeded) 判断
+type TimeoutError struct {
+	Op    string        // get、set、del、lookup、loader
+	Tier  int           // 缓存下标，lookup、loader 为 NoTier
+	Limit time.Duration // 超时时间
+	Err   error
+}
+
+func (e *TimeoutError) Error() string {
+	if e.Tier == NoTier {
+		return fmt.Sprintf("cache %s timeout after %s: %v", e.Op, e.Limit, e.Err)
+	}
+	return fmt.Sprintf("cache tier %d %s timeout after %s: %v", e.Tier, e.Op, e.Limit, e.Err)
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// Timeout 实现 net.Error 风格的超时判断
+func (e *TimeoutError) Timeout() bool {
+	return true
+}
+
+// withTimeout 派生子ctx，timeout为0直接返回原ctx
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// wrapTimeout 错误是由本层派生的ctx超时导致（调用方的ctx未结束）时包装为 *TimeoutError
+func wrapTimeout(parent, ctx context.Context, err error, op string, tier int, timeout time.Duration) error {
+	if err == nil || timeout <= 0 || parent.Err() != nil || !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return err
+	}
+
+	var timeoutErr *TimeoutError
+	if errors.As(err, &timeoutErr) {
+		return err
+	}
+	return &TimeoutError{Op: op, Tier: tier, Limit: timeout, Err: err}
+}
+
+// callTier 在第i级缓存的超时限制内执行f
+func callTier(ctx context.Context, i int, op string, timeout time.Duration, f func(ctx context.Context) error) error {
+	tierCtx, cancel := withTimeout(ctx, timeout)
+	defer cancel()
+
+	return wrapTimeout(ctx, tierCtx, f(tierCtx), op, i, timeout)
+}
+
+// callBatches 把超时限制作为每一批的超时传给分批执行的f（例如 DelPrefix），不限制总耗时
+func callBatches(ctx context.Context, i int, op string, timeout time.Duration, f func(ctx context.Context) error) error {
+	err := f(client.WithBatchTimeout(ctx, timeout))
+	if err == nil || timeout <= 0 || ctx.Err() != nil {
+		return err
+	}
+	if !errors.Is(err, context.DeadlineExceeded) && !errors.Is(err, os.ErrDeadlineExceeded) {
+		return err
+	}
+
+	var timeoutErr *TimeoutError
+	if errors.As(err, &timeoutErr) {
+		return err
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		err = errors.Join(context.DeadlineExceeded, err) // 连接读写超时
+	}
+	return &TimeoutError{Op: op, Tier: i, Limit: timeout, Err: err}
+}
+
+// loaded 加载函数的返回结果
+type loaded[R any] struct {
+	val R
+	err error
+}
+
+// runLoader 在超时限制内调用加载函数
+// 加载函数不接收ctx，超时或ctx结束后直接返回，加载函数在后台执行完成后结果丢弃，Close 会等待其结束
+func runLoader[R any](ctx context.Context, lc *lifecycle, timeout time.Duration, f func() (R, error)) (R, error) {
+	if timeout <= 0 {
+		return f()
+	}
+
+	var (
+		zero R
+		ch   = make(chan loaded[R], 1)
+	)
+	lc.wg.Add(1) // 调用方持有一次 enter，这里计数不会和 shutdown 冲突
+	go func() {
+		defer lc.wg.Done()
+		val, err := f()
+		ch <- loaded[R]{val: val, err: err}
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case v := <-ch:
+		return v.val, v.err
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	case <-timer.C:
+		return zero, &TimeoutError{Op: "loader", Tier: NoTier, Limit: timeout, Err: context.DeadlineExceeded}
+	}
+}
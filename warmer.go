@@ -0,0 +1,302 @@
+package tmpcache
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/PycMono/go-cache/client"
+	"os"
+	"sync"
+)
+
+// WarmOptions 预热配置
+type WarmOptions[T any] struct {
+	// Loader 批量加载缓存中没有的key，为nil时只调用 Get，多级缓存会从下一级缓存回写进程内缓存
+	Loader      func(keys []string) (map[string]T, error)
+	BatchSize   int     // 每批预热的key数量，默认100
+	Concurrency int     // 同时预热的批数，默认4
+	FillRatio   float64 // 就绪需要达到的填充比例（已缓存的key/全部key），取值(0, 1]，默认1，加载不到数据的key不计入已缓存
+	// Progress 每批预热完成后调用，同一时间只有一个调用，调用时不持有锁，可以调用 Warmer 的其它方法
+	// 并发的批次完成顺序不确定，比已经回调过的进度更旧的进度不再回调
+	Progress func(p WarmProgress)
+}
+
+// WarmProgress 预热进度
+type WarmProgress struct {
+	Total  int  // 全部key数量
+	Filled int  // 预热后缓存中存在的key数量
+	Failed int  // 预热出错的key数量
+	Listed bool // key来源是否已读取完，读取完后才开始预热
+	Done   bool // 预热是否结束
+}
+
+// Ratio 填充比例
+func (p WarmProgress) Ratio() float64 {
+	if p.Total == 0 {
+		return 1
+	}
+	return float64(p.Filled) / float64(p.Total)
+}
+
+// Warmer 启动时预热缓存，key来源可以是 client.SliceIterator、ICache.Scan 或 KeysFromFile
+type Warmer[T any] struct {
+	cache ICache[T]
+	opts  *WarmOptions[T]
+
+	sm       sync.Mutex
+	progress WarmProgress
+	seq      uint64 // 进度的修改次数
+	err      error
+	ready    chan struct{} // 达到填充比例或预热结束时关闭
+	once     sync.Once
+
+	callback sync.Mutex // 保证 Progress 同一时间只有一个调用
+	reported uint64     // 最后一次回调的进度对应的 seq
+}
+
+func NewWarmer[T any](cache ICache[T], opts *WarmOptions[T]) *Warmer[T] {
+	return &Warmer[T]{
+		cache: cache,
+		opts:  opts,
+		ready: make(chan struct{}),
+	}
+}
+
+// Warm 先读取全部key，再按批并发预热，阻塞直到结束，单批出错不中断预热，错误合并返回
+// 一个 Warmer 只能预热一次
+func (w *Warmer[T]) Warm(ctx context.Context, keys client.KeyIterator) (WarmProgress, error) {
+	var all []string
+	for keys.Next(ctx) {
+		all = append(all, keys.Keys()...)
+	}
+	if err := keys.Err(); err != nil {
+		w.update(func(p *WarmProgress) { p.Done = true })
+		return w.Progress(), err
+	}
+	w.update(func(p *WarmProgress) {
+		p.Total = len(all)
+		p.Listed = true
+	})
+
+	var (
+		batches = make(chan []string)
+		wg      sync.WaitGroup
+		size    = w.batchSize()
+	)
+	for i := 0; i < w.concurrency(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range batches {
+				w.warm(ctx, batch)
+			}
+		}()
+	}
+loop:
+	for i := 0; i < len(all); i += size {
+		select {
+		case batches <- all[i:min(i+size, len(all))]:
+		case <-ctx.Done():
+			break loop
+		}
+	}
+	close(batches)
+	wg.Wait()
+	w.update(func(p *WarmProgress) { p.Done = true })
+
+	w.sm.Lock()
+	defer w.sm.Unlock()
+	return w.progress, errors.Join(ctx.Err(), w.err)
+}
+
+// Start 在后台预热，通过 WaitReady、Ready、Progress 查看状态
+func (w *Warmer[T]) Start(ctx context.Context, keys client.KeyIterator) {
+	go func() {
+		if _, err := w.Warm(ctx, keys); err != nil {
+			fmt.Println(err)
+		}
+	}()
+}
+
+// Ready key来源读取完且达到填充比例后返回true，可用于就绪检查
+func (w *Warmer[T]) Ready() bool {
+	w.sm.Lock()
+	defer w.sm.Unlock()
+
+	return w.reached()
+}
+
+// WaitReady 阻塞直到达到填充比例，预热结束仍未达到时返回错误
+func (w *Warmer[T]) WaitReady(ctx context.Context) error {
+	select {
+	case <-w.ready:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	w.sm.Lock()
+	defer w.sm.Unlock()
+	if w.reached() {
+		return nil
+	}
+	err := fmt.Errorf("cache: warm-up filled %.2f of %d keys, want %.2f", w.progress.Ratio(), w.progress.Total, w.fillRatio())
+	return errors.Join(err, w.err)
+}
+
+// Progress 当前进度
+func (w *Warmer[T]) Progress() WarmProgress {
+	w.sm.Lock()
+	defer w.sm.Unlock()
+
+	return w.progress
+}
+
+// warm 预热一批key，Loader 为nil时只查询缓存
+func (w *Warmer[T]) warm(ctx context.Context, keys []string) {
+	var (
+		kv  map[string]T
+		err error
+	)
+	if w.opts.Loader != nil {
+		kv, err = w.cache.GetAndSet(ctx, keys, w.opts.Loader)
+	} else {
+		kv, err = w.cache.Get(ctx, keys)
+	}
+
+	w.update(func(p *WarmProgress) {
+		if err != nil {
+			p.Failed += len(keys)
+			w.err = errors.Join(w.err, err)
+			return
+		}
+		p.Filled += len(kv)
+	})
+}
+
+// update 修改进度，达到填充比例或预热结束时通知 WaitReady，释放锁后再回调 Progress
+func (w *Warmer[T]) update(f func(p *WarmProgress)) {
+	w.sm.Lock()
+	f(&w.progress)
+	w.seq++
+	var (
+		progress = w.progress
+		seq      = w.seq
+	)
+	if w.reached() || w.progress.Done {
+		w.once.Do(func() { close(w.ready) })
+	}
+	w.sm.Unlock()
+
+	if w.opts.Progress == nil {
+		return
+	}
+	w.callback.Lock()
+	defer w.callback.Unlock()
+	if seq > w.reported {
+		w.reported = seq
+		w.opts.Progress(progress)
+	}
+}
+
+// reached 是否达到填充比例，调用方需要持有 w.sm
+func (w *Warmer[T]) reached() bool {
+	return w.progress.Listed && w.progress.Ratio() >= w.fillRatio()
+}
+
+func (w *Warmer[T]) fillRatio() float64 {
+	if w.opts.FillRatio <= 0 || w.opts.FillRatio > 1 {
+		return 1
+	}
+	return w.opts.FillRatio
+}
+
+func (w *Warmer[T]) batchSize() int {
+	if w.opts.BatchSize <= 0 {
+		return 100
+	}
+	return w.opts.BatchSize
+}
+
+func (w *Warmer[T]) concurrency() int {
+	if w.opts.Concurrency <= 0 {
+		return 4
+	}
+	return w.opts.Concurrency
+}
+
+// FileKeys KeysFromFile 返回的迭代器，遍历结束（包括ctx结束和出错）时自动关闭文件，提前停止遍历时需要调用 Close
+type FileKeys struct {
+	client.KeyIterator
+	f    *os.File
+	once sync.Once
+	err  error
+}
+
+// Next 没有更多key时关闭文件
+func (k *FileKeys) Next(ctx context.Context) bool {
+	if k.KeyIterator.Next(ctx) {
+		return true
+	}
+	_ = k.Close()
+	return false
+}
+
+// Close 关闭文件，可以重复调用
+func (k *FileKeys) Close() error {
+	k.once.Do(func() {
+		k.err = k.f.Close()
+	})
+	return k.err
+}
+
+// KeysFromFile 读取快照文件中的key，每行一个，空行忽略
+func KeysFromFile(path string) (*FileKeys, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	scanner := bufio.NewScanner(f)
+	return &FileKeys{
+		KeyIterator: client.NewKeyIterator(func(ctx context.Context) ([]string, bool, error) {
+			var keys []string
+			for len(keys) < 1000 {
+				if !scanner.Scan() {
+					return keys, true, scanner.Err()
+				}
+				if line := scanner.Text(); line != "" {
+					keys = append(keys, line)
+				}
+			}
+			return keys, false, nil
+		}),
+		f: f,
+	}, nil
+}
+
+// SaveKeys 把key写入快照文件，每行一个，用于下次启动时 KeysFromFile 预热，例如退出前保存 ICache.Scan 的结果
+func SaveKeys(ctx context.Context, path string, keys client.KeyIterator) (int, error) {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		n int
+		w = bufio.NewWriter(f)
+	)
+	for keys.Next(ctx) {
+		for _, k := range keys.Keys() {
+			_, _ = w.WriteString(k)
+			_ = w.WriteByte('\n')
+			n++
+		}
+	}
+	if err = errors.Join(keys.Err(), w.Flush(), f.Sync(), f.Close()); err != nil {
+		_ = os.Remove(tmp)
+		return 0, err
+	}
+	return n, os.Rename(tmp, path)
+}
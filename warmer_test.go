@@ -0,0 +1,102 @@
+package tmpcache
+
+import (
+	"context"
+	"fmt"
+	"github.com/PycMono/go-cache/client"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWarmer(t *testing.T) {
+	var (
+		mem   = newMemAdaptor(1024 * 1024)
+		opts  = &MultiCacheOptions{Base: Base{Prefix: "demo"}, Expire: time.Minute}
+		cache = NewMultiCacheWithLocal[string](opts, newLocalAdaptor[string](), mem)
+		ctx   = context.TODO()
+		loads atomic.Int64
+		calls atomic.Int64
+		last  atomic.Value
+	)
+	var keys []string
+	for i := 0; i < 100; i++ {
+		keys = append(keys, fmt.Sprintf("%d", i))
+	}
+
+	var warmer *Warmer[string]
+	warmer = NewWarmer[string](cache, &WarmOptions[string]{
+		Loader: func(keys []string) (map[string]string, error) {
+			loads.Add(int64(len(keys)))
+			out := make(map[string]string)
+			for _, k := range keys {
+				if k != "99" { // 数据源中不存在
+					out[k] = "v" + k
+				}
+			}
+			return out, nil
+		},
+		BatchSize:   10,
+		Concurrency: 3,
+		FillRatio:   0.9,
+		Progress: func(p WarmProgress) {
+			calls.Add(1)
+			last.Store(p)
+			_ = warmer.Progress() // 回调时不持有锁
+		},
+	})
+	warmer.Start(ctx, client.SliceIterator(keys, 30))
+	if err := warmer.WaitReady(ctx); err != nil {
+		panic(err)
+	}
+	if !warmer.Ready() {
+		t.Fatalf("Ready = false after WaitReady")
+	}
+	for p, _ := last.Load().(WarmProgress); !p.Done; p, _ = last.Load().(WarmProgress) {
+		time.Sleep(time.Millisecond)
+	}
+	if p := warmer.Progress(); p.Total != 100 || p.Filled != 99 || loads.Load() != 100 || calls.Load() < 2 {
+		t.Fatalf("progress = %+v, loads = %d, calls = %d", p, loads.Load(), calls.Load())
+	}
+	if p := last.Load().(WarmProgress); p.Filled != 99 {
+		t.Fatalf("last reported progress = %+v, want final", p)
+	}
+
+	// 保存 redis 中的key，重启后从快照文件预热，不需要加载函数
+	path := filepath.Join(t.TempDir(), "keys")
+	n, err := SaveKeys(ctx, path, cache.Scan(ctx, "*"))
+	if err != nil || n != 99 {
+		t.Fatalf("SaveKeys = %d, %v, want 99", n, err)
+	}
+	restarted := NewMultiCacheWithLocal[string](opts, newLocalAdaptor[string](), mem)
+	it, err := KeysFromFile(path)
+	if err != nil {
+		panic(err)
+	}
+	p, err := NewWarmer[string](restarted, &WarmOptions[string]{}).Warm(ctx, it)
+	if err != nil || p.Filled != 99 {
+		t.Fatalf("progress = %+v, err = %v", p, err)
+	}
+	// 遍历结束后文件已关闭
+	if err = it.f.Close(); err == nil {
+		t.Fatalf("file not closed after iteration")
+	}
+
+	// ctx 结束时关闭文件
+	if it, err = KeysFromFile(path); err != nil {
+		panic(err)
+	}
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if it.Next(canceled) || it.Err() == nil || it.f.Close() == nil {
+		t.Fatalf("want ctx error and closed file")
+	}
+
+	// 达不到填充比例
+	warmer = NewWarmer[string](NewLocalCache[string](newLocalAdaptor[string](), &Options{}), &WarmOptions[string]{})
+	warmer.Start(ctx, client.SliceIterator(keys, 30))
+	if err = warmer.WaitReady(ctx); err == nil {
+		t.Fatalf("want fill ratio error")
+	}
+}
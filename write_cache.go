@@ -0,0 +1,422 @@
+package tmpcache
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/PycMono/go-cache/client"
+	"github.com/bytedance/sonic"
+	"hash/fnv"
+	"os"
+	"sync"
+	"time"
+)
+
+// Writer 数据源的写入接口，由业务实现（例如写数据库）
+type Writer[T any] interface {
+	// Store 批量写入数据源
+	Store(ctx context.Context, params map[string]T) error
+	// Delete 批量从数据源删除
+	Delete(ctx context.Context, keys []string) error
+}
+
+// WriteMode 写入模式
+type WriteMode int
+
+const (
+	WriteThrough WriteMode = iota // 同步写入数据源，成功后写入缓存
+	WriteBehind                   // 立即写入缓存，合并后异步批量写入数据源
+)
+
+// WriteOptions WriteCache 配置
+type WriteOptions struct {
+	Mode WriteMode
+	// 以下配置只对 WriteBehind 生效
+	FlushInterval time.Duration       // 刷新间隔，默认1秒
+	BatchSize     int                 // 每批写入数据源的key数量上限，未刷新的key达到该数量时立即刷新，默认100
+	Retry         *client.RetryPolicy // 每批写入的重试策略，默认 client.DefaultRetryPolicy 且所有错误都重试，重试用完的key保留到下次刷新
+	// 持久化队列文件路径，为空时未刷新的数据只保存在内存中
+	// 设置后每次写入先追加到文件，刷新后重写为剩余的数据，重启时从文件恢复未刷新的数据
+	Journal string
+}
+
+// getRetry 获取重试策略
+func (o *WriteOptions) getRetry() client.RetryPolicy {
+	if o.Retry == nil {
+		return client.DefaultRetryPolicy().WithRetryable(func(err error) bool { return true })
+	}
+	return *o.Retry
+}
+
+// writeOp 一个key未刷新的写入，Del 为true表示删除
+type writeOp[T any] struct {
+	Key   string `json:"key"`
+	Value T      `json:"value,omitempty"`
+	Del   bool   `json:"del,omitempty"`
+}
+
+// WriteCache 写入时同时维护数据源的缓存，Set、Del 按 WriteMode 写入数据源，其它方法直接调用底层缓存
+type WriteCache[T any] struct {
+	ICache[T]
+	writer Writer[T]
+	opts   *WriteOptions
+	retry  client.RetryPolicy
+	lc     lifecycle
+
+	keyLocks [64]sync.Mutex // WriteBehind 按key分段的锁，写缓存和记录待刷新队列在同一把锁内完成，两者的顺序一致
+	sm       sync.Mutex
+	pending  map[string]writeOp[T] // 未刷新的写入，同一个key只保留最后一次
+	journal  *os.File
+	flushing sync.Mutex // 同一时间只有一个刷新
+	trigger  chan struct{}
+	done     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewWriteCache cache 可以是 Cache 或 MultiCache，关闭 WriteCache 时一起关闭
+// WriteBehind 设置了 Journal 时从文件恢复上次未刷新的数据
+func NewWriteCache[T any](cache ICache[T], writer Writer[T], opts *WriteOptions) (*WriteCache[T], error) {
+	c := &WriteCache[T]{
+		ICache:  cache,
+		writer:  writer,
+		opts:    opts,
+		retry:   opts.getRetry(),
+		pending: make(map[string]writeOp[T]),
+		trigger: make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+	if opts.Mode != WriteBehind {
+		return c, nil
+	}
+
+	if opts.Journal != "" {
+		if err := c.replay(); err != nil {
+			return nil, err
+		}
+		f, err := os.OpenFile(opts.Journal, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, err
+		}
+		c.journal = f
+	}
+
+	interval := opts.FlushInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	c.wg.Add(1)
+	go c.loop(interval)
+
+	return c, nil
+}
+
+// Set WriteThrough 先写入数据源，失败直接返回不写缓存；WriteBehind 先写缓存，再记录到待刷新队列
+func (c *WriteCache[T]) Set(ctx context.Context, params map[string]T) error {
+	if err := c.lc.enter(); err != nil {
+		return err
+	}
+	defer c.lc.leave()
+
+	if c.opts.Mode != WriteBehind {
+		if err := c.writer.Store(ctx, params); err != nil {
+			return err
+		}
+		return c.ICache.Set(ctx, params)
+	}
+
+	ops := make([]writeOp[T], 0, len(params))
+	for k, v := range params {
+		ops = append(ops, writeOp[T]{Key: k, Value: v})
+	}
+	unlock := c.lockKeys(ops)
+	defer unlock()
+
+	if err := c.ICache.Set(ctx, params); err != nil {
+		return err
+	}
+	return c.enqueue(ops)
+}
+
+// Del WriteThrough 先从数据源删除再删除缓存；WriteBehind 先删除缓存，再记录到待刷新队列
+func (c *WriteCache[T]) Del(ctx context.Context, keys []string) error {
+	if err := c.lc.enter(); err != nil {
+		return err
+	}
+	defer c.lc.leave()
+
+	if c.opts.Mode != WriteBehind {
+		if err := c.writer.Delete(ctx, keys); err != nil {
+			return err
+		}
+		return c.ICache.Del(ctx, keys)
+	}
+
+	ops := make([]writeOp[T], 0, len(keys))
+	for _, k := range keys {
+		ops = append(ops, writeOp[T]{Key: k, Del: true})
+	}
+	unlock := c.lockKeys(ops)
+	defer unlock()
+
+	if err := c.ICache.Del(ctx, keys); err != nil {
+		return err
+	}
+	return c.enqueue(ops)
+}
+
+// lockKeys 按下标顺序获取ops中的key对应的分段锁，避免并发的多key写入互相等待
+func (c *WriteCache[T]) lockKeys(ops []writeOp[T]) func() {
+	var locked [len(c.keyLocks)]bool
+	for _, op := range ops {
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(op.Key))
+		locked[h.Sum32()%uint32(len(c.keyLocks))] = true
+	}
+	for i := range locked {
+		if locked[i] {
+			c.keyLocks[i].Lock()
+		}
+	}
+	return func() {
+		for i := range locked {
+			if locked[i] {
+				c.keyLocks[i].Unlock()
+			}
+		}
+	}
+}
+
+// Flush 立即把未刷新的数据写入数据源，失败的key保留到下次刷新
+func (c *WriteCache[T]) Flush(ctx context.Context) error {
+	if err := c.lc.enter(); err != nil {
+		return err
+	}
+	defer c.lc.leave()
+
+	return c.flush(ctx)
+}
+
+// Pending 未刷新到数据源的key数量
+func (c *WriteCache[T]) Pending() int {
+	c.sm.Lock()
+	defer c.sm.Unlock()
+
+	return len(c.pending)
+}
+
+// Close 停止后台刷新，把未刷新的数据写入数据源后关闭底层缓存
+// 写入失败时数据保留在持久化队列中，下次启动时恢复
+func (c *WriteCache[T]) Close(ctx context.Context) error {
+	err := c.lc.shutdown(ctx)
+	if errors.Is(err, ErrClosed) {
+		return err
+	}
+
+	close(c.done)
+	c.wg.Wait()
+	if c.opts.Mode == WriteBehind {
+		err = errors.Join(err, c.flush(ctx))
+	}
+	if c.journal != nil {
+		err = errors.Join(err, c.journal.Close())
+	}
+	return errors.Join(err, c.ICache.Close(ctx))
+}
+
+// enqueue 合并到待刷新队列，设置了持久化队列时先追加到文件
+func (c *WriteCache[T]) enqueue(ops []writeOp[T]) error {
+	c.sm.Lock()
+	defer c.sm.Unlock()
+
+	if c.journal != nil {
+		if err := appendOps(c.journal, ops); err != nil {
+			return err
+		}
+	}
+	for _, op := range ops {
+		c.pending[op.Key] = op
+	}
+
+	if len(c.pending) >= c.batchSize() {
+		select {
+		case c.trigger <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+func (c *WriteCache[T]) batchSize() int {
+	if c.opts.BatchSize <= 0 {
+		return 100
+	}
+	return c.opts.BatchSize
+}
+
+// flush 按批写入数据源，写入期间新的写入不会被覆盖：只有值未变化的key才从队列中移除
+func (c *WriteCache[T]) flush(ctx context.Context) error {
+	c.flushing.Lock()
+	defer c.flushing.Unlock()
+
+	c.sm.Lock()
+	ops := make([]writeOp[T], 0, len(c.pending))
+	for _, op := range c.pending {
+		ops = append(ops, op)
+	}
+	c.sm.Unlock()
+	if len(ops) == 0 {
+		return nil
+	}
+
+	var (
+		errs []error
+		done []writeOp[T]
+		size = c.batchSize()
+	)
+	for i := 0; i < len(ops); i += size {
+		batch := ops[i:min(i+size, len(ops))]
+		if err := c.write(ctx, batch); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		done = append(done, batch...)
+	}
+
+	c.sm.Lock()
+	defer c.sm.Unlock()
+	for _, op := range done {
+		if cur, ok := c.pending[op.Key]; ok && sameOp(cur, op) {
+			delete(c.pending, op.Key)
+		}
+	}
+	if c.journal != nil {
+		errs = append(errs, c.compact())
+	}
+	return errors.Join(errs...)
+}
+
+// write 写入一批数据，写入和删除分别调用 Writer
+func (c *WriteCache[T]) write(ctx context.Context, batch []writeOp[T]) error {
+	var (
+		stores = make(map[string]T)
+		dels   []string
+	)
+	for _, op := range batch {
+		if op.Del {
+			dels = append(dels, op.Key)
+			continue
+		}
+		stores[op.Key] = op.Value
+	}
+
+	return c.retry.Do(ctx, func() error {
+		if len(stores) > 0 {
+			if err := c.writer.Store(ctx, stores); err != nil {
+				return err
+			}
+		}
+		if len(dels) > 0 {
+			return c.writer.Delete(ctx, dels)
+		}
+		return nil
+	})
+}
+
+// sameOp 判断刷新期间key是否被再次写入，值通过序列化比较
+func sameOp[T any](a, b writeOp[T]) bool {
+	if a.Del || b.Del {
+		return a.Del == b.Del
+	}
+	ab, err1 := sonic.Marshal(a.Value)
+	bb, err2 := sonic.Marshal(b.Value)
+	return err1 == nil && err2 == nil && string(ab) == string(bb)
+}
+
+// compact 把持久化队列重写为剩余的未刷新数据，调用方需要持有 c.sm
+func (c *WriteCache[T]) compact() error {
+	tmp := c.opts.Journal + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	ops := make([]writeOp[T], 0, len(c.pending))
+	for _, op := range c.pending {
+		ops = append(ops, op)
+	}
+	if err = errors.Join(appendOps(f, ops), f.Close()); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp, c.opts.Journal); err != nil {
+		return err
+	}
+
+	journal, err := os.OpenFile(c.opts.Journal, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	_ = c.journal.Close()
+	c.journal = journal
+	return nil
+}
+
+// replay 从持久化队列恢复未刷新的数据，文件不存在时忽略
+func (c *WriteCache[T]) replay() error {
+	f, err := os.Open(c.opts.Journal)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 64*1024*1024)
+	for scanner.Scan() {
+		var op writeOp[T]
+		if err = sonic.Unmarshal(scanner.Bytes(), &op); err != nil {
+			// 最后一行可能因为进程退出只写了一半，丢弃
+			fmt.Println(err)
+			continue
+		}
+		c.pending[op.Key] = op
+	}
+	return scanner.Err()
+}
+
+// appendOps 每个写入一行JSON追加到文件并同步到磁盘
+func appendOps[T any](f *os.File, ops []writeOp[T]) error {
+	w := bufio.NewWriter(f)
+	for _, op := range ops {
+		b, err := sonic.Marshal(op)
+		if err != nil {
+			return err
+		}
+		_, _ = w.Write(b)
+		_ = w.WriteByte('\n')
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+// loop 定时或队列达到 BatchSize 时刷新，出错只打印日志，数据保留到下次刷新
+func (c *WriteCache[T]) loop(interval time.Duration) {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-c.trigger:
+		case <-c.done:
+			return
+		}
+		if err := c.flush(context.Background()); err != nil {
+			fmt.Println(err)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package tmpcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/PycMono/go-cache/client"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memWriter 记录写入的数据源，fail 为true时所有写入失败
+type memWriter struct {
+	sm     sync.Mutex
+	data   map[string]string
+	stores int
+	fail   bool
+}
+
+func (w *memWriter) Store(ctx context.Context, params map[string]string) error {
+	w.sm.Lock()
+	defer w.sm.Unlock()
+
+	if w.fail {
+		return errors.New("store failed")
+	}
+	w.stores++
+	for k, v := range params {
+		w.data[k] = v
+	}
+	return nil
+}
+
+func (w *memWriter) Delete(ctx context.Context, keys []string) error {
+	w.sm.Lock()
+	defer w.sm.Unlock()
+
+	if w.fail {
+		return errors.New("delete failed")
+	}
+	for _, k := range keys {
+		delete(w.data, k)
+	}
+	return nil
+}
+
+func (w *memWriter) setFail(fail bool) {
+	w.sm.Lock()
+	defer w.sm.Unlock()
+
+	w.fail = fail
+}
+
+func (w *memWriter) get(k string) (string, bool) {
+	w.sm.Lock()
+	defer w.sm.Unlock()
+
+	v, ok := w.data[k]
+	return v, ok
+}
+
+func newWriteTestCache() ICache[string] {
+	return NewCache[string](newMemAdaptor(1024*1024), &Options{Base: Base{Prefix: "demo"}, Expire: time.Minute})
+}
+
+func TestWriteThrough(t *testing.T) {
+	writer := &memWriter{data: make(map[string]string)}
+	cache, err := NewWriteCache[string](newWriteTestCache(), writer, &WriteOptions{Mode: WriteThrough})
+	if err != nil {
+		panic(err)
+	}
+	ctx := context.TODO()
+
+	if err = cache.Set(ctx, map[string]string{"1": "a"}); err != nil {
+		panic(err)
+	}
+	if v, _ := writer.get("1"); v != "a" {
+		t.Fatalf("store = %q, want a", v)
+	}
+
+	// 数据源写入失败不写缓存
+	writer.setFail(true)
+	if err = cache.Set(ctx, map[string]string{"2": "b"}); err == nil {
+		t.Fatalf("want store error")
+	}
+	if kvMap, _ := cache.Get(ctx, []string{"2"}); len(kvMap) != 0 {
+		t.Fatalf("kvMap = %v, want empty", kvMap)
+	}
+	writer.setFail(false)
+
+	if err = cache.Del(ctx, []string{"1"}); err != nil {
+		panic(err)
+	}
+	if _, ok := writer.get("1"); ok {
+		t.Fatalf("key 1 not deleted from store")
+	}
+	_ = cache.Close(ctx)
+}
+
+func TestWriteBehind(t *testing.T) {
+	writer := &memWriter{data: make(map[string]string)}
+	retry := client.RetryPolicy{}
+	cache, err := NewWriteCache[string](newWriteTestCache(), writer, &WriteOptions{
+		Mode:          WriteBehind,
+		FlushInterval: time.Hour,
+		BatchSize:     2,
+		Retry:         &retry,
+	})
+	if err != nil {
+		panic(err)
+	}
+	ctx := context.TODO()
+
+	// 同一个key多次写入只刷新最后一次
+	_ = cache.Set(ctx, map[string]string{"1": "a"})
+	_ = cache.Set(ctx, map[string]string{"1": "b"})
+	if kvMap, _ := cache.Get(ctx, []string{"1"}); kvMap["1"] != "b" {
+		t.Fatalf("cache = %v, want b", kvMap)
+	}
+	if err = cache.Flush(ctx); err != nil {
+		panic(err)
+	}
+	if v, _ := writer.get("1"); v != "b" || writer.stores != 1 {
+		t.Fatalf("store = %q after %d stores, want b after 1", v, writer.stores)
+	}
+
+	// 刷新失败的数据保留到下次
+	writer.setFail(true)
+	_ = cache.Set(ctx, map[string]string{"2": "c"})
+	_ = cache.Del(ctx, []string{"1"})
+	if err = cache.Flush(ctx); err == nil {
+		t.Fatalf("want flush error")
+	}
+	if n := cache.Pending(); n != 2 {
+		t.Fatalf("pending = %d, want 2", n)
+	}
+	writer.setFail(false)
+
+	// 达到 BatchSize 触发后台刷新
+	_ = cache.Set(ctx, map[string]string{"3": "d"})
+	deadline := time.Now().Add(time.Second)
+	for cache.Pending() > 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if n := cache.Pending(); n != 0 {
+		t.Fatalf("pending = %d, want 0", n)
+	}
+	if _, ok := writer.get("1"); ok {
+		t.Fatalf("key 1 not deleted from store")
+	}
+
+	// Close 刷新未写入的数据
+	_ = cache.Set(ctx, map[string]string{"4": "e"})
+	if err = cache.Close(ctx); err != nil {
+		panic(err)
+	}
+	if v, _ := writer.get("4"); v != "e" {
+		t.Fatalf("store = %q, want e", v)
+	}
+}
+
+func TestWriteBehindConcurrent(t *testing.T) {
+	writer := &memWriter{data: make(map[string]string)}
+	cache, err := NewWriteCache[string](newWriteTestCache(), writer, &WriteOptions{Mode: WriteBehind, FlushInterval: time.Hour, BatchSize: 1 << 20})
+	if err != nil {
+		panic(err)
+	}
+	ctx := context.TODO()
+
+	// 并发写入和删除同一个key，缓存和待刷新队列最终一致
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if i%5 == 0 {
+				_ = cache.Del(ctx, []string{"1"})
+				return
+			}
+			_ = cache.Set(ctx, map[string]string{"1": fmt.Sprint(i), "2": fmt.Sprint(i)})
+		}(i)
+	}
+	wg.Wait()
+	if err = cache.Flush(ctx); err != nil {
+		panic(err)
+	}
+
+	kvMap, _ := cache.Get(ctx, []string{"1", "2"})
+	for _, k := range []string{"1", "2"} {
+		if v, ok := writer.get(k); v != kvMap[k] || ok != (kvMap[k] != "") {
+			t.Fatalf("key %s store = %q, cache = %q, want equal", k, v, kvMap[k])
+		}
+	}
+	_ = cache.Close(ctx)
+}
+
+func TestWriteBehindJournal(t *testing.T) {
+	var (
+		journal = filepath.Join(t.TempDir(), "write.journal")
+		writer  = &memWriter{data: make(map[string]string), fail: true}
+		retry   = client.RetryPolicy{}
+		opts    = &WriteOptions{Mode: WriteBehind, FlushInterval: time.Hour, Retry: &retry, Journal: journal}
+		ctx     = context.TODO()
+	)
+	cache, err := NewWriteCache[string](newWriteTestCache(), writer, opts)
+	if err != nil {
+		panic(err)
+	}
+	for i := 0; i < 5; i++ {
+		_ = cache.Set(ctx, map[string]string{fmt.Sprintf("%d", i): "v"})
+	}
+	_ = cache.Del(ctx, []string{"0"})
+	if err = cache.Close(ctx); err == nil {
+		t.Fatalf("want flush error on Close")
+	}
+
+	// 重启后从持久化队列恢复
+	writer.setFail(false)
+	cache, err = NewWriteCache[string](newWriteTestCache(), writer, opts)
+	if err != nil {
+		panic(err)
+	}
+	if n := cache.Pending(); n != 5 {
+		t.Fatalf("pending = %d, want 5", n)
+	}
+	if err = cache.Close(ctx); err != nil {
+		panic(err)
+	}
+	if len(writer.data) != 4 {
+		t.Fatalf("store = %v, want 1..4", writer.data)
+	}
+
+	cache, err = NewWriteCache[string](newWriteTestCache(), writer, opts)
+	if err != nil {
+		panic(err)
+	}
+	if n := cache.Pending(); n != 0 {
+		t.Fatalf("pending = %d after successful flush, want 0", n)
+	}
+	_ = cache.Close(ctx)
+}
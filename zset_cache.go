@@ -0,0 +1,165 @@
+package tmpcache
+
+import (
+	"context"
+	"github.com/PycMono/go-cache/client"
+	"github.com/bytedance/sonic"
+)
+
+// Scored 有序集合的成员及分数
+type Scored[T any] struct {
+	Member T
+	Score  float64
+}
+
+// ZSetCache 有序集合缓存，对应 redis ZSET，适合排行榜
+// 成员按序列化后的字节比较，分数相同时按字节序排序
+type ZSetCache[T any] struct {
+	collectionBase[T]
+}
+
+func NewZSetCache[T any](handler client.ICollectionAdaptor, opts *CollectionOptions) *ZSetCache[T] {
+	return &ZSetCache[T]{collectionBase: collectionBase[T]{handler: handler, opts: opts}}
+}
+
+// Add 已存在的成员更新分数，返回新增的成员数
+func (c *ZSetCache[T]) Add(ctx context.Context, key string, members ...Scored[T]) (int64, error) {
+	zs := make([]client.Z, len(members))
+	for i, m := range members {
+		b, err := sonic.Marshal(m.Member)
+		if err != nil {
+			return 0, err
+		}
+		zs[i] = client.Z{Member: b, Score: m.Score}
+	}
+
+	var n int64
+	err := c.write(ctx, func(ctx context.Context) (err error) {
+		n, err = c.handler.ZAdd(ctx, c.opts.buildKey(key), zs, c.opts.Expire)
+		return err
+	})
+	return n, err
+}
+
+// IncrBy 给成员的分数加上incr，成员不存在时从0开始，返回加上后的分数
+func (c *ZSetCache[T]) IncrBy(ctx context.Context, key string, member T, incr float64) (float64, error) {
+	b, err := sonic.Marshal(member)
+	if err != nil {
+		return 0, err
+	}
+
+	var score float64
+	err = c.write(ctx, func(ctx context.Context) (err error) {
+		score, err = c.handler.ZIncrBy(ctx, c.opts.buildKey(key), b, incr, c.opts.Expire)
+		return err
+	})
+	return score, err
+}
+
+// Remove 返回删除的成员数
+func (c *ZSetCache[T]) Remove(ctx context.Context, key string, members ...T) (int64, error) {
+	b, err := c.marshal(members)
+	if err != nil {
+		return 0, err
+	}
+
+	var n int64
+	err = c.write(ctx, func(ctx context.Context) (err error) {
+		n, err = c.handler.ZRem(ctx, c.opts.buildKey(key), b)
+		return err
+	})
+	return n, err
+}
+
+// Range 按分数从小到大返回 [start, stop] 范围内的成员
+func (c *ZSetCache[T]) Range(ctx context.Context, key string, start, stop int64) ([]Scored[T], error) {
+	return c.zrange(ctx, key, start, stop, false)
+}
+
+// RevRange 按分数从大到小返回 [start, stop] 范围内的成员
+func (c *ZSetCache[T]) RevRange(ctx context.Context, key string, start, stop int64) ([]Scored[T], error) {
+	return c.zrange(ctx, key, start, stop, true)
+}
+
+// Page 按分数从大到小分页读取，page 从1开始
+func (c *ZSetCache[T]) Page(ctx context.Context, key string, page, size int64) ([]Scored[T], error) {
+	start, stop, err := pageRange(page, size)
+	if err != nil {
+		return nil, err
+	}
+	return c.zrange(ctx, key, start, stop, true)
+}
+
+// Rank 按分数从小到大的排名，从0开始，成员不存在时 ok 为false
+func (c *ZSetCache[T]) Rank(ctx context.Context, key string, member T) (int64, bool, error) {
+	return c.rank(ctx, key, member, false)
+}
+
+// RevRank 按分数从大到小的排名，从0开始，成员不存在时 ok 为false
+func (c *ZSetCache[T]) RevRank(ctx context.Context, key string, member T) (int64, bool, error) {
+	return c.rank(ctx, key, member, true)
+}
+
+// Score 成员不存在时 ok 为false
+func (c *ZSetCache[T]) Score(ctx context.Context, key string, member T) (float64, bool, error) {
+	b, err := sonic.Marshal(member)
+	if err != nil {
+		return 0, false, err
+	}
+
+	var (
+		score float64
+		ok    bool
+	)
+	err = c.read(ctx, func(ctx context.Context) (err error) {
+		score, ok, err = c.handler.ZScore(ctx, c.opts.buildKey(key), b)
+		return err
+	})
+	return score, ok, err
+}
+
+func (c *ZSetCache[T]) Len(ctx context.Context, key string) (int64, error) {
+	var n int64
+	err := c.read(ctx, func(ctx context.Context) (err error) {
+		n, err = c.handler.ZCard(ctx, c.opts.buildKey(key))
+		return err
+	})
+	return n, err
+}
+
+func (c *ZSetCache[T]) zrange(ctx context.Context, key string, start, stop int64, desc bool) ([]Scored[T], error) {
+	var zs []client.Z
+	err := c.read(ctx, func(ctx context.Context) (err error) {
+		zs, err = c.handler.ZRange(ctx, c.opts.buildKey(key), start, stop, desc)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]Scored[T], len(zs))
+	for i, z := range zs {
+		if err = sonic.Unmarshal(z.Member, &out[i].Member); err != nil {
+			return nil, err
+		}
+		out[i].Score = z.Score
+	}
+	return out, nil
+}
+
+func (c *ZSetCache[T]) rank(ctx context.Context, key string, member T, desc bool) (int64, bool, error) {
+	b, err := sonic.Marshal(member)
+	if err != nil {
+		return 0, false, err
+	}
+
+	var (
+		rank int64
+		ok   bool
+	)
+	err = c.read(ctx, func(ctx context.Context) (err error) {
+		rank, ok, err = c.handler.ZRank(ctx, c.opts.buildKey(key), b, desc)
+		return err
+	})
+	return rank, ok, err
+}